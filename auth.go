@@ -0,0 +1,63 @@
+package revel
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// A User is the application's representation of an authenticated user, as
+// returned by its AuthResolver.
+type User interface{}
+
+// An AuthResolver identifies the user making a request, e.g. from the session
+// or a bearer token.  The application sets DefaultAuthResolver on startup.
+type AuthResolver interface {
+	// ResolveUser returns the user making the request, or nil if the request
+	// is not authenticated.
+	ResolveUser(c *Controller) (User, error)
+}
+
+// DefaultAuthResolver is used by Controller.RequireAuth.
+var DefaultAuthResolver AuthResolver
+
+// RequireAuth returns the user making the request, as resolved by
+// DefaultAuthResolver.  If the request is not authenticated, it instead
+// returns a Result for the action to return:
+//   - For HTML requests, a redirect to the login page, configured by
+//     "auth.login.url" (default "/login").  The requested URL is passed in the
+//     "next" query parameter.
+//   - For other formats (e.g. JSON APIs), a 401 Unauthorized error.
+//
+// For example:
+//   func (c Orders) Index() revel.Result {
+//     user, result := c.RequireAuth()
+//     if result != nil {
+//       return result
+//     }
+//     return c.Render(user)
+//   }
+func (c *Controller) RequireAuth() (User, Result) {
+	if DefaultAuthResolver == nil {
+		ERROR.Println("RequireAuth called without a DefaultAuthResolver")
+		return nil, c.InternalServerError("Authentication is not configured")
+	}
+
+	user, err := DefaultAuthResolver.ResolveUser(c)
+	if err != nil {
+		ERROR.Println("Failed to resolve user:", err)
+		return nil, c.RenderError(err)
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	if c.Request.Format == "html" {
+		loginUrl := Config.StringDefault("auth.login.url", "/login")
+		return nil, c.Redirect(loginUrl + "?next=" + url.QueryEscape(c.Request.URL.RequestURI()))
+	}
+	c.Response.Status = http.StatusUnauthorized
+	return nil, c.RenderError(&Error{
+		Title:       "Unauthorized",
+		Description: "Authentication is required",
+	})
+}
@@ -0,0 +1,48 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type sessionAuthResolver struct{}
+
+func (sessionAuthResolver) ResolveUser(c *Controller) (User, error) {
+	if name := c.Session["user"]; name != "" {
+		return name, nil
+	}
+	return nil, nil
+}
+
+func TestRequireAuth(t *testing.T) {
+	startFakeBookingApp()
+	DefaultAuthResolver = sessionAuthResolver{}
+	defer func() { DefaultAuthResolver = nil }()
+
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	c.Session = Session{"user": "alice"}
+	if user, result := c.RequireAuth(); user != "alice" || result != nil {
+		t.Errorf("Expected the logged-in user, got %v %v", user, result)
+	}
+
+	resp := httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Session = Session{}
+	c.Request.Format = "html"
+	_, result := c.RequireAuth()
+	result.Apply(c.Request, c.Response)
+	if resp.Code != http.StatusFound || resp.Header().Get("Location") != "/login?next=%2Fhotels%2F3" {
+		t.Errorf("Expected a redirect to the login page, got %d %v", resp.Code, resp.Header())
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.Session = Session{}
+	c.Request.Format = "json"
+	_, result = c.RequireAuth()
+	result.Apply(c.Request, c.Response)
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 for an API request, got %d", resp.Code)
+	}
+}
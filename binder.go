@@ -7,6 +7,7 @@ import (
 	"mime/multipart"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -20,15 +21,16 @@ type Binder struct {
 	// Example
 	//
 	// Request:
-	//   url?id=123&ol[0]=1&ol[1]=2&ul[]=str&ul[]=array&user.Name=rob
+	//   url?id=123&ol[0]=1&ol[1]=2&ul[]=str&ul[]=array&rl=4&rl=x&rl=5&user.Name=rob
 	//
 	// Action:
-	//   Example.Action(id int, ol []int, ul []string, user User)
+	//   Example.Action(id int, ol []int, ul []string, rl []int, user User)
 	//
 	// Calls:
 	//   Bind(params, "id", int): 123
 	//   Bind(params, "ol", []int): {1, 2}
 	//   Bind(params, "ul", []string): {"str", "array"}
+	//   Bind(params, "rl", []int): {4, 5} (and a BindError for "x")
 	//   Bind(params, "user", User): User{Name:"rob"}
 	//
 	// Note that only exported struct fields may be bound.
@@ -40,16 +42,41 @@ type Binder struct {
 }
 
 // An adapter for easily making one-key-value binders.
+// If f returns an invalid reflect.Value (i.e. reflect.Value{}), the value could
+// not be converted: a BindError is recorded on the Params and the zero value of
+// the type is used instead.
 func ValueBinder(f func(value string, typ reflect.Type) reflect.Value) func(*Params, string, reflect.Type) reflect.Value {
 	return func(params *Params, name string, typ reflect.Type) reflect.Value {
 		vals, ok := params.Values[name]
 		if !ok || len(vals) == 0 {
 			return reflect.Zero(typ)
 		}
-		return f(vals[0], typ)
+		// A blank value (e.g. "?page=" or an empty form field) binds to the
+		// zero value, rather than being reported as invalid.
+		if vals[0] == "" {
+			return reflect.Zero(typ)
+		}
+		result := f(vals[0], typ)
+		if !result.IsValid() {
+			params.bindErrors = append(params.bindErrors, &BindError{name, vals[0], typ})
+			return reflect.Zero(typ)
+		}
+		return result
 	}
 }
 
+// A BindError describes a parameter value that could not be converted to the
+// requested type.
+type BindError struct {
+	Name  string       // The parameter name, e.g. "id" or "user.Age"
+	Value string       // The value that failed to convert, e.g. "abc"
+	Type  reflect.Type // The type it was bound to, e.g. int
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("%q is not a valid %s", e.Value, e.Type)
+}
+
 const (
 	DEFAULT_DATE_FORMAT     = "2006-01-02"
 	DEFAULT_DATETIME_FORMAT = "2006-01-02 15:04"
@@ -62,9 +89,21 @@ var (
 	KindBinders = make(map[reflect.Kind]Binder)
 
 	// Applications can add custom time formats to this array, and they will be
-	// automatically attempted when binding a time.Time.
+	// automatically attempted when binding a time.Time.  On start, the formats
+	// configured by "format.datetime", "format.date", and "format.times" (a
+	// list separated by "|") are added, followed by RFC 3339 and ISO 8601
+	// dates, e.g. "2014-03-01T10:00:00Z" and "2014-03-01".  Values that match
+	// none of them are also accepted as Unix timestamps of 9 or 10 digits,
+	// e.g. "1393668000" (but not "20140301", which needs a "20060102" layout).
+	//
+	// A struct field may be bound with a layout of its own instead, e.g.
+	//   From time.Time `layout:"01/2006"`
 	TimeFormats = []string{}
 
+	// Unix timestamps from 1973 to 2286, so that a compact date (e.g.
+	// "20140301") is not taken for one.
+	unixTimestampPattern = regexp.MustCompile(`^[0-9]{9,10}$`)
+
 	DateFormat     string
 	DateTimeFormat string
 
@@ -76,7 +115,7 @@ var (
 			intValue, err := strconv.ParseInt(val, 10, 64)
 			if err != nil {
 				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetInt(intValue)
@@ -95,7 +134,7 @@ var (
 			uintValue, err := strconv.ParseUint(val, 10, 64)
 			if err != nil {
 				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetUint(uintValue)
@@ -114,7 +153,7 @@ var (
 			floatValue, err := strconv.ParseFloat(val, 64)
 			if err != nil {
 				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetFloat(floatValue)
@@ -164,12 +203,19 @@ var (
 
 	TimeBinder = Binder{
 		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
+			if len(val) == 0 {
+				return reflect.Zero(typ)
+			}
 			for _, f := range TimeFormats {
 				if r, err := time.Parse(f, val); err == nil {
 					return reflect.ValueOf(r)
 				}
 			}
-			return reflect.Zero(typ)
+			if unixTimestampPattern.MatchString(val) {
+				sec, _ := strconv.ParseInt(val, 10, 64)
+				return reflect.ValueOf(time.Unix(sec, 0))
+			}
+			return reflect.Value{}
 		}),
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			var (
@@ -226,10 +272,28 @@ func init() {
 	OnAppStart(func() {
 		DateTimeFormat = Config.StringDefault("format.datetime", DEFAULT_DATETIME_FORMAT)
 		DateFormat = Config.StringDefault("format.date", DEFAULT_DATE_FORMAT)
-		TimeFormats = append(TimeFormats, DateTimeFormat, DateFormat)
+		addTimeFormat(DateTimeFormat)
+		addTimeFormat(DateFormat)
+		for _, f := range strings.Split(Config.StringDefault("format.times", ""), "|") {
+			if f = strings.TrimSpace(f); f != "" {
+				addTimeFormat(f)
+			}
+		}
+		addTimeFormat(time.RFC3339)
+		addTimeFormat(DEFAULT_DATE_FORMAT)
 	})
 }
 
+// addTimeFormat appends the layout to TimeFormats, unless it is already there.
+func addTimeFormat(layout string) {
+	for _, f := range TimeFormats {
+		if f == layout {
+			return
+		}
+	}
+	TimeFormats = append(TimeFormats, layout)
+}
+
 // Used to keep track of the index for individual keyvalues.
 type sliceValue struct {
 	index int           // Index extracted from brackets.  If -1, no index was provided.
@@ -239,7 +303,8 @@ type sliceValue struct {
 // This function creates a slice of the given type, Binds each of the individual
 // elements, and then sets them to their appropriate location in the slice.
 // If elements are provided without an explicit index, they are added (in
-// unspecified order) to the end of the slice.
+// unspecified order) to the end of the slice.  A blank value is no element,
+// whether indexed (ids[2]=), un-indexed (ids[]=), or repeated (ids=).
 func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	// Collect an array of slice elements with their indexes (and the max index).
 	maxIndex := -1
@@ -262,6 +327,9 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 
 		// Handle the indexed case.
 		if index > -1 {
+			if subKeyIndex == len(key) && len(vals) > 0 && vals[0] == "" {
+				return
+			}
 			if index > maxIndex {
 				maxIndex = index
 			}
@@ -275,11 +343,13 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		// It's an un-indexed element.  (e.g. element[])
 		numNoIndex += len(vals) + len(files)
 		for _, val := range vals {
+			if val == "" {
+				continue
+			}
 			// Unindexed values can only be direct-bound.
-			sliceValues = append(sliceValues, sliceValue{
-				index: -1,
-				value: BindValue(val, typ.Elem()),
-			})
+			if value, ok := bindSliceValue(params, key, val, typ.Elem()); ok {
+				sliceValues = append(sliceValues, sliceValue{index: -1, value: value})
+			}
 		}
 
 		for _, fileHeader := range files {
@@ -297,6 +367,20 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		processElement(key, nil, fileHeaders)
 	}
 
+	// A repeated param is also un-indexed, e.g. ?id=1&id=2 (but not for
+	// elements that are bound from several params, such as structs).
+	if vals := params.Values[name]; len(vals) > 0 && isScalarType(typ.Elem()) {
+		numNoIndex += len(vals)
+		for _, val := range vals {
+			if val == "" {
+				continue
+			}
+			if value, ok := bindSliceValue(params, name, val, typ.Elem()); ok {
+				sliceValues = append(sliceValues, sliceValue{index: -1, value: value})
+			}
+		}
+	}
+
 	resultArray := reflect.MakeSlice(typ, maxIndex+1, maxIndex+1+numNoIndex)
 	for _, sv := range sliceValues {
 		if sv.index != -1 {
@@ -309,6 +393,31 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	return resultArray
 }
 
+// bindSliceValue binds one of the un-indexed values of a slice, given under
+// key.  If it can not be converted, a BindError is recorded on params, and
+// false is returned so that the element may be left out.
+func bindSliceValue(params *Params, key, val string, typ reflect.Type) (reflect.Value, bool) {
+	elementParams := &Params{Values: map[string][]string{key: {val}}}
+	value := Bind(elementParams, key, typ)
+	params.bindErrors = append(params.bindErrors, elementParams.bindErrors...)
+	return value, len(elementParams.bindErrors) == 0
+}
+
+// isScalarType returns true if values of the type are bound from a single
+// param, rather than several (as a struct, slice, or map is).
+func isScalarType(typ reflect.Type) bool {
+	if _, ok := TypeBinders[typ]; ok {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return isScalarType(typ.Elem())
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return false
+	}
+	return true
+}
+
 // Break on dots and brackets.
 // e.g. bar => "bar", bar.baz => "bar", bar[0] => "bar"
 func nextKey(key string) string {
@@ -342,16 +451,22 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 
 		if _, ok := fieldValues[fieldName]; !ok {
 			// Time to bind this field.  Get it and make sure we can set it.
-			fieldValue := result.FieldByName(fieldName)
-			if !fieldValue.IsValid() {
+			field, ok := fieldByParamName(typ, fieldName)
+			if !ok {
 				WARN.Println("W: bindStruct: Field not found:", fieldName)
 				continue
 			}
+			fieldValue := result.FieldByIndex(field.Index)
 			if !fieldValue.CanSet() {
 				WARN.Println("W: bindStruct: Field not settable:", fieldName)
 				continue
 			}
-			boundVal := Bind(params, key[:len(name)+1+fieldLen], fieldValue.Type())
+			var boundVal reflect.Value
+			if layout := field.Tag.Get("layout"); layout != "" {
+				boundVal = bindTimeLayout(params, key[:len(name)+1+fieldLen], fieldValue.Type(), layout)
+			} else {
+				boundVal = Bind(params, key[:len(name)+1+fieldLen], fieldValue.Type())
+			}
 			fieldValue.Set(boundVal)
 			fieldValues[fieldName] = boundVal
 		}
@@ -360,6 +475,66 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 	return result
 }
 
+// fieldByParamName returns the field of the struct type bound to the param
+// key: the field tagged with it, e.g. `form:"city"`, or else the field of that
+// name, ignoring case.  Fields tagged `form:"-"` are never bound.
+func fieldByParamName(typ reflect.Type, key string) (reflect.StructField, bool) {
+	var (
+		byName reflect.StructField
+		found  bool
+	)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		switch tag := field.Tag.Get("form"); {
+		case tag == key:
+			return field, true
+		case tag == "" && !found && strings.EqualFold(field.Name, key):
+			byName, found = field, true
+		case tag == "" && field.Name == key:
+			byName, found = field, true
+		}
+	}
+	if !found {
+		// e.g. a field promoted from an embedded struct.
+		if field, ok := typ.FieldByName(key); ok && field.Tag.Get("form") == "" {
+			return field, true
+		}
+	}
+	return byName, found
+}
+
+// bindTimeLayout binds a time.Time (or *time.Time) struct field with the
+// layout of its tag, rather than the TimeFormats.
+func bindTimeLayout(params *Params, name string, typ reflect.Type, layout string) reflect.Value {
+	if typ != timeType && typ != reflect.PtrTo(timeType) {
+		WARN.Println("W: bindStruct: layout tag on a field that is not a time.Time:", name)
+		return Bind(params, name, typ)
+	}
+	return ValueBinder(func(val string, typ reflect.Type) reflect.Value {
+		t, err := time.Parse(layout, val)
+		if err != nil {
+			return reflect.Value{}
+		}
+		if typ.Kind() == reflect.Ptr {
+			return reflect.ValueOf(&t)
+		}
+		return reflect.ValueOf(t)
+	})(params, name, typ)
+}
+
+// paramFieldName returns the param key that binds the struct field, or ""
+// if it is never bound.
+func paramFieldName(field reflect.StructField) string {
+	switch tag := field.Tag.Get("form"); tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return tag
+	}
+}
+
 func unbindStruct(output map[string]string, name string, iface interface{}) {
 	val := reflect.ValueOf(iface)
 	typ := val.Type()
@@ -368,8 +543,8 @@ func unbindStruct(output map[string]string, name string, iface interface{}) {
 		fieldValue := val.Field(i)
 
 		// PkgPath is specified to be empty exactly for exported fields.
-		if structField.PkgPath == "" {
-			Unbind(output, fmt.Sprintf("%s.%s", name, structField.Name), fieldValue.Interface())
+		if key := paramFieldName(structField); structField.PkgPath == "" && key != "" {
+			Unbind(output, fmt.Sprintf("%s.%s", name, key), fieldValue.Interface())
 		}
 	}
 }
@@ -364,3 +364,103 @@ func valEq(t *testing.T, name string, actual, expected reflect.Value) {
 		eq(t, name, actual.Interface(), expected.Interface())
 	}
 }
+
+func TestBindRepeatedParams(t *testing.T) {
+	params := &Params{Values: map[string][]string{
+		"ids":     {"1", "abc", "3", ""},
+		"names[]": {"a", "b"},
+		"dates":   {"1982-07-09", "07/09"},
+		"users":   {"rob"},
+	}}
+
+	var ids []int
+	params.Bind(&ids, "ids")
+	if !reflect.DeepEqual(ids, []int{1, 3}) {
+		t.Errorf("Expected the valid ids [1 3], got %v", ids)
+	}
+	if errs := params.bindErrorsFor("ids"); len(errs) != 1 || errs[0].Value != "abc" {
+		t.Errorf("Expected an error for abc, got %v", errs)
+	}
+
+	var names []string
+	params.Bind(&names, "names")
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("Expected the bracketed names, got %v", names)
+	}
+
+	var dates []time.Time
+	params.Bind(&dates, "dates")
+	if len(dates) != 1 || !dates[0].Equal(testDate) {
+		t.Errorf("Expected the valid date, got %v", dates)
+	}
+
+	// A blank value is skipped, however it is given.
+	for _, values := range []map[string][]string{
+		{"ids": {"1", ""}},
+		{"ids[]": {"1", ""}},
+		{"ids[0]": {"1"}, "ids[1]": {""}},
+	} {
+		var ids []int
+		(&Params{Values: values}).Bind(&ids, "ids")
+		if !reflect.DeepEqual(ids, []int{1}) {
+			t.Errorf("Expected [1] for %v, got %v", values, ids)
+		}
+	}
+
+	// A struct is bound from several params, so a repeated param is not one.
+	var users []A
+	params.Bind(&users, "users")
+	if len(users) != 0 {
+		t.Errorf("Expected no users, got %v", users)
+	}
+}
+
+type Report struct {
+	From  time.Time  `layout:"01/2006"`
+	Until *time.Time `form:"until" layout:"01/2006"`
+	At    time.Time
+}
+
+func TestBindTimeLayouts(t *testing.T) {
+	params := &Params{Values: map[string][]string{
+		"report.From":  {"03/2014"},
+		"report.until": {"04/2014"},
+		"report.At":    {"1393668000"},
+		"bad.From":     {"2014-03-01"},
+	}}
+
+	var report Report
+	if errs := params.BindStruct(&report, "report"); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if !report.From.Equal(time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)) ||
+		report.Until == nil || !report.Until.Equal(time.Date(2014, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the fields to be bound with their layout, got %v %v", report.From, report.Until)
+	}
+	if !report.At.Equal(time.Unix(1393668000, 0)) {
+		t.Errorf("Expected a Unix timestamp to be accepted, got %v", report.At)
+	}
+
+	// A value that does not match the layout is an error, not a zero time.
+	errs := params.BindStruct(&Report{}, "bad")
+	if len(errs) != 1 || errs[0].Name != "bad.From" {
+		t.Errorf("Expected an error for bad.From, got %v", errs)
+	}
+
+	// A compact date is not taken for a Unix timestamp.
+	params = &Params{Values: map[string][]string{"report.At": {"20140301"}}}
+	if errs := params.BindStruct(&report, "report"); len(errs) != 1 {
+		t.Errorf("Expected an error for a compact date, got %v (%v)", errs, report.At)
+	}
+}
+
+func TestTimeFormatsDeduped(t *testing.T) {
+	startFakeBookingApp()
+	seen := map[string]bool{}
+	for _, f := range TimeFormats {
+		if seen[f] {
+			t.Errorf("Duplicate layout %q in %v", f, TimeFormats)
+		}
+		seen[f] = true
+	}
+}
@@ -0,0 +1,66 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ErrBodyTooLarge is returned for request bodies larger than the limit set by
+// "http.maxbodysize".
+var ErrBodyTooLarge = fmt.Errorf("revel/request: body too large")
+
+// RawBody returns the request body, e.g. for verifying the signature of a
+// webhook.  The body is read once, and kept for later calls (and DecodeJSON).
+// Bodies larger than "http.maxbodysize" (10MB by default) are not read, to
+// guard against exhausting memory, and ErrBodyTooLarge is returned.
+//
+// Form bodies are consumed by the ParamsFilter, so they are not available.
+func (req *Request) RawBody() ([]byte, error) {
+	if req.bodyRead {
+		return req.body, req.bodyErr
+	}
+	req.bodyRead = true
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	limit := int64(Config.IntDefault("http.maxbodysize", 10<<20))
+	if req.ContentLength > limit {
+		req.bodyErr = ErrBodyTooLarge
+		return nil, req.bodyErr
+	}
+	req.body, req.bodyErr = ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	if req.bodyErr == nil && int64(len(req.body)) > limit {
+		req.body, req.bodyErr = nil, ErrBodyTooLarge
+	}
+	return req.body, req.bodyErr
+}
+
+// DecodeJSON unmarshals the JSON request body into dest.  Malformed JSON is
+// reported with the offset of the error, e.g.
+//   revel/request: invalid JSON at byte 12: invalid character '}' looking for beginning of value
+// for clients to be told what is wrong with their request:
+//   var booking models.Booking
+//   if err := c.Request.DecodeJSON(&booking); err != nil {
+//     return c.BadRequest("%s", err)
+//   }
+func (req *Request) DecodeJSON(dest interface{}) error {
+	body, err := req.RawBody()
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return fmt.Errorf("revel/request: empty JSON body")
+	}
+	if err = json.Unmarshal(body, dest); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			return fmt.Errorf("revel/request: invalid JSON at byte %d: %s", syntaxErr.Offset, err)
+		}
+		return fmt.Errorf("revel/request: invalid JSON: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package revel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newBodyRequest(body string) *Request {
+	req, _ := http.NewRequest("POST", "/hotels/3/booking", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return NewRequest(req)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	startFakeBookingApp()
+	var booking struct {
+		Hotel  int
+		Nights int
+	}
+	req := newBodyRequest(`{"Hotel": 3, "Nights": 2}`)
+	if err := req.DecodeJSON(&booking); err != nil || booking.Hotel != 3 || booking.Nights != 2 {
+		t.Errorf("Failed to decode the body: %v %+v", err, booking)
+	}
+	if body, err := req.RawBody(); err != nil || string(body) != `{"Hotel": 3, "Nights": 2}` {
+		t.Errorf("Expected the raw body to remain available, got %q %v", body, err)
+	}
+
+	err := newBodyRequest(`{"Hotel": 3,}`).DecodeJSON(&booking)
+	if err == nil || !strings.Contains(err.Error(), "at byte 13") {
+		t.Errorf("Expected the offset of the syntax error, got %v", err)
+	}
+	if err = newBodyRequest("").DecodeJSON(&booking); err == nil {
+		t.Errorf("Expected an error for an empty body")
+	}
+}
+
+func TestRawBodyTooLarge(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("http.maxbodysize", "8")
+	defer Config.SetOption("http.maxbodysize", "10485760")
+
+	for _, body := range []string{"12345678", "123456789"} {
+		req := newBodyRequest(body)
+		req.ContentLength = -1 // Not known up front, e.g. chunked.
+		raw, err := req.RawBody()
+		if len(body) <= 8 && (err != nil || string(raw) != body) {
+			t.Errorf("%s: expected the body, got %q %v", body, raw, err)
+		}
+		if len(body) > 8 && err != ErrBodyTooLarge {
+			t.Errorf("%s: expected ErrBodyTooLarge, got %q %v", body, raw, err)
+		}
+	}
+	if _, err := newBodyRequest("123456789").RawBody(); err != ErrBodyTooLarge {
+		t.Errorf("Expected the Content-Length to be checked, got %v", err)
+	}
+}
@@ -50,4 +50,11 @@ func init() {
 		// By default, use the in-memory cache.
 		Instance = NewInMemoryCache(defaultExpiration)
 	})
+
+	// Keep sessions in the cache?
+	revel.OnAppStart(func() {
+		if revel.Config.StringDefault("session.store", "cookie") == "cache" {
+			revel.SessionStorage = SessionStore{Instance}
+		}
+	})
 }
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"github.com/robfig/revel"
+	"strconv"
+	"time"
+)
+
+// SessionStore keeps Revel sessions in a Cache, such as Redis or memcached, so
+// that they are shared by all of the application's processes.  Set
+// "session.store = cache" in app.conf to use the configured cache, or install
+// one directly:
+//   revel.SessionStorage = cache.NewRedisSessionStore("localhost:6379", "")
+type SessionStore struct {
+	Cache Cache
+}
+
+// NewRedisSessionStore returns a SessionStore backed by the given Redis host.
+func NewRedisSessionStore(host, password string) SessionStore {
+	return SessionStore{NewRedisCache(host, password, DEFAULT)}
+}
+
+func sessionKey(id string) string {
+	return "revel_session:" + id
+}
+
+func (s SessionStore) Get(id string) (revel.Session, error) {
+	var session revel.Session
+	if err := s.Cache.Get(sessionKey(id), &session); err != nil {
+		if err == ErrCacheMiss {
+			return make(revel.Session), nil
+		}
+		return nil, err
+	}
+	if session == nil {
+		session = make(revel.Session)
+	}
+	return session, nil
+}
+
+// Save caches the session until it expires, or for the cache's default
+// expiration if it lasts for the browser session.
+func (s SessionStore) Save(id string, session revel.Session) error {
+	expires := DEFAULT
+	if ts, err := strconv.ParseInt(session[revel.TS_KEY], 10, 64); err == nil {
+		if expires = time.Unix(ts, 0).Sub(time.Now()); expires <= 0 {
+			return s.Delete(id)
+		}
+	}
+	return s.Cache.Set(sessionKey(id), session, expires)
+}
+
+func (s SessionStore) Delete(id string) error {
+	if err := s.Cache.Delete(sessionKey(id)); err != nil && err != ErrCacheMiss {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package revel
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// The proxies (e.g. load balancers) trusted to report the client's address in
+// the X-Forwarded-For or X-Real-IP header.  Configured by "http.trustedproxies"
+// in app.conf, as a list of IP addresses or CIDR ranges separated by spaces or
+// commas, e.g. "10.0.0.0/8, 192.168.1.10".  None are trusted by default.
+var trustedProxies []*net.IPNet
+
+func init() {
+	OnAppStart(func() {
+		trustedProxies = nil
+		list := Config.StringDefault("http.trustedproxies", "")
+		for _, proxy := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if !strings.Contains(proxy, "/") {
+				if strings.Contains(proxy, ":") {
+					proxy += "/128"
+				} else {
+					proxy += "/32"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(proxy)
+			if err != nil {
+				panic(fmt.Errorf("http.trustedproxies invalid: %s", err))
+			}
+			trustedProxies = append(trustedProxies, ipNet)
+		}
+	})
+}
+
+// ClientIP returns the IP address of the client.  If the request came through
+// a trusted proxy (see "http.trustedproxies"), that is the address the proxy
+// reports: the last in X-Forwarded-For that is not itself a trusted proxy, or
+// else X-Real-IP.  Otherwise, it is the address of the connection, since the
+// headers may be set by anyone.
+func (c *Controller) ClientIP() string {
+	peer := c.Request.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !isTrustedProxy(peer) {
+		return peer
+	}
+
+	// Each proxy appends the address it received the request from, so the
+	// client is the last one that was not added by a trusted proxy.
+	var forwardedFor []string
+	for _, header := range c.Request.Header["X-Forwarded-For"] {
+		forwardedFor = append(forwardedFor, strings.Split(header, ",")...)
+	}
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwardedFor[i])
+		if net.ParseIP(addr) == nil {
+			break
+		}
+		if !isTrustedProxy(addr) || i == 0 {
+			return addr
+		}
+	}
+
+	if realIP := strings.TrimSpace(c.Request.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return peer
+}
+
+// isTrustedProxy returns true if the address is one of the trustedProxies.
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
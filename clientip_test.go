@@ -0,0 +1,44 @@
+package revel
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	defer func() { trustedProxies = nil }()
+	for _, cidr := range []string{"10.0.0.0/8", "192.168.1.10/32"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		trustedProxies = append(trustedProxies, ipNet)
+	}
+
+	for _, test := range []struct {
+		remoteAddr, forwardedFor, realIP, expected string
+	}{
+		{"203.0.113.5:1234", "", "", "203.0.113.5"},
+		// Headers from an untrusted peer are ignored.
+		{"203.0.113.5:1234", "1.2.3.4", "1.2.3.4", "203.0.113.5"},
+		{"10.1.2.3:80", "198.51.100.7", "", "198.51.100.7"},
+		{"10.1.2.3:80", "", "198.51.100.7", "198.51.100.7"},
+		// A client can not spoof its address by prepending to the header.
+		{"10.1.2.3:80", "1.2.3.4, 198.51.100.7, 192.168.1.10", "", "198.51.100.7"},
+		{"10.1.2.3:80", "10.0.0.1, 10.0.0.2", "", "10.0.0.1"},
+		{"10.1.2.3:80", "garbage", "", "10.1.2.3"},
+		{"[2001:db8::1]:443", "198.51.100.7", "", "2001:db8::1"},
+	} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+		if test.realIP != "" {
+			req.Header.Set("X-Real-IP", test.realIP)
+		}
+		c := NewController(NewRequest(req), nil)
+		if ip := c.ClientIP(); ip != test.expected {
+			t.Errorf("%s, X-Forwarded-For %q, X-Real-IP %q: expected %s, got %s",
+				test.remoteAddr, test.forwardedFor, test.realIP, test.expected, ip)
+		}
+	}
+}
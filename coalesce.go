@@ -0,0 +1,140 @@
+package revel
+
+import (
+	"sync"
+)
+
+// CoalescingFilter returns a filter that coalesces identical concurrent GET
+// requests: while one request is being processed, others with the same key
+// wait for it to complete and share its response, rather than repeating the
+// computation.  It is intended for expensive, cacheable endpoints that are hit
+// by many clients at once.
+//
+// Requests are coalesced if keyFunc returns the same key for them, and are
+// processed independently if it returns "".  If keyFunc is nil, CoalescingKey
+// is used.  Cookies set by the shared response are not passed on to the
+// waiting requests, which keep their own session and flash.
+//
+// Requests for which Controller.NoResultCache has been called neither wait on
+// nor share a response.
+//
+// The default key only coalesces requests from the same session, since the
+// response may be personalized.  For responses that do not depend on the user,
+// a key without the session lets all clients share them, for example:
+//   revel.FilterAction(Reports.Summary).
+//     Add(revel.CoalescingFilter(func(c *revel.Controller) string {
+//       return c.Request.Method + " " + c.Request.URL.RequestURI() + " " + c.Request.Format
+//     }))
+func CoalescingFilter(keyFunc func(c *Controller) string) Filter {
+	if keyFunc == nil {
+		keyFunc = CoalescingKey
+	}
+	return (&requestCoalescer{
+		keyFunc: keyFunc,
+		calls:   make(map[string]*coalescedCall),
+	}).Filter
+}
+
+// CoalescingKey is the default key for CoalescingFilter.  It identifies the
+// request by its method, path, query string, format, and locale, and by the
+// session and flash cookies that it bears.
+// Requests bearing an Authorization header are not coalesced.
+func CoalescingKey(c *Controller) string {
+	if c.Request.Header.Get("Authorization") != "" {
+		return ""
+	}
+	key := c.Request.Method + " " + c.Request.URL.RequestURI() +
+		" " + c.Request.Format + " " + c.Request.Locale
+	if cookie, err := readChunkedCookie(c.Request.Request, CookiePrefix+"_SESSION"); err == nil {
+		key += " " + cookie.Value
+	}
+	if cookie, err := c.Request.Cookie(CookiePrefix + "_FLASH"); err == nil {
+		key += " " + cookie.Value
+	}
+	return key
+}
+
+type requestCoalescer struct {
+	keyFunc func(c *Controller) string
+	mu      sync.Mutex
+	calls   map[string]*coalescedCall
+}
+
+// A coalescedCall is a request in progress, and those waiting on it.
+type coalescedCall struct {
+	done chan struct{}
+	resp *RecordedResponse // nil if the request produced no response.
+}
+
+func (rc *requestCoalescer) Filter(c *Controller, fc []Filter) {
+	var key string
+	if c.Request.Method == "GET" && !c.ResultCacheBypassed() {
+		key = rc.keyFunc(c)
+	}
+	if key == "" {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	rc.mu.Lock()
+	if call, ok := rc.calls[key]; ok {
+		rc.mu.Unlock()
+		<-call.done
+		if call.resp != nil {
+			c.Result = call.resp
+		} else {
+			fc[0](c, fc[1:])
+		}
+		return
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	rc.calls[key] = call
+	rc.mu.Unlock()
+
+	// If the action panics, produces no result, bypasses the cache, or its
+	// result is replaced (e.g. by an interceptor) and never applied, let the
+	// waiters proceed on their own.
+	result := &coalescedResult{finish: func(resp *RecordedResponse) {
+		rc.mu.Lock()
+		delete(rc.calls, key)
+		rc.mu.Unlock()
+		call.resp = resp
+		close(call.done)
+	}}
+	c.addCleanup(func() {
+		if !result.done {
+			result.finish(nil)
+		}
+	})
+
+	fc[0](c, fc[1:])
+	if c.Result != nil && !c.ResultCacheBypassed() {
+		result.Result = c.Result
+		c.Result = result
+	}
+}
+
+// coalescedResult records the response of the wrapped Result as it is
+// applied, and shares it with the waiting requests.
+type coalescedResult struct {
+	Result
+	finish func(*RecordedResponse)
+	done   bool // Set once the response has been shared.
+}
+
+func (r *coalescedResult) Apply(req *Request, resp *Response) {
+	recorder := newResponseRecorder(resp.Out)
+	resp.Out = recorder
+	completed := false
+	defer func() {
+		resp.Out = recorder.ResponseWriter
+		if completed {
+			r.finish(recorder.SharedResponse())
+		} else {
+			r.finish(nil)
+		}
+		r.done = true
+	}()
+	r.Result.Apply(req, resp)
+	completed = true
+}
@@ -0,0 +1,154 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCoalescingController(cookie string) (*Controller, *httptest.ResponseRecorder) {
+	httpReq, _ := http.NewRequest("GET", "/report?year=2014", nil)
+	if cookie != "" {
+		httpReq.AddCookie(&http.Cookie{Name: CookiePrefix + "_SESSION", Value: cookie})
+	}
+	resp := httptest.NewRecorder()
+	return NewController(NewRequest(httpReq), NewResponse(resp)), resp
+}
+
+func TestCoalescingFilter(t *testing.T) {
+	startFakeBookingApp()
+	var (
+		rc = &requestCoalescer{
+			keyFunc: CoalescingKey,
+			calls:   make(map[string]*coalescedCall),
+		}
+		computations = 0
+		started      = make(chan struct{}, 10)
+		release      = make(chan struct{})
+		report       = func(c *Controller, _ []Filter) {
+			computations++
+			started <- struct{}{}
+			<-release
+			c.SetCookie(&http.Cookie{Name: CookiePrefix + "_FLASH", Value: "leader"})
+			c.Result = c.RenderText("report %d", computations)
+		}
+	)
+
+	serve := func() *httptest.ResponseRecorder {
+		c, resp := newCoalescingController("")
+		rc.Filter(c, []Filter{report})
+		c.Result.Apply(c.Request, c.Response)
+		c.runCleanups()
+		return resp
+	}
+
+	leader, waiter := make(chan *httptest.ResponseRecorder), make(chan *httptest.ResponseRecorder)
+	go func() { leader <- serve() }()
+	<-started
+	go func() { waiter <- serve() }()
+	waitForCoalescedRequest(t)
+	close(release)
+
+	first, second := <-leader, <-waiter
+	if first.Body.String() != "report 1" || second.Body.String() != "report 1" {
+		t.Errorf("Expected a shared response, got %q and %q", first.Body.String(), second.Body.String())
+	}
+	if computations != 1 {
+		t.Errorf("Expected the action to be invoked once, got %d", computations)
+	}
+	if second.Header().Get("Set-Cookie") != "" {
+		t.Errorf("Expected cookies not to be shared, got %v", second.Header())
+	}
+	if len(rc.calls) != 0 {
+		t.Errorf("Expected the call to be finished")
+	}
+}
+
+// waitForCoalescedRequest waits until a request is blocked in the Filter,
+// waiting on another with the same key.
+func waitForCoalescedRequest(t *testing.T) {
+	buf := make([]byte, 1<<20)
+	for start := time.Now(); time.Since(start) < 5*time.Second; runtime.Gosched() {
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		for _, stack := range strings.Split(stacks, "\n\n") {
+			// The state is on the first line, and the innermost call on the next.
+			lines := strings.SplitN(stack, "\n", 3)
+			if len(lines) > 1 && strings.Contains(lines[0], "[chan receive") &&
+				strings.Contains(lines[1], "(*requestCoalescer).Filter") {
+				return
+			}
+		}
+	}
+	t.Fatal("Timed out waiting for the request to be coalesced")
+}
+
+func TestCoalescingKey(t *testing.T) {
+	startFakeBookingApp()
+	alice, _ := newCoalescingController("alice")
+	bob, _ := newCoalescingController("bob")
+	if CoalescingKey(alice) == CoalescingKey(bob) {
+		t.Errorf("Expected requests from different sessions not to be coalesced")
+	}
+
+	authorized, _ := newCoalescingController("")
+	authorized.Request.Header.Set("Authorization", "Bearer x")
+	if CoalescingKey(authorized) != "" {
+		t.Errorf("Expected authorized requests not to be coalesced")
+	}
+}
+
+func TestCoalescingFilterReplacedResult(t *testing.T) {
+	startFakeBookingApp()
+	rc := &requestCoalescer{
+		keyFunc: CoalescingKey,
+		calls:   make(map[string]*coalescedCall),
+	}
+	c, _ := newCoalescingController("")
+	rc.Filter(c, []Filter{func(c *Controller, _ []Filter) {
+		c.Result = c.RenderText("report")
+	}})
+
+	// An interceptor replaces the result, so the recording one is never applied.
+	c.Result = c.RenderText("replaced")
+	c.Result.Apply(c.Request, c.Response)
+	c.runCleanups()
+	if len(rc.calls) != 0 {
+		t.Errorf("Expected the call to be released")
+	}
+}
+
+func TestCoalescingFilterNoResultCache(t *testing.T) {
+	startFakeBookingApp()
+	rc := &requestCoalescer{
+		keyFunc: CoalescingKey,
+		calls:   make(map[string]*coalescedCall),
+	}
+	c, resp := newCoalescingController("")
+	rc.Filter(c, []Filter{func(c *Controller, _ []Filter) {
+		c.NoResultCache()
+		c.Result = c.RenderText("preview")
+	}})
+	if _, ok := c.Result.(*coalescedResult); ok {
+		t.Errorf("Expected the result not to be shared")
+	}
+	c.Result.Apply(c.Request, c.Response)
+	c.runCleanups()
+	if resp.Body.String() != "preview" || len(rc.calls) != 0 {
+		t.Errorf("Expected the call to be released, got %q", resp.Body.String())
+	}
+
+	// A request that bypasses the cache beforehand does not wait on others.
+	// (Were it to wait, on a call that never finishes, it would hang.)
+	rc.calls[CoalescingKey(c)] = &coalescedCall{done: make(chan struct{})}
+	c, _ = newCoalescingController("")
+	c.NoResultCache()
+	rc.Filter(c, []Filter{func(c *Controller, _ []Filter) {
+		c.Result = c.RenderText("preview")
+	}})
+	if c.Result == nil {
+		t.Errorf("Expected the request to be served on its own")
+	}
+}
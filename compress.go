@@ -3,6 +3,7 @@ package revel
 import (
 	"compress/gzip"
 	"compress/zlib"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -23,6 +24,7 @@ var compressableMimes = [...]string{
 	"application/rss+xml",
 	"application/javascript",
 	"application/x-javascript",
+	"application/json",
 }
 
 type WriteFlusher interface {
@@ -35,60 +37,155 @@ type CompressResponseWriter struct {
 	compressWriter  WriteFlusher
 	compressionType string
 	headersWritten  bool
+	enabled         bool   // results.compressed is on, so the response varies by Accept-Encoding.
+	minSize         int    // Bodies shorter than this are sent uncompressed.
+	status          int    // The status held back until the body is long enough to decide.
+	buffer          []byte // The start of the body, held back until it reaches minSize.
 }
 
+// CompressFilter compresses the result with gzip or deflate when the client
+// accepts it, the content type is compressible, and the body is at least
+// results.compressed.minsize bytes (1024 by default).  Responses that already
+// carry a Content-Encoding, such as pre-compressed files from RenderBinary,
+// are passed through untouched.
 func CompressFilter(c *Controller, fc []Filter) {
-	writer := CompressResponseWriter{c.Response.Out, nil, "", false}
+	writer := CompressResponseWriter{
+		ResponseWriter: c.Response.Out,
+		minSize:        Config.IntDefault("results.compressed.minsize", 1024),
+	}
 	writer.DetectCompressionType(c.Request, c.Response)
 	c.Response.Out = &writer
+	c.addCleanup(func() { writer.Close() })
 
 	fc[0](c, fc[1:])
 }
 
-func (c *CompressResponseWriter) prepareHeaders() {
-	if c.compressionType != "" {
-		responseMime := c.Header().Get("Content-Type")
-		responseMime = strings.TrimSpace(strings.SplitN(responseMime, ";", 2)[0])
-		shouldEncode := false
-
-		for _, compressableMime := range compressableMimes {
-			if responseMime == compressableMime {
-				shouldEncode = true
-				c.Header().Set("Content-Encoding", c.compressionType)
-				c.Header().Del("Content-Length")
-				break
-			}
-		}
+func (c *CompressResponseWriter) prepareHeaders(compress bool) {
+	if !c.enabled {
+		return
+	}
 
-		if !shouldEncode {
-			c.compressWriter = nil
-			c.compressionType = ""
+	responseMime := c.Header().Get("Content-Type")
+	responseMime = strings.TrimSpace(strings.SplitN(responseMime, ";", 2)[0])
+	compressable := false
+	for _, compressableMime := range compressableMimes {
+		if responseMime == compressableMime {
+			compressable = true
+			break
 		}
 	}
+
+	// Already-encoded bodies (e.g. a .gz file) must not be compressed twice.
+	if !compressable || c.Header().Get("Content-Encoding") != "" {
+		compress = false
+	} else {
+		c.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	if compress && c.compressionType != "" {
+		c.Header().Set("Content-Encoding", c.compressionType)
+		c.Header().Del("Content-Length")
+	} else {
+		c.compressWriter = nil
+		c.compressionType = ""
+	}
 }
 
-func (c *CompressResponseWriter) WriteHeader(status int) {
+func (c *CompressResponseWriter) writeHeader(status int, compress bool) {
 	c.headersWritten = true
-	c.prepareHeaders()
+	// A byte range is of the uncompressed content.
+	if status == http.StatusPartialContent {
+		compress = false
+	}
+	c.prepareHeaders(compress)
 	c.ResponseWriter.WriteHeader(status)
 }
 
+// holding reports whether the headers and body are being held back until it
+// is known whether the body reaches minSize.
+func (c *CompressResponseWriter) holding() bool {
+	return !c.headersWritten && c.compressionType != "" && c.minSize > 0
+}
+
+func (c *CompressResponseWriter) WriteHeader(status int) {
+	if c.headersWritten {
+		return
+	}
+	if c.holding() {
+		c.status = status
+		return
+	}
+	c.writeHeader(status, true)
+}
+
 func (c *CompressResponseWriter) Write(b []byte) (int, error) {
-	if !c.headersWritten {
-		c.prepareHeaders()
-		c.headersWritten = true
+	if c.holding() && len(c.buffer)+len(b) < c.minSize {
+		c.buffer = append(c.buffer, b...)
+		return len(b), nil
+	}
+	if err := c.release(true); err != nil {
+		return 0, err
+	}
+	return c.write(b)
+}
+
+// release sends the held headers and body, compressed if compress is set.
+func (c *CompressResponseWriter) release(compress bool) error {
+	if c.headersWritten {
+		return nil
 	}
+	status := c.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.writeHeader(status, compress)
+
+	buffered := c.buffer
+	c.buffer = nil
+	if len(buffered) > 0 {
+		if _, err := c.write(buffered); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+func (c *CompressResponseWriter) write(b []byte) (int, error) {
 	if c.compressionType != "" {
-		defer c.compressWriter.Flush()
 		return c.compressWriter.Write(b)
-	} else {
-		return c.ResponseWriter.Write(b)
 	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client, for streaming results.
+// A stream is compressed even if it has not yet reached minSize.
+func (c *CompressResponseWriter) Flush() {
+	c.release(true)
+	if c.compressWriter != nil {
+		c.compressWriter.Flush()
+	}
+	flushResponse(c.ResponseWriter)
+}
+
+// Close finishes the response: a body that never reached minSize is sent
+// uncompressed, and the compressor's trailer is written.
+func (c *CompressResponseWriter) Close() error {
+	if c.holding() && (c.status != 0 || len(c.buffer) > 0) {
+		if err := c.release(false); err != nil {
+			return err
+		}
+	}
+	if closer, ok := c.compressWriter.(io.Closer); ok && c.headersWritten {
+		c.compressWriter = nil
+		c.compressionType = ""
+		return closer.Close()
+	}
+	return nil
 }
 
 func (c *CompressResponseWriter) DetectCompressionType(req *Request, resp *Response) {
 	if Config.BoolDefault("results.compressed", false) {
+		c.enabled = true
 		acceptedEncodings := strings.Split(req.Request.Header.Get("Accept-Encoding"), ",")
 
 		largestQ := 0.0
@@ -1,6 +1,9 @@
 package revel
 
 import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -20,6 +23,64 @@ func TestBenchmarkCompressed(t *testing.T) {
 	}
 }
 
+// Test that the CompressFilter gzips large results, closes the stream, and
+// leaves small or already-encoded results alone.
+func TestCompressFilter(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.compressed", "true")
+	Config.SetOption("results.compressed.minsize", "100")
+	defer Config.SetOption("results.compressed", "false")
+
+	render := func(contentEncoding string, result Result) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		if contentEncoding != "" {
+			c.Response.Out.Header().Set("Content-Encoding", contentEncoding)
+		}
+		CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			result.Apply(c.Request, c.Response)
+		}})
+		c.runCleanups()
+		return resp
+	}
+
+	large := strings.Repeat("300 Main St. ", 50)
+	resp := render("", RenderTextResult{text: large})
+	if resp.HeaderMap.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", resp.HeaderMap.Get("Content-Encoding"))
+	}
+	if resp.HeaderMap.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", resp.HeaderMap.Get("Vary"))
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read the whole gzip stream: %s", err)
+	}
+	if string(body) != large {
+		t.Errorf("Unexpected decompressed body: %q", body)
+	}
+
+	resp = render("", RenderTextResult{text: "small"})
+	if resp.HeaderMap.Get("Content-Encoding") != "" || resp.Body.String() != "small" {
+		t.Errorf("Expected a small body to be sent as-is, got %q (%q)",
+			resp.Body.String(), resp.HeaderMap.Get("Content-Encoding"))
+	}
+	if resp.HeaderMap.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding on a small body, got %q", resp.HeaderMap.Get("Vary"))
+	}
+
+	resp = render("gzip", RenderTextResult{text: large})
+	if resp.Body.String() != large {
+		t.Errorf("Expected an already-encoded body to be passed through")
+	}
+}
+
 func BenchmarkRenderCompressed(b *testing.B) {
 	startFakeBookingApp()
 	resp := httptest.NewRecorder()
@@ -1,6 +1,9 @@
 package revel
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
@@ -8,7 +11,10 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -26,11 +32,19 @@ type Controller struct {
 	Result   Result
 
 	Flash      Flash                  // User cookie, cleared after 1 request.
-	Session    Session                // Session, stored in cookie, signed.
+	Session    Session                // Session, stored in cookie, signed (or in SessionStorage).
 	Params     *Params                // Parameters from URL and form (including multipart).
 	Args       map[string]interface{} // Per-request scratch space.
+	BoundArgs  map[string]interface{} // Pointers to the bound action arguments, for PostBind.
 	RenderArgs map[string]interface{} // Args passed to the template.
 	Validation *Validation            // Data validation helpers
+	Layout     string                 // Template that wraps rendered templates; see RenderWithLayout.
+
+	cleanups      []func() // Run once the response is complete; see addCleanup.
+	deferred      int      // The number of cleanups registered by Defer.
+	noResultCache bool     // Set by NoResultCache.
+	handlingError bool     // Set while an ErrorHandler runs; see RenderError.
+	requestId     string   // Set by RequestID.
 }
 
 func NewController(req *Request, resp *Response) *Controller {
@@ -56,7 +70,18 @@ func (c *Controller) SetCookie(cookie *http.Cookie) {
 	http.SetCookie(c.Response.Out, cookie)
 }
 
+// RenderError renders the error page for the response status (500 if none is
+// set), using the ErrorHandler registered for it, if any.  Within the handler,
+// RenderError (and NotFound, etc) render the default error page.
 func (c *Controller) RenderError(err error) Result {
+	if handler := errorHandlerFor(c.Response.Status); handler != nil && !c.handlingError {
+		c.handlingError = true
+		result := handler(c, err)
+		c.handlingError = false
+		if result != nil {
+			return result
+		}
+	}
 	return ErrorResult{c.RenderArgs, err}
 }
 
@@ -73,9 +98,38 @@ func (c *Controller) RenderError(err error) Result {
 //
 // This action will render views/Users/ShowUser.html, passing in an extra
 // key-value "user": (User).
+//
+// The names are found by the line of the Render call in the action's source,
+// which is recorded when the app is built.  They can not be found if Render is
+// called from outside an action, e.g. in a helper function or closure, in
+// which case the arguments are dropped and an error is logged.  Use
+// RenderNamed there instead.
 func (c *Controller) Render(extraRenderArgs ...interface{}) Result {
-	// Get the calling function name.
-	_, _, line, ok := runtime.Caller(1)
+	c.setRenderArgs(extraRenderArgs)
+	return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
+}
+
+// RenderFormat is like Render, but renders the action's template for the
+// given format rather than the request's, e.g. the text body of an email:
+//   func (c Bookings) Confirm(id int) revel.Result {
+//     booking := loadBooking(id)
+//     return c.RenderFormat("txt", booking)  // views/Bookings/Confirm.txt
+//   }
+// The response has the content type of the format (unless one is set).
+func (c *Controller) RenderFormat(format string, extraRenderArgs ...interface{}) Result {
+	c.setRenderArgs(extraRenderArgs)
+	if contentType := ContentTypeByFilename("xxx." + format); c.Response.ContentType == "" &&
+		contentType != DefaultFileContentType {
+		c.Response.ContentType = contentType
+	}
+	return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + format)
+}
+
+// setRenderArgs adds the extra render args passed to Render or RenderFormat to
+// c.RenderArgs, named by the line of the call in the action's source.
+func (c *Controller) setRenderArgs(extraRenderArgs []interface{}) {
+	// Get the line of the call to Render.
+	_, _, line, ok := runtime.Caller(2)
 	if !ok {
 		ERROR.Println("Failed to get Caller information")
 	}
@@ -84,7 +138,7 @@ func (c *Controller) Render(extraRenderArgs ...interface{}) Result {
 	if renderArgNames, ok := c.MethodType.RenderArgNames[line]; ok {
 		if len(renderArgNames) == len(extraRenderArgs) {
 			for i, extraRenderArg := range extraRenderArgs {
-				c.RenderArgs[renderArgNames[i]] = extraRenderArg
+				c.mergeRenderArg(renderArgNames[i], extraRenderArg, renderArgsOverwrite)
 			}
 		} else {
 			ERROR.Println(len(renderArgNames), "RenderArg names found for",
@@ -94,13 +148,172 @@ func (c *Controller) Render(extraRenderArgs ...interface{}) Result {
 		ERROR.Println("No RenderArg names found for Render call on line", line,
 			"(Action", c.Action, ")")
 	}
+}
 
+// RenderNamed is like Render, but with the render args named explicitly, so
+// that it may be called from anywhere, e.g. a helper shared by actions:
+//   func (c Users) renderUser(user *User) revel.Result {
+//     return c.RenderNamed(map[string]interface{}{"user": user})
+//   }
+func (c *Controller) RenderNamed(renderArgs map[string]interface{}) Result {
+	for name, value := range renderArgs {
+		c.mergeRenderArg(name, value, renderArgsOverwrite)
+	}
 	return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
 }
 
+// How the args given to Render (and RenderFormat and RenderNamed) are merged
+// into those already set, e.g. defaults set by an interceptor.  Configured by
+// "results.renderargs" in app.conf:
+//   overwrite - (the default) the action's args replace those already set.
+//   keep      - the args already set are kept.
+// If "results.renderargs.warn" is true, each collision is logged in dev mode.
+var (
+	renderArgsOverwrite    = true
+	warnRenderArgCollision = false
+)
+
+func init() {
+	OnAppStart(func() {
+		switch merge := Config.StringDefault("results.renderargs", "overwrite"); merge {
+		case "overwrite", "keep":
+			renderArgsOverwrite = merge == "overwrite"
+		default:
+			panic(fmt.Errorf("results.renderargs invalid: %s (must be overwrite or keep)", merge))
+		}
+		warnRenderArgCollision = Config.BoolDefault("results.renderargs.warn", false)
+	})
+}
+
+// AddRenderArgs adds the args to c.RenderArgs, except those already set, e.g.
+// for an interceptor to supply defaults that actions may refine:
+//   c.AddRenderArgs(map[string]interface{}{"title": "Hotels", "user": c.connected()})
+// To replace them, set c.RenderArgs directly.
+func (c *Controller) AddRenderArgs(args map[string]interface{}) {
+	for name, value := range args {
+		c.mergeRenderArg(name, value, false)
+	}
+}
+
+// mergeRenderArg sets the render arg, unless it is already set and overwrite
+// is false, and warns of the collision if configured to.
+func (c *Controller) mergeRenderArg(name string, value interface{}, overwrite bool) {
+	if _, ok := c.RenderArgs[name]; ok {
+		if warnRenderArgCollision && DevMode {
+			action := "kept the existing value"
+			if overwrite {
+				action = "replaced the existing value"
+			}
+			WARN.Printf("Render arg %q of %s is already set; %s", name, c.Action, action)
+		}
+		if !overwrite {
+			return
+		}
+	}
+	c.RenderArgs[name] = value
+}
+
+// RenderAuto renders o in the format requested by the client: as JSON or XML
+// for those formats, or otherwise by the action's template, with o available
+// as "data".  If the client accepted a vendor media type with a +json or +xml
+// suffix, such as "application/vnd.api+json", the response has that type.
+//   func (c Users) Show(id int) revel.Result {
+//     return c.RenderAuto(loadUser(id))
+//   }
+func (c *Controller) RenderAuto(o interface{}) Result {
+	switch c.Request.Format {
+	case "json":
+		c.setSuffixContentType("+json")
+		return c.RenderJson(o)
+	case "xml":
+		c.setSuffixContentType("+xml")
+		return c.RenderXml(o)
+	}
+	c.RenderArgs["data"] = o
+	return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
+}
+
+// RenderNegotiated renders o as JSON, XML, or HTML (by the action's template,
+// like RenderAuto), whichever the client prefers according to the quality
+// values of its Accept header.  For example, "application/json;q=0.9,
+// text/html" gets HTML.  Without an Accept header, or for */*, the request
+// format is used.
+// If the client accepts none of them, the result is a 406 Not Acceptable.
+func (c *Controller) RenderNegotiated(o interface{}) Result {
+	acceptTypes := ResolveAcceptTypes(c.Request.Request)
+	if len(acceptTypes) == 0 {
+		return c.RenderAuto(o)
+	}
+
+	// Formats may be refused explicitly, with a quality of 0.
+	refused := make(map[string]bool)
+	for _, acceptType := range acceptTypes {
+		if acceptType.Quality <= 0 && acceptType.MediaType != "*/*" {
+			refused[negotiatedFormat(acceptType.MediaType)] = true
+		}
+	}
+	for _, acceptType := range acceptTypes {
+		if acceptType.Quality <= 0 {
+			continue
+		}
+		formats := []string{negotiatedFormat(acceptType.MediaType)}
+		if acceptType.MediaType == "*/*" {
+			formats = []string{c.Request.Format, "html", "json", "xml"}
+			if formats[0] != "json" && formats[0] != "xml" {
+				formats[0] = "html"
+			}
+		}
+		for _, format := range formats {
+			if format != "" && !refused[format] {
+				c.Request.Format = format
+				return c.RenderAuto(o)
+			}
+		}
+	}
+
+	c.Response.Status = http.StatusNotAcceptable
+	return c.RenderError(&Error{
+		Title:       "Not Acceptable",
+		Description: "The resource is available as JSON, XML, or HTML",
+	})
+}
+
+// negotiatedFormat returns the format that serves the media range, or "" if
+// there is none.
+func negotiatedFormat(mediaType string) string {
+	switch {
+	case mediaType == "text/html", mediaType == "application/xhtml+xml", mediaType == "text/*":
+		return "html"
+	case mediaType == "application/json", mediaType == "text/javascript",
+		mediaType == "application/*", strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return "xml"
+	}
+	return ""
+}
+
+// setSuffixContentType sets the response Content-Type to the vendor media type
+// with the given suffix that the client accepted, if any.
+func (c *Controller) setSuffixContentType(suffix string) {
+	if c.Response.ContentType != "" {
+		return
+	}
+	if mediaType := acceptedSuffixType(c.Request.Header.Get("Accept"), suffix); mediaType != "" {
+		c.Response.ContentType = mediaType + "; charset=utf-8"
+	}
+}
+
 // A less magical way to render a template.
 // Renders the given template, using the current RenderArgs.
+//
+// If c.Layout is set, the template is wrapped in that layout, as with
+// RenderWithLayout, provided both have the same extension (so that e.g.
+// "Hotels/Show.json" is not wrapped in "layouts/main.html").
 func (c *Controller) RenderTemplate(templatePath string) Result {
+	if c.Layout != "" && filepath.Ext(c.Layout) == filepath.Ext(templatePath) {
+		return c.RenderWithLayout(c.Layout, templatePath)
+	}
 
 	// Get the Template.
 	template, err := MainTemplateLoader.Template(templatePath)
@@ -114,19 +327,305 @@ func (c *Controller) RenderTemplate(templatePath string) Result {
 	}
 }
 
-// Uses encoding/json.Marshal to return JSON to the client.
+// RenderStatic renders the template as RenderTemplate does, for a page that
+// changes only with its template and the data last modified at modTime (zero
+// if the page has no data).  The response has a Last-Modified of the later of
+// the two, so that a client that has the page is sent a 304 Not Modified
+// rather than it being rendered again.  The page must not depend on the user,
+// e.g. by their session or flash.
+//   return c.RenderStatic("Pages/About.html", page.Updated)
+func (c *Controller) RenderStatic(templatePath string, modTime time.Time) Result {
+	result := c.RenderTemplate(templatePath)
+	if r, ok := result.(*RenderTemplateResult); ok {
+		for _, template := range []Template{r.Template, r.Layout} {
+			if template == nil {
+				continue
+			}
+			if t := MainTemplateLoader.ModTime(template.Name()); t.After(modTime) {
+				modTime = t
+			}
+		}
+		r.ModTime = modTime
+	}
+	return result
+}
+
+// Render304NotModified responds 304 Not Modified, for an action that checks
+// the request's If-Modified-Since (or If-None-Match) itself.
+func (c *Controller) Render304NotModified() Result {
+	return RenderStatusResult{http.StatusNotModified}
+}
+
+// RenderTemplateString renders the template with the given args (or
+// c.RenderArgs, if nil) and returns the output, rather than responding with it,
+// e.g. for the body of an email:
+//   body, err := c.RenderTemplateString("Mail/Welcome.txt", map[string]interface{}{"user": user})
+func (c *Controller) RenderTemplateString(templatePath string, args map[string]interface{}) (string, error) {
+	template, err := MainTemplateLoader.Template(templatePath)
+	if err != nil {
+		return "", err
+	}
+	if args == nil {
+		args = c.RenderArgs
+	}
+	var b bytes.Buffer
+	if err = template.Render(&b, args); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// RenderWithLayout renders the template, and then the layout template with the
+// output of the first in place of {{content .}}, e.g. "layouts/main.html":
+//   <html>
+//     <head><title>{{.title}}</title></head>
+//     <body>{{template "header.html" .}}{{content .}}</body>
+//   </html>
+// Values set by the template (e.g. {{set . "title" "Hotels"}}) are available
+// to the layout.  To use a layout for every template an action renders,
+// including by Render, set c.Layout instead, e.g. in a BEFORE interceptor.
+func (c *Controller) RenderWithLayout(layout, templatePath string) Result {
+	template, err := MainTemplateLoader.Template(templatePath)
+	if err != nil {
+		return c.RenderError(err)
+	}
+	layoutTemplate, err := MainTemplateLoader.Template(layout)
+	if err != nil {
+		return c.RenderError(err)
+	}
+
+	return &RenderTemplateResult{
+		Template:   template,
+		Layout:     layoutTemplate,
+		RenderArgs: c.RenderArgs,
+	}
+}
+
+// Uses encoding/json to return JSON to the client.
 func (c *Controller) RenderJson(o interface{}) Result {
-	return RenderJsonResult{o, ""}
+	return RenderJsonResult{obj: o, action: c.Action}
+}
+
+// RenderJsonWith is like RenderJson, but uses the given options instead of
+// the app-wide ones.
+func (c *Controller) RenderJsonWith(o interface{}, options JsonOptions) Result {
+	return RenderJsonResult{obj: o, options: &options, action: c.Action}
 }
 
-// Renders a JSONP result using encoding/json.Marshal
+// RenderJsonPretty is like RenderJson, but always indents the JSON, whatever
+// the run mode.  (RenderJson indents it in dev mode, or if "results.pretty"
+// is set.)
+func (c *Controller) RenderJsonPretty(o interface{}) Result {
+	options := DefaultJsonOptions()
+	options.Pretty = true
+	return RenderJsonResult{obj: o, options: &options, action: c.Action}
+}
+
+// RenderJsonError renders validation errors as JSON, in the same shape for
+// every action:
+//   {"errors": [{"field": "booking.CheckInDate", "message": "Required"}]}
+// If no errors are given, those of c.Validation are rendered.  The status
+// defaults to 422 Unprocessable Entity if zero.
+//   if c.Validation.HasErrors() {
+//     return c.RenderJsonError(0)
+//   }
+func (c *Controller) RenderJsonError(status int, errs ...*ValidationError) Result {
+	if len(errs) == 0 {
+		errs = c.Validation.Errors
+	}
+	if status == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	body := jsonErrors{Errors: make([]jsonError, 0, len(errs))}
+	for _, err := range errs {
+		body.Errors = append(body.Errors, jsonError{Field: err.Key, Message: err.Message})
+	}
+	return c.RenderWithStatus(status, c.RenderJson(body))
+}
+
+// jsonErrors is the body rendered by RenderJsonError.
+type jsonErrors struct {
+	Errors []jsonError `json:"errors"`
+}
+
+type jsonError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Renders a JSONP result using encoding/json.Marshal, as
+// application/javascript.  Since the callback usually comes from the request,
+// it must be a JavaScript identifier or property path (e.g. "jQuery123" or
+// "app.onLoad"), so that it can not inject script; otherwise the response is a
+// 400 Bad Request.
 func (c *Controller) RenderJsonP(callback string, o interface{}) Result {
-	return RenderJsonResult{o, callback}
+	if !jsonpCallbackPattern.MatchString(callback) {
+		return c.BadRequest("Invalid JSONP callback")
+	}
+	return RenderJsonResult{obj: o, callback: callback, action: c.Action}
+}
+
+var jsonpCallbackPattern = regexp.MustCompile(`^[a-zA-Z_$][\w$.]*$`)
+
+// RenderJsonStream renders a JSON array whose elements are received from the
+// channel, writing each as it arrives, so that a large collection need not be
+// held in memory.  The array is complete once the channel is closed.
+// If an element can not be rendered, the connection is closed, so that the
+// client does not take the partial array for the whole.
+//   ch := make(chan interface{})
+//   go func() {
+//     defer close(ch)
+//     for rows.Next() {
+//       ch <- row
+//     }
+//   }()
+//   return c.RenderJsonStream(ch)
+func (c *Controller) RenderJsonStream(ch <-chan interface{}) Result {
+	return RenderJsonStreamResult{ch: ch}
 }
 
-// Uses encoding/xml.Marshal to return XML to the client.
+// RenderJsonObjectStream renders a JSON object whose members are received
+// from the channel, writing each as it arrives, so that a large keyed dataset
+// need not be held in memory.  The object is complete once the channel is
+// closed.  For example:
+//   ch := make(chan revel.KV)
+//   go func() {
+//     defer close(ch)
+//     for rows.Next() {
+//       ch <- revel.KV{row.Id, row}
+//     }
+//   }()
+//   return c.RenderJsonObjectStream(ch)
+func (c *Controller) RenderJsonObjectStream(ch <-chan KV) Result {
+	return RenderJsonObjectStreamResult{ch: ch}
+}
+
+// RenderJsonObjectStreamTee is like RenderJsonObjectStream, but also copies
+// the object to tee as it is sent, e.g. to warm a cache.  The tee is written
+// from another goroutine so that it does not hold up the client; if it falls
+// too far behind, it is dropped and closed with an error.
+func (c *Controller) RenderJsonObjectStreamTee(ch <-chan KV, tee ResponseTee) Result {
+	return RenderJsonObjectStreamResult{ch: ch, tee: tee}
+}
+
+// RenderEventStream renders a stream of server-sent events, each carrying the
+// JSON of a value received from the channel.  The stream ends when the channel
+// is closed.  If the client goes away, the rest of the channel is discarded.
+func (c *Controller) RenderEventStream(updates <-chan interface{}) Result {
+	return &RenderEventStreamResult{updates: updates}
+}
+
+// RenderLive serves both polling and streaming clients from one action.
+// Clients that accept text/event-stream get the snapshot followed by the
+// updates as server-sent events; others get the snapshot as JSON, and the
+// updates are discarded.  For example:
+//   func (c Dashboard) Stats() revel.Result {
+//     return c.RenderLive(currentStats, subscribeStats())
+//   }
+// The snapshot is taken only once the format is known.
+func (c *Controller) RenderLive(snapshot func() interface{}, updates <-chan interface{}) Result {
+	if !strings.Contains(c.Request.Header.Get("Accept"), "text/event-stream") {
+		go discard(updates)
+		return c.RenderJson(snapshot())
+	}
+	return &RenderEventStreamResult{snapshot: snapshot, updates: updates}
+}
+
+// RenderJsonObjectStreamWith is like RenderJsonObjectStream, but sends the
+// object according to the options: buffering it in larger writes, and telling
+// the producer when to stop if the client goes away.  For example:
+//   ch, done := make(chan revel.KV), make(chan struct{})
+//   go export(ch, done)
+//   return c.RenderJsonObjectStreamWith(ch, revel.StreamOptions{
+//     BufferSize: 32 << 10,
+//     Done:       done,
+//   })
+func (c *Controller) RenderJsonObjectStreamWith(ch <-chan KV, options StreamOptions) Result {
+	return RenderJsonObjectStreamResult{ch: ch, options: options}
+}
+
+// Uses encoding/xml.Marshal to return XML to the client, after the standard
+// XML declaration (xml.Header).  Like RenderJson, it is indented in dev mode.
 func (c *Controller) RenderXml(o interface{}) Result {
-	return RenderXmlResult{o}
+	return RenderXmlResult{obj: o, header: xml.Header}
+}
+
+// RenderXmlWithHeader is like RenderXml, but writes the given header in place
+// of the standard XML declaration, or none if it is empty.  For example:
+//   return c.RenderXmlWithHeader(feed, xml.Header+`<?xml-stylesheet href="/public/feed.xsl" type="text/xsl"?>`)
+func (c *Controller) RenderXmlWithHeader(o interface{}, header string) Result {
+	return RenderXmlResult{obj: o, header: header}
+}
+
+// RenderXmlWith is like RenderXml, but sets the name and namespace
+// declarations of the root element.  For example:
+//   return c.RenderXmlWith(track, revel.XmlOptions{
+//     RootName:   "gpx",
+//     Namespaces: map[string]string{"": "http://www.topografix.com/GPX/1/1"},
+//   })
+func (c *Controller) RenderXmlWith(o interface{}, options XmlOptions) Result {
+	return RenderXmlResult{obj: o, options: &options, header: xml.Header}
+}
+
+// RenderYaml renders o as YAML, naming struct fields by their yaml tags.
+// Unlike RenderJson, it is always indented (in block style), which is what
+// YAML is for.
+func (c *Controller) RenderYaml(o interface{}) Result {
+	return RenderYamlResult{obj: o}
+}
+
+// RenderMsgPack streams o as MessagePack (application/msgpack), for clients
+// that want smaller payloads than JSON.  It takes the same values as
+// RenderJson: struct fields are named by their msgpack tags, or else their
+// json tags.  (See gopkg.in/vmihailenco/msgpack.v4.)
+//   return c.RenderMsgPack(hotels)
+func (c *Controller) RenderMsgPack(o interface{}) Result {
+	return RenderMsgPackResult{obj: o}
+}
+
+// RenderCsv streams the records as a CSV attachment, suggesting the given
+// filename if it is not empty.  The records are a [][]string or a slice of
+// structs; see RenderCsvResult.
+//   return c.RenderCsv(bookings, "bookings.csv")
+func (c *Controller) RenderCsv(records interface{}, filename string) Result {
+	return RenderCsvResult{records: records, options: CsvOptions{Filename: filename}}
+}
+
+// RenderCsvWith is like RenderCsv, but with the given options, e.g. a
+// different delimiter:
+//   return c.RenderCsvWith(rows, revel.CsvOptions{Filename: "report.csv", Comma: ';'})
+func (c *Controller) RenderCsvWith(records interface{}, options CsvOptions) Result {
+	return RenderCsvResult{records: records, options: options}
+}
+
+// RenderRss renders the feed as an RSS 2.0 document.
+// The feed and each item must have a Title and Link.
+func (c *Controller) RenderRss(feed Feed) Result {
+	return FeedResult{Feed: feed}
+}
+
+// RenderAtom renders the feed as an Atom document.
+// The feed and each item must have a Title and Link.
+func (c *Controller) RenderAtom(feed Feed) Result {
+	return FeedResult{Feed: feed, Atom: true}
+}
+
+// RenderProtobuf writes the message in the protobuf binary encoding, for
+// clients that speak protobuf rather than JSON.  A nil message is sent as an
+// empty one.  See ProtoMarshaler for messages that do not marshal themselves.
+func (c *Controller) RenderProtobuf(msg ProtoMarshaler) Result {
+	return RenderProtobufResult{msg}
+}
+
+// RenderGrpcWeb writes the message and status in the gRPC-Web wire format, for
+// browser clients of gRPC services.  The message is omitted (and may be nil)
+// if the status is not GrpcOK; with GrpcOK, a nil message is sent as an empty
+// one.  The statusMessage describes an error to the
+// client, and may be empty.  A message that fails to marshal is reported with
+// the Internal status.  For example:
+//   return c.RenderGrpcWeb(nil, int(codes.NotFound), "no such hotel")
+func (c *Controller) RenderGrpcWeb(msg ProtoMarshaler, status int, statusMessage string) Result {
+	return RenderGrpcWebResult{msg, status, statusMessage}
 }
 
 // Render plaintext in response, printf style.
@@ -135,7 +634,18 @@ func (c *Controller) RenderText(text string, objs ...interface{}) Result {
 	if len(objs) > 0 {
 		finalText = fmt.Sprintf(text, objs...)
 	}
-	return &RenderTextResult{finalText}
+	return &RenderTextResult{text: finalText}
+}
+
+// Render text in response with the given content type, printf style.
+// For example:
+//   c.RenderTextAs("text/csv; charset=utf-8", "%s,%d\n", name, count)
+func (c *Controller) RenderTextAs(contentType, text string, objs ...interface{}) Result {
+	finalText := text
+	if len(objs) > 0 {
+		finalText = fmt.Sprintf(text, objs...)
+	}
+	return &RenderTextResult{finalText, contentType}
 }
 
 // Render html in response
@@ -143,6 +653,108 @@ func (c *Controller) RenderHtml(html string) Result {
 	return &RenderHtmlResult{html}
 }
 
+// RenderStatus responds with the status alone, and no body, e.g.:
+//   return c.RenderStatus(http.StatusAccepted)
+func (c *Controller) RenderStatus(code int) Result {
+	return RenderStatusResult{code}
+}
+
+// NoContent responds 204 No Content, e.g. to a successful DELETE.
+func (c *Controller) NoContent() Result {
+	return RenderStatusResult{http.StatusNoContent}
+}
+
+// RenderAccepted responds 202 Accepted, for requests that start work that will
+// complete asynchronously.  The Location and Content-Location headers are set
+// to statusLocation, the resource that reports on the progress of the work.
+// If a body is given, it is rendered as JSON.  For example:
+//   job := jobs.Submit(report)
+//   return c.RenderAccepted(routes.Jobs.Status(job.Id), job)
+func (c *Controller) RenderAccepted(statusLocation string, body ...interface{}) Result {
+	if statusLocation == "" {
+		return c.InternalServerError("RenderAccepted requires the location of the status resource")
+	}
+	c.Response.Status = http.StatusAccepted
+	result := &AcceptedResult{Location: statusLocation}
+	if len(body) > 0 {
+		result.Body = body[0]
+	}
+	return result
+}
+
+// SetStatus sets the HTTP status of the response, e.g. to 201 Created.
+// It is honored by the results that render a body: templates, RenderHtml,
+// RenderJson (and JsonP), RenderXml, RenderYaml, RenderCsv, RenderProtobuf,
+// RenderText, RenderBinary of a stream, and RenderAccepted.  Error results use
+// it as the error status.  RenderJson sends no body with a 204 No Content.
+// RenderWithStatus does the same for a single result, and wins over SetStatus.
+//
+// Results that define their own status ignore it: redirects (which use 302
+// unless another 3xx is set), RenderFile and RenderBinary of an
+// io.ReadSeeker (which answer 200, 206, or 304 as the request requires), and
+// RenderGrpcWeb (always 200).
+func (c *Controller) SetStatus(code int) {
+	c.Response.Status = code
+}
+
+// RenderWithStatus applies result with the given status, e.g.:
+//   return c.RenderWithStatus(http.StatusCreated, c.RenderJson(booking))
+//   return c.RenderWithStatus(422, c.RenderJson(c.Validation.ErrorMap()))
+// The status replaces any set by SetStatus, since it is set when the result is
+// applied.  Like SetStatus, it is ignored by results that define their own
+// status, such as redirects.
+func (c *Controller) RenderWithStatus(status int, result Result) Result {
+	return statusResult{status: status, result: result}
+}
+
+// RenderCached applies result with headers that let the client and shared
+// caches (e.g. a CDN) keep the response for maxAge: "Cache-Control: public,
+// max-age=N" and the equivalent Expires, e.g.:
+//   return c.RenderCached(time.Hour, c.RenderJson(hotels))
+// Responses to authenticated requests (with an Authorization header, or values
+// in the session) are marked private instead, to be kept only by the client.
+//
+// Once maxAge has passed, a client holding an ETag (e.g. from RenderJson of a
+// Versioned value, or RenderFile) revalidates with If-None-Match, and gets a
+// 304 Not Modified with the same headers if the content is unchanged.
+func (c *Controller) RenderCached(maxAge time.Duration, result Result) Result {
+	visibility := "public"
+	if c.isAuthenticated() {
+		visibility = "private"
+	}
+	seconds := int64(maxAge / time.Second)
+	return cacheControlResult{
+		cacheControl: fmt.Sprintf("%s, max-age=%d", visibility, seconds),
+		expires:      time.Now().Add(time.Duration(seconds) * time.Second),
+		result:       result,
+	}
+}
+
+// NoCache applies result with headers that forbid caching the response
+// ("Cache-Control: no-store, no-cache", and an Expires in the past), e.g. for
+// pages showing account details.
+func (c *Controller) NoCache(result Result) Result {
+	return cacheControlResult{
+		cacheControl: "no-store, no-cache",
+		expires:      time.Unix(0, 0),
+		result:       result,
+	}
+}
+
+// isAuthenticated returns true if the request carries credentials, either in
+// an Authorization header or in the session.
+func (c *Controller) isAuthenticated() bool {
+	if c.Request.Header.Get("Authorization") != "" {
+		return true
+	}
+	for key := range c.Session {
+		if !isSessionMetaKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Render a "todo" indicating that the action isn't done yet.
 func (c *Controller) Todo() Result {
 	c.Response.Status = http.StatusNotImplemented
@@ -152,6 +764,18 @@ func (c *Controller) Todo() Result {
 	})
 }
 
+func (c *Controller) BadRequest(msg string, objs ...interface{}) Result {
+	finalText := msg
+	if len(objs) > 0 {
+		finalText = fmt.Sprintf(msg, objs...)
+	}
+	c.Response.Status = http.StatusBadRequest
+	return c.RenderError(&Error{
+		Title:       "Bad Request",
+		Description: finalText,
+	})
+}
+
 func (c *Controller) NotFound(msg string, objs ...interface{}) Result {
 	finalText := msg
 	if len(objs) > 0 {
@@ -188,6 +812,46 @@ func (c *Controller) Forbidden(msg string, objs ...interface{}) Result {
 	})
 }
 
+// TooManyRequests responds 429 Too Many Requests, telling the client how long
+// to wait before retrying, both in the Retry-After header (in seconds) and, as
+// "retryAfter", in the error page.
+//   if !limiter.Allow(c.Session.Id()) {
+//     return c.TooManyRequests(30*time.Second, "Slow down")
+//   }
+func (c *Controller) TooManyRequests(retryAfter time.Duration, msg string, objs ...interface{}) Result {
+	seconds := retryAfterSeconds(retryAfter)
+	c.Response.Out.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.tooManyRequests(seconds, msg, objs)
+}
+
+// TooManyRequestsUntil is like TooManyRequests, but gives the time at which
+// the client may retry, as an HTTP-date in the Retry-After header.
+func (c *Controller) TooManyRequestsUntil(retryAt time.Time, msg string, objs ...interface{}) Result {
+	c.Response.Out.Header().Set("Retry-After", retryAt.UTC().Format(http.TimeFormat))
+	return c.tooManyRequests(retryAfterSeconds(retryAt.Sub(time.Now())), msg, objs)
+}
+
+func (c *Controller) tooManyRequests(seconds int, msg string, objs []interface{}) Result {
+	finalText := msg
+	if len(objs) > 0 {
+		finalText = fmt.Sprintf(msg, objs...)
+	}
+	c.Response.Status = http.StatusTooManyRequests
+	c.RenderArgs["retryAfter"] = seconds
+	return c.RenderError(&Error{
+		Title:       "Too Many Requests",
+		Description: finalText,
+	})
+}
+
+// retryAfterSeconds returns the delay in whole seconds, rounded up.
+func retryAfterSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
 // Return a file, either displayed inline or downloaded as an attachment.
 // The name and size are taken from the file info.
 func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Result {
@@ -209,6 +873,15 @@ func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Resu
 // the output from some function, or bytes streamed from somewhere else, as long
 // it implements io.Reader).  When called directly on something generated or
 // streamed, modtime should mostly likely be time.Now().
+//
+// The Content-Type is inferred from the extension of filename, or else by
+// sniffing the content.  To override it, set c.Response.ContentType.
+//
+// If memfile is an io.ReadSeeker (as a file is), it is served by
+// http.ServeContent, which answers Range requests with 206 Partial Content,
+// e.g. for seeking in audio and video, or an inline PDF.  Other readers are
+// sent in full.  If memfile is a file, it is not read beyond what is sent:
+// a zero modtime is taken from the file.
 func (c *Controller) RenderBinary(memfile io.Reader, filename string, delivery ContentDisposition, modtime time.Time) Result {
 	return &BinaryResult{
 		Reader:   memfile,
@@ -219,6 +892,29 @@ func (c *Controller) RenderBinary(memfile io.Reader, filename string, delivery C
 	}
 }
 
+// RenderBinaryWithLength is like RenderBinary, but for a stream of a known
+// length, which is sent as the Content-Length (e.g. so that browsers can show
+// the progress of a download).  Exactly length bytes are sent; a warning is
+// logged if the reader has more or fewer.
+func (c *Controller) RenderBinaryWithLength(memfile io.Reader, filename string, delivery ContentDisposition, modtime time.Time, length int64) Result {
+	return &BinaryResult{
+		Reader:   memfile,
+		Name:     filename,
+		Delivery: delivery,
+		Length:   length,
+		ModTime:  modtime,
+	}
+}
+
+// RenderReader streams the reader to the client inline, as the given content
+// type, without the filename and Content-Disposition of RenderBinary.  For
+// example, to show a generated chart in an <img>:
+//   return c.RenderReader(chart.PNG(), "image/png")
+// The reader is closed when done, if it is an io.Closer.
+func (c *Controller) RenderReader(r io.Reader, contentType string) Result {
+	return &ReaderResult{Reader: r, ContentType: contentType}
+}
+
 // Redirect to an action or to a URL.
 //   c.Redirect(Controller.Action)
 //   c.Redirect("/controller/action")
@@ -233,6 +929,154 @@ func (c *Controller) Redirect(val interface{}, args ...interface{}) Result {
 	return &RedirectToActionResult{val}
 }
 
+// RedirectToAction redirects to the named action, with the params filling in
+// its route, or else added to the query string:
+//   return c.RedirectToAction("Hotels", "Show", map[string]interface{}{"id": hotel.HotelId})
+// If the action is not registered, or has no route, an error is logged and
+// rendered instead.  (Redirect, given a method such as Hotels.Show, is
+// checked the same way when the result is applied.)
+func (c *Controller) RedirectToAction(controllerName, methodName string, params map[string]interface{}) Result {
+	url, err := actionUrl(controllerName, methodName, params)
+	if err != nil {
+		ERROR.Println("Couldn't resolve redirect:", err)
+		return c.RenderError(err)
+	}
+	return &RedirectToUrlResult{url}
+}
+
+// RedirectStatus is like Redirect, but with the given status: 301 Moved
+// Permanently, 302 Found, 303 See Other, 307 Temporary Redirect, or 308
+// Permanent Redirect (307 and 308 preserve the method and body of a POST).
+// Any other status is logged and replaced by 302, as Redirect uses.
+//   c.RedirectStatus(http.StatusMovedPermanently, "/hotels/%d", id)
+func (c *Controller) RedirectStatus(code int, val interface{}, args ...interface{}) Result {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		WARN.Printf("Invalid redirect status %d; using 302 Found", code)
+		code = http.StatusFound
+	}
+	return statusResult{status: code, result: c.Redirect(val, args...)}
+}
+
+// NoResultCache marks the request as one whose response must neither be served
+// from nor stored in a result cache, e.g. for an editor previewing unpublished
+// content:
+//   func (c Pages) Show(slug string, preview bool) revel.Result {
+//     if preview {
+//       c.NoResultCache()
+//     }
+//     ...
+//   }
+// Cache filters check it with ResultCacheBypassed.  The response may not be
+// served from the cache if the flag is set only once the action is invoked,
+// so set it in an interceptor or filter to bypass reading the cache as well.
+func (c *Controller) NoResultCache() {
+	c.noResultCache = true
+}
+
+// ResultCacheBypassed returns true if NoResultCache was called for the request.
+func (c *Controller) ResultCacheBypassed() bool {
+	return c.noResultCache
+}
+
+// Context returns the context of the request, which is canceled when the
+// client disconnects, for passing to database drivers, HTTP clients, etc:
+//   rows, err := db.QueryContext(c.Context(), "SELECT ...")
+func (c *Controller) Context() context.Context {
+	return c.Request.Context()
+}
+
+// SetContext replaces the context of the request, e.g. so that an interceptor
+// may give the action a deadline, or attach values to be passed downstream.
+// It must be derived from Context, to keep the request's cancellation.
+func (c *Controller) SetContext(ctx context.Context) {
+	c.Request.Request = c.Request.WithContext(ctx)
+}
+
+// WithTimeout returns a context derived from Context that is also canceled
+// after d, e.g. to bound a call to a slow backend:
+//   ctx, cancel := c.WithTimeout(2 * time.Second)
+//   defer cancel()
+// It is canceled once the response is complete, if not before.
+func (c *Controller) WithTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(c.Context(), d)
+	c.addCleanup(cancel)
+	return ctx, cancel
+}
+
+// addCleanup registers f to run once the result has been applied (or the
+// request has failed), for filters that must release resources regardless of
+// what becomes of the result they set.
+func (c *Controller) addCleanup(f func()) {
+	c.cleanups = append(c.cleanups, f)
+}
+
+// runCleanups runs the registered cleanups, most recent first.
+func (c *Controller) runCleanups() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	c.cleanups, c.deferred = nil, 0
+}
+
+// Defer registers f to run once the response has been sent, whatever the
+// result, even if the action or the result panicked.  For example, an
+// interceptor may record the time taken by each request:
+//   start := time.Now()
+//   c.Defer(func() { metrics.Record(c.Action, time.Since(start)) })
+// The functions run most recent first, like deferred calls, after the body
+// has been written out and flushed to the client.  If one panics, the panic
+// is logged and the rest still run.
+func (c *Controller) Defer(f func()) {
+	// Deferred functions go before the other cleanups, so that they run last,
+	// once the response is complete.
+	c.cleanups = append(c.cleanups, nil)
+	copy(c.cleanups[c.deferred+1:], c.cleanups[c.deferred:])
+	c.cleanups[c.deferred] = func() {
+		defer func() {
+			if err := recover(); err != nil {
+				ERROR.Println("Deferred function panicked:", err)
+			}
+		}()
+		f()
+	}
+	c.deferred++
+}
+
+// GetArg returns c.Args[key] as a T.  It returns false if there is no such
+// value or it is not a T, rather than panicking as a failed type assertion
+// would:
+//   user, ok := revel.GetArg[*models.User](c, "user")
+//   if !ok {
+//     return c.Redirect(Application.Login)
+//   }
+// (Go methods can not take type parameters, so this is a function.)
+func GetArg[T any](c *Controller, key string) (T, bool) {
+	return getTypedArg[T](c.Args, key)
+}
+
+// SetArg sets c.Args[key] to v, for GetArg to read as a T.
+func SetArg[T any](c *Controller, key string, v T) {
+	c.Args[key] = v
+}
+
+// GetRenderArg is like GetArg, for c.RenderArgs.
+func GetRenderArg[T any](c *Controller, key string) (T, bool) {
+	return getTypedArg[T](c.RenderArgs, key)
+}
+
+// SetRenderArg is like SetArg, for c.RenderArgs.
+func SetRenderArg[T any](c *Controller, key string, v T) {
+	c.RenderArgs[key] = v
+}
+
+func getTypedArg[T any](args map[string]interface{}, key string) (T, bool) {
+	v, ok := args[key].(T)
+	return v, ok
+}
+
 // Perform a message lookup for the given message name using the given arguments
 // using the current language defined for this controller.
 //
@@ -243,6 +1087,10 @@ func (c *Controller) Message(message string, args ...interface{}) (value string)
 
 // SetAction sets the action that is being invoked in the current request.
 // It sets the following properties: Name, Action, Type, MethodType
+// The controller and method names are matched ignoring case, so that a route
+// to "hotels.show" (or /hotels/show, by a /:controller/:action route) invokes
+// Hotels.Show.  (The paths of routes are matched exactly; but see
+// "routes.trailingslash" for a trailing slash.)
 func (c *Controller) SetAction(controllerName, methodName string) error {
 
 	// Look up the controller and method types.
@@ -254,6 +1102,11 @@ func (c *Controller) SetAction(controllerName, methodName string) error {
 		return errors.New("revel/controller: failed to find action " + methodName)
 	}
 
+	// An alias is invoked as the method it names, e.g. for its template.
+	if !strings.EqualFold(methodName, c.MethodType.Name) {
+		methodName = c.MethodType.Name
+	}
+
 	c.Name, c.MethodName = c.Type.Type.Name(), methodName
 	c.Action = c.Name + "." + c.MethodName
 
@@ -336,6 +1189,9 @@ type MethodType struct {
 	Name           string
 	Args           []*MethodArg
 	RenderArgNames map[int][]string
+	Aliases        []string     // Other names that the method may be invoked by.
+	ReturnsError   bool         // True if the method returns (Result, error).
+	Middleware     []Middleware // Wraps the method, first outermost; see UseMiddleware.
 	lowerName      string
 }
 
@@ -344,7 +1200,16 @@ type MethodArg struct {
 	Type reflect.Type
 }
 
-// Searches for a given exported method (case insensitive)
+// MethodArgs returns the arguments of the named action method (or alias, case
+// insensitive), in order, or nil if there is no such method.
+func (ct *ControllerType) MethodArgs(name string) []*MethodArg {
+	if method := ct.Method(name); method != nil {
+		return method.Args
+	}
+	return nil
+}
+
+// Searches for a given exported method, by name or alias (case insensitive)
 func (ct *ControllerType) Method(name string) *MethodType {
 	lowerName := strings.ToLower(name)
 	for _, method := range ct.Methods {
@@ -352,11 +1217,109 @@ func (ct *ControllerType) Method(name string) *MethodType {
 			return method
 		}
 	}
+	for _, method := range ct.Methods {
+		for _, alias := range method.Aliases {
+			if strings.ToLower(alias) == lowerName {
+				return method
+			}
+		}
+	}
 	return nil
 }
 
+// Aliases to apply to methods as their controllers are registered, keyed by
+// lower-cased action, e.g. "application.index".
+var methodAliases = make(map[string][]string)
+
+// AliasAction registers other names by which an action may be invoked, e.g.
+// so that routes (and reverse routes) using the old name of a renamed action
+// keep working:
+//   func init() {
+//     revel.AliasAction("Application.Index", "Home")
+//   }
+// The action is invoked as itself, so it renders its own template.
+func AliasAction(action string, aliases ...string) {
+	lowerAction := strings.ToLower(action)
+	methodAliases[lowerAction] = append(methodAliases[lowerAction], aliases...)
+
+	// Apply them now if the controller has already been registered.
+	if dot := strings.Index(lowerAction, "."); dot != -1 {
+		if ct, ok := controllers[lowerAction[:dot]]; ok {
+			for _, m := range ct.Methods {
+				if m.lowerName == lowerAction[dot+1:] {
+					m.Aliases = append(m.Aliases, aliases...)
+				}
+			}
+		}
+	}
+}
+
+// A Middleware wraps the invocation of an action: it may run code before and
+// after it, by calling next (which returns the action's Result), or return a
+// Result of its own instead.
+type Middleware func(c *Controller, next func() Result) Result
+
+// Middleware to apply to methods as their controllers are registered, keyed by
+// lower-cased action, e.g. "hotels.book".
+var methodMiddleware = make(map[string][]Middleware)
+
+// UseMiddleware wraps an action in the given middleware, the first outermost.
+// Unlike filters, it runs only for that action, once its arguments are bound.
+// For example:
+//   func init() {
+//     revel.UseMiddleware("Hotels.Book", requireUser, logBooking)
+//   }
+//   func requireUser(c *revel.Controller, next func() revel.Result) revel.Result {
+//     if c.Session["user"] == "" {
+//       return c.Redirect(Application.Index)
+//     }
+//     return next()
+//   }
+// Middleware may also be given with the method to RegisterController.
+func UseMiddleware(action string, middleware ...Middleware) {
+	lowerAction := strings.ToLower(action)
+	methodMiddleware[lowerAction] = append(methodMiddleware[lowerAction], middleware...)
+
+	// Apply it now if the controller has already been registered.
+	if dot := strings.Index(lowerAction, "."); dot != -1 {
+		if ct, ok := controllers[lowerAction[:dot]]; ok {
+			for _, m := range ct.Methods {
+				if m.lowerName == lowerAction[dot+1:] {
+					m.Middleware = append(m.Middleware, middleware...)
+				}
+			}
+		}
+	}
+}
+
 var controllers = make(map[string]*ControllerType)
 
+// RegisteredControllers returns the registered controllers, sorted by name,
+// e.g. for tools that document the app's actions.  Each lists its action
+// methods, with the names and types of their arguments:
+//   for _, ct := range revel.RegisteredControllers() {
+//     for _, m := range ct.Methods {
+//       fmt.Println(ct.Type.Name() + "." + m.Name)
+//       for _, arg := range m.Args {
+//         fmt.Println("  ", arg.Name, arg.Type)
+//       }
+//     }
+//   }
+// The registry must not be modified.
+func RegisteredControllers() []*ControllerType {
+	names := make([]string, 0, len(controllers))
+	for name := range controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registered := make([]*ControllerType, len(names))
+	for i, name := range names {
+		registered[i] = controllers[name]
+	}
+	return registered
+}
+
 // Register a Controller and its Methods with Revel.
 func RegisterController(c interface{}, methods []*MethodType) {
 	// De-star the controller type
@@ -367,9 +1330,14 @@ func RegisterController(c interface{}, methods []*MethodType) {
 	// De-star all of the method arg types too.
 	for _, m := range methods {
 		m.lowerName = strings.ToLower(m.Name)
+		m.Aliases = append(m.Aliases, methodAliases[strings.ToLower(elem.Name())+"."+m.lowerName]...)
+		m.Middleware = append(m.Middleware, methodMiddleware[strings.ToLower(elem.Name())+"."+m.lowerName]...)
 		for _, arg := range m.Args {
 			arg.Type = arg.Type.Elem()
 		}
+		if method, ok := t.MethodByName(m.Name); ok {
+			m.ReturnsError = method.Type.NumOut() == 2 && method.Type.Out(1) == errorType
+		}
 	}
 
 	controllers[strings.ToLower(elem.Name())] = &ControllerType{
@@ -0,0 +1,200 @@
+package revel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetArg(t *testing.T) {
+	c := NewController(NewRequest(showRequest), NewResponse(nil))
+	SetArg(c, "user", &Hotel{Name: "Ann"})
+	SetArg(c, "count", 3)
+	SetArg[error](c, "err", fmt.Errorf("failed"))
+	SetRenderArg(c, "title", "Hotels")
+
+	if hotel, ok := GetArg[*Hotel](c, "user"); !ok || hotel.Name != "Ann" {
+		t.Errorf("Expected the hotel, got %v", hotel)
+	}
+	if count, ok := GetArg[int](c, "count"); !ok || count != 3 {
+		t.Errorf("Expected 3, got %d", count)
+	}
+	if err, ok := GetArg[error](c, "err"); !ok || err.Error() != "failed" {
+		t.Errorf("Expected the value as an interface, got %v", err)
+	}
+	if title, ok := GetRenderArg[string](c, "title"); !ok || title != "Hotels" {
+		t.Errorf("Expected the render arg, got %q", title)
+	}
+
+	// The raw maps hold the same values.
+	if c.Args["count"] != 3 || c.RenderArgs["title"] != "Hotels" {
+		t.Errorf("Expected the values in the maps, got %v and %v", c.Args, c.RenderArgs)
+	}
+
+	// Misses and mismatched types return the zero value.
+	if name, ok := GetArg[string](c, "missing"); ok || name != "" {
+		t.Errorf("Expected a miss, got %q", name)
+	}
+	if name, ok := GetArg[string](c, "count"); ok || name != "" {
+		t.Errorf("Expected a miss for another type, got %q", name)
+	}
+}
+
+type contextKey string
+
+func TestControllerContext(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	parent, cancelRequest := context.WithCancel(context.Background())
+	c := NewController(NewRequest(req.WithContext(parent)), NewResponse(httptest.NewRecorder()))
+
+	// An interceptor attaches a value, which the action sees.
+	c.SetContext(context.WithValue(c.Context(), contextKey("user"), "ann"))
+	if c.Context().Value(contextKey("user")) != "ann" {
+		t.Errorf("Expected the context set by SetContext")
+	}
+
+	ctx, cancel := c.WithTimeout(time.Hour)
+	defer cancel()
+	if ctx.Value(contextKey("user")) != "ann" {
+		t.Errorf("Expected WithTimeout to derive from the controller's context")
+	}
+	cancelRequest()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Errorf("Expected the client disconnecting to cancel the derived context")
+	}
+
+	// A derived context is released once the response is complete.
+	c = NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+	ctx, _ = c.WithTimeout(time.Hour)
+	c.runCleanups()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Expected the context to be canceled by the cleanups, got %v", ctx.Err())
+	}
+}
+
+func TestRegisterErrorHandler(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { errorHandlers = map[int]ErrorHandler{} }()
+
+	RegisterErrorHandler(http.StatusNotFound, func(c *Controller, err error) Result {
+		if !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			return nil
+		}
+		return c.RenderJson(map[string]string{"error": err.Error()})
+	})
+	RegisterErrorHandler(AnyStatus, func(c *Controller, err error) Result {
+		// Falls back to the default error page.
+		c.Response.Out.Header().Set("X-Handled", "any")
+		return c.RenderError(err)
+	})
+
+	tests := []struct {
+		path    string
+		render  func(c *Controller) Result
+		status  int
+		body    string
+		handled string
+	}{
+		{"/api/hotels/3", func(c *Controller) Result { return c.NotFound("No hotel %d", 3) }, 404, `{"error":"Not Found: No hotel 3"}`, ""},
+		{"/hotels/3", func(c *Controller) Result { return c.NotFound("No hotel %d", 3) }, 404, "No hotel 3", ""},
+		{"/hotels/3", func(c *Controller) Result { return c.Forbidden("Not yours") }, 403, "Not yours", "any"},
+		{"/hotels/3", func(c *Controller) Result { return c.RenderError(errors.New("oops")) }, 500, "oops", "any"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", test.path, nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.Request.Format = "txt"
+		test.render(c).Apply(c.Request, c.Response)
+		if resp.Code != test.status || !strings.Contains(resp.Body.String(), test.body) {
+			t.Errorf("%s: expected %d with %q, got %d %q", test.path, test.status, test.body, resp.Code, resp.Body)
+		}
+		if handled := resp.Header().Get("X-Handled"); handled != test.handled {
+			t.Errorf("%s: expected X-Handled %q, got %q", test.path, test.handled, handled)
+		}
+	}
+}
+
+func TestRenderArgsPrecedence(t *testing.T) {
+	startFakeBookingApp()
+	defer func(mode bool) {
+		renderArgsOverwrite, warnRenderArgCollision, DevMode = true, false, mode
+	}(DevMode)
+	defer func(logger *log.Logger) { WARN = logger }(WARN)
+	var logged bytes.Buffer
+	WARN = log.New(&logged, "", 0)
+
+	newController := func() *Controller {
+		c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+		c.SetAction("Hotels", "Show")
+		c.RenderArgs["title"] = "Hotels" // e.g. by an interceptor
+		return c
+	}
+
+	// AddRenderArgs never replaces args.
+	c := newController()
+	c.AddRenderArgs(map[string]interface{}{"title": "Default", "user": "bob"})
+	if c.RenderArgs["title"] != "Hotels" || c.RenderArgs["user"] != "bob" {
+		t.Errorf("Expected only the unset args to be added, got %v", c.RenderArgs)
+	}
+
+	// The action's args replace them, unless configured to keep them.
+	for _, overwrite := range []bool{true, false} {
+		renderArgsOverwrite = overwrite
+		c = newController()
+		c.RenderNamed(map[string]interface{}{"title": "Hotel 3"})
+		if expected := map[bool]string{true: "Hotel 3", false: "Hotels"}[overwrite]; c.RenderArgs["title"] != expected {
+			t.Errorf("overwrite %v: expected title %q, got %v", overwrite, expected, c.RenderArgs["title"])
+		}
+	}
+
+	// Collisions are logged in dev mode, if configured.
+	if logged.Len() != 0 {
+		t.Errorf("Expected no warnings unless configured, got %s", logged.String())
+	}
+	warnRenderArgCollision, DevMode = true, true
+	newController().AddRenderArgs(map[string]interface{}{"title": "Default"})
+	if !strings.Contains(logged.String(), `"title"`) {
+		t.Errorf("Expected a warning about the collision, got %q", logged.String())
+	}
+}
+
+func TestRegisteredControllers(t *testing.T) {
+	startFakeBookingApp()
+	registered := RegisteredControllers()
+	var names []string
+	for _, ct := range registered {
+		names = append(names, ct.Type.Name())
+	}
+	if !sort.SliceIsSorted(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) }) {
+		t.Errorf("Expected the controllers sorted by name, got %v", names)
+	}
+
+	var hotels *ControllerType
+	for _, ct := range registered {
+		if ct.Type.Name() == "Hotels" {
+			hotels = ct
+		}
+	}
+	if hotels == nil {
+		t.Fatalf("Expected the Hotels controller, got %v", names)
+	}
+	args := hotels.MethodArgs("show")
+	if len(args) != 1 || args[0].Name != "id" || args[0].Type != reflect.TypeOf(0) {
+		t.Errorf("Expected Hotels.Show(id int), got %v", args)
+	}
+	if args := hotels.MethodArgs("Missing"); args != nil {
+		t.Errorf("Expected no args for a missing method, got %v", args)
+	}
+}
@@ -0,0 +1,186 @@
+package revel
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Browsers limit each cookie to 4096 bytes, including its name and
+	// attributes, and silently drop larger ones.
+	cookieMaxSize = 4096
+
+	// Browsers also limit the number of cookies per domain (to as few as 50),
+	// so cap how many chunks a single cookie may be split across.
+	maxCookieChunks = 10
+)
+
+// CookieOptions are the attributes of a cookie set by SetSecureCookie.
+type CookieOptions struct {
+	Path    string    // Defaults to "/".
+	Domain  string    // Defaults to the host of the request.
+	Expires time.Time // If zero (and MaxAge too), the cookie lasts for the browser session.
+	MaxAge  int
+
+	// SameSite defaults to the "cookie.samesite" policy of the framework's
+	// cookies: Lax, unless configured otherwise.
+	SameSite http.SameSite
+
+	// AllowScripts leaves off HttpOnly, for cookies that scripts must read.
+	AllowScripts bool
+}
+
+// SetSecureCookie sets a cookie with safe defaults: HttpOnly, SameSite Lax,
+// and Secure in prod mode, for HTTPS requests, or if "cookie.secure" is set.
+//   c.SetSecureCookie("theme", "dark", revel.CookieOptions{MaxAge: 365 * 24 * 3600})
+func (c *Controller) SetSecureCookie(name, value string, opts CookieOptions) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		Expires:  opts.Expires,
+		MaxAge:   opts.MaxAge,
+		HttpOnly: !opts.AllowScripts,
+		Secure:   CookieSecure || !DevMode || c.Request.TLS != nil,
+		SameSite: opts.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = CookieSameSite
+	}
+	// Browsers reject SameSite=None cookies that are not Secure.
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	c.SetCookie(cookie)
+}
+
+// parseSameSite returns the SameSite mode named in app.conf.
+func parseSameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "", "default":
+		return http.SameSiteDefaultMode
+	}
+	ERROR.Println("Unknown cookie.samesite:", mode)
+	return http.SameSiteLaxMode
+}
+
+// setChunkedCookie sets the cookie on the response.
+//
+// If cookie chunking is enabled ("cookie.chunked" in app.conf) and the value
+// is too large for a single cookie, it is split across numbered cookies:
+// "name.0", "name.1", etc.  If it would take more than maxCookieChunks, an
+// error is logged and the cookie is set unsplit, as it is without chunking.
+//
+// Cookies left over from the previous value (e.g. an unsplit cookie, or extra
+// chunks of a larger value) are expired.
+func setChunkedCookie(c *Controller, cookie *http.Cookie) {
+	var (
+		chunked   = Config.BoolDefault("cookie.chunked", false)
+		chunkSize = cookieChunkSize(cookie)
+		numChunks = (len(cookie.Value) + chunkSize - 1) / chunkSize
+		oldChunks = countCookieChunks(c.Request.Request, cookie.Name)
+	)
+
+	if numChunks > maxCookieChunks && chunked {
+		ERROR.Printf("Cookie %s needs %d chunks, more than the maximum of %d",
+			cookie.Name, numChunks, maxCookieChunks)
+	}
+	if !chunked || numChunks <= 1 || numChunks > maxCookieChunks {
+		c.SetCookie(cookie)
+		expireCookieChunks(c, cookie, 0, oldChunks)
+		return
+	}
+
+	for i := 0; i < numChunks; i++ {
+		end := (i + 1) * chunkSize
+		if end > len(cookie.Value) {
+			end = len(cookie.Value)
+		}
+		chunk := *cookie
+		chunk.Name = cookieChunkName(cookie.Name, i)
+		chunk.Value = cookie.Value[i*chunkSize : end]
+		c.SetCookie(&chunk)
+	}
+	expireCookieChunks(c, cookie, numChunks, oldChunks)
+
+	if _, err := c.Request.Cookie(cookie.Name); err == nil {
+		expireCookie(c, cookie, cookie.Name)
+	}
+}
+
+// cookieChunkSize returns how much of the cookie's value fits in each chunk,
+// leaving room for the chunk's name and the cookie's attributes.
+func cookieChunkSize(cookie *http.Cookie) int {
+	chunk := *cookie
+	chunk.Name = cookieChunkName(cookie.Name, maxCookieChunks-1)
+	chunk.Value = ""
+	// Less 2 for the quotes around a value with spaces or commas.
+	size := cookieMaxSize - len(chunk.String()) - 2
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// readChunkedCookie returns the named cookie from the request, reassembling it
+// from its chunks if it was split by setChunkedCookie.
+func readChunkedCookie(req *http.Request, name string) (*http.Cookie, error) {
+	if cookie, err := req.Cookie(name); err == nil {
+		return cookie, nil
+	}
+
+	numChunks := countCookieChunks(req, name)
+	if numChunks == 0 {
+		return nil, http.ErrNoCookie
+	}
+
+	var value string
+	for i := 0; i < numChunks; i++ {
+		chunk, _ := req.Cookie(cookieChunkName(name, i))
+		value += chunk.Value
+	}
+	return &http.Cookie{Name: name, Value: value}, nil
+}
+
+// countCookieChunks returns the number of consecutive chunks of the named
+// cookie present on the request.
+func countCookieChunks(req *http.Request, name string) int {
+	for i := 0; ; i++ {
+		if _, err := req.Cookie(cookieChunkName(name, i)); err != nil {
+			return i
+		}
+	}
+}
+
+func cookieChunkName(name string, i int) string {
+	return name + "." + strconv.Itoa(i)
+}
+
+// expireCookieChunks expires the chunks numbered [from, to).
+func expireCookieChunks(c *Controller, cookie *http.Cookie, from, to int) {
+	for i := from; i < to; i++ {
+		expireCookie(c, cookie, cookieChunkName(cookie.Name, i))
+	}
+}
+
+func expireCookie(c *Controller, cookie *http.Cookie, name string) {
+	c.SetCookie(&http.Cookie{
+		Name:    name,
+		Path:    cookie.Path,
+		Domain:  cookie.Domain,
+		MaxAge:  -1,
+		Expires: time.Unix(1, 0),
+	})
+}
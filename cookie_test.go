@@ -0,0 +1,95 @@
+package revel
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkedCookie(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("cookie.chunked", "true")
+	defer Config.SetOption("cookie.chunked", "false")
+
+	value := strings.Repeat("x", 2*cookieMaxSize)
+	req, _ := http.NewRequest("GET", "/", nil)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	setChunkedCookie(c, &http.Cookie{
+		Name:     "big",
+		Value:    value,
+		Path:     "/account/settings",
+		Domain:   "www.example.com",
+		Expires:  time.Now().Add(time.Hour),
+		MaxAge:   3600,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	cookies := (&http.Response{Header: resp.Header()}).Cookies()
+	if len(cookies) != 3 || cookies[0].Name != "big.0" || cookies[2].Name != "big.2" {
+		t.Fatalf("Expected 3 chunks, got %v", cookies)
+	}
+	// Each chunk, with its name and attributes, must fit within the limit.
+	for _, header := range resp.Header()["Set-Cookie"] {
+		if len(header) > cookieMaxSize {
+			t.Errorf("Chunk is %d bytes, over the limit of %d", len(header), cookieMaxSize)
+		}
+	}
+
+	// Read it back.
+	req, _ = http.NewRequest("GET", "/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	cookie, err := readChunkedCookie(req, "big")
+	if err != nil || cookie.Value != value {
+		t.Errorf("Failed to reassemble chunked cookie: %v", err)
+	}
+
+	// A small value replaces the chunks with a single cookie.
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(req), NewResponse(resp))
+	setChunkedCookie(c, &http.Cookie{Name: "big", Value: "small", Path: "/"})
+	cookies = (&http.Response{Header: resp.Header()}).Cookies()
+	if len(cookies) != 4 || cookies[0].Value != "small" || cookies[1].MaxAge != -1 {
+		t.Errorf("Expected the cookie and 3 expired chunks, got %v", cookies)
+	}
+}
+
+func TestSetSecureCookie(t *testing.T) {
+	startFakeBookingApp()
+	defer func(devMode bool, sameSite http.SameSite) {
+		DevMode, CookieSameSite = devMode, sameSite
+	}(DevMode, CookieSameSite)
+	CookieSameSite = parseSameSite("lax")
+
+	for _, test := range []struct {
+		devMode, https bool
+		opts           CookieOptions
+		expected       string
+	}{
+		{true, false, CookieOptions{}, "theme=dark; Path=/; HttpOnly; SameSite=Lax"},
+		{true, true, CookieOptions{}, "theme=dark; Path=/; HttpOnly; Secure; SameSite=Lax"},
+		{false, false, CookieOptions{AllowScripts: true}, "theme=dark; Path=/; Secure; SameSite=Lax"},
+		{true, false, CookieOptions{Path: "/admin", SameSite: http.SameSiteStrictMode},
+			"theme=dark; Path=/admin; HttpOnly; SameSite=Strict"},
+		{true, false, CookieOptions{SameSite: http.SameSiteNoneMode}, "theme=dark; Path=/; HttpOnly; Secure; SameSite=None"},
+	} {
+		DevMode = test.devMode
+		req, _ := http.NewRequest("GET", "/", nil)
+		if test.https {
+			req.TLS = &tls.ConnectionState{}
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.SetSecureCookie("theme", "dark", test.opts)
+		if actual := resp.Header().Get("Set-Cookie"); actual != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package revel
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+const (
+	// CSRF_TOKEN_KEY is the session key holding the CSRF token.
+	CSRF_TOKEN_KEY = "_CSRF"
+
+	// CsrfFieldName is the form field that CsrfFilter reads the token from,
+	// and CsrfHeaderName the header it reads it from otherwise (for AJAX).
+	CsrfFieldName  = "csrf_token"
+	CsrfHeaderName = "X-CSRF-Token"
+)
+
+// CsrfFilter protects against cross-site request forgery.  It keeps a random
+// token in the session, available to templates as {{.csrfToken}}, and rejects
+// unsafe requests (any method but GET, HEAD, OPTIONS, and TRACE) that do not
+// send it back with a 403 Forbidden.  Forms include it as a hidden field:
+//   <input type="hidden" name="csrf_token" value="{{.csrfToken}}">
+// and scripts in the X-CSRF-Token header.
+//
+// It is not installed by default.  Add it after the SessionFilter and
+// ParamsFilter, and remove it from actions that can not send the token, such
+// as webhooks:
+//   revel.FilterAction(Hooks.Receive).Remove(revel.CsrfFilter)
+func CsrfFilter(c *Controller, fc []Filter) {
+	token, ok := c.Session[CSRF_TOKEN_KEY]
+	if !ok {
+		token = newCsrfToken()
+		c.Session[CSRF_TOKEN_KEY] = token
+	}
+	c.RenderArgs["csrfToken"] = token
+
+	switch c.Request.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+	default:
+		sent := c.Params.Get(CsrfFieldName)
+		if sent == "" {
+			sent = c.Request.Header.Get(CsrfHeaderName)
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			c.Result = c.Forbidden("Invalid CSRF token")
+			return
+		}
+	}
+
+	fc[0](c, fc[1:])
+}
+
+func newCsrfToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.URLEncoding.EncodeToString(b)
+}
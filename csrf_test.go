@@ -0,0 +1,59 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCsrfFilter(t *testing.T) {
+	startFakeBookingApp()
+	// run runs the filter for a request with the given session, returning the
+	// response code and whether the action was invoked.
+	run := func(method string, session Session, form url.Values, header string) (int, bool) {
+		req, _ := http.NewRequest(method, "/hotels/3/booking", nil)
+		if header != "" {
+			req.Header.Set(CsrfHeaderName, header)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.Request.Format = "json"
+		c.Session = session
+		c.Params.Values = form
+		invoked := false
+		CsrfFilter(c, []Filter{func(c *Controller, _ []Filter) { invoked = true }})
+		if c.Result != nil {
+			c.Result.Apply(c.Request, c.Response)
+		}
+		return resp.Code, invoked
+	}
+
+	// A safe request gets a token.
+	session := make(Session)
+	if _, invoked := run("GET", session, nil, ""); !invoked || session[CSRF_TOKEN_KEY] == "" {
+		t.Fatalf("Expected a GET to be allowed and to create a token, got %v", session)
+	}
+	token := session[CSRF_TOKEN_KEY]
+
+	for _, test := range []struct {
+		session  Session
+		form     url.Values
+		header   string
+		expected bool
+	}{
+		{session, url.Values{CsrfFieldName: {token}}, "", true},
+		{session, nil, token, true},
+		{session, url.Values{CsrfFieldName: {"forged"}}, "", false},
+		{session, nil, "", false},
+		{make(Session), url.Values{CsrfFieldName: {""}}, "", false},
+	} {
+		code, invoked := run("POST", test.session, test.form, test.header)
+		if invoked != test.expected {
+			t.Errorf("%v %q: expected invoked=%v, got %v", test.form, test.header, test.expected, invoked)
+		}
+		if !test.expected && code != http.StatusForbidden {
+			t.Errorf("%v %q: expected 403, got %d", test.form, test.header, code)
+		}
+	}
+}
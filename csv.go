@@ -0,0 +1,135 @@
+package revel
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// CsvOptions control how a RenderCsvResult writes its records.
+type CsvOptions struct {
+	// Filename is suggested to the client in the Content-Disposition header,
+	// e.g. "bookings.csv".
+	Filename string
+
+	// Comma is the field delimiter, e.g. ';' or '\t'.  It defaults to ','.
+	Comma rune
+}
+
+// RenderCsvResult streams records to the client as a CSV attachment.
+// The records are either a [][]string, or a slice of structs (or pointers to
+// structs), whose exported fields become the columns.  A header row is written
+// from the field names, which may be overridden with a csv tag:
+//   type Booking struct {
+//     Id       int       `csv:"id"`
+//     CheckIn  time.Time `csv:"check_in"`
+//     CardCvc  string    `csv:"-"`
+//   }
+type RenderCsvResult struct {
+	records interface{}
+	options CsvOptions
+}
+
+func (r RenderCsvResult) Apply(req *Request, resp *Response) {
+	rows, err := csvRows(r.records)
+	if err != nil {
+		renderEncodingError(req, resp, "CSV", err)
+		return
+	}
+
+	resp.Out.Header().Set("Content-Disposition", Attachment.header(r.options.Filename))
+	resp.WriteHeader(http.StatusOK, "text/csv; charset=utf-8")
+
+	writer := csv.NewWriter(resp.Out)
+	if r.options.Comma != 0 {
+		writer.Comma = r.options.Comma
+	}
+	err = rows(writer.Write)
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		ERROR.Println("Failed to write CSV:", err)
+	}
+}
+
+// csvRows returns a function that passes each row of records to write,
+// stopping at the first error, or an error if records is neither a [][]string
+// nor a slice of structs.
+func csvRows(records interface{}) (func(write func([]string) error) error, error) {
+	if rows, ok := records.([][]string); ok {
+		return func(write func([]string) error) error {
+			for _, row := range rows {
+				if err := write(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("revel: cannot render %T as CSV", records)
+	}
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("revel: cannot render %T as CSV", records)
+	}
+
+	var header []string
+	var fields []int
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		name := field.Tag.Get("csv")
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	return func(write func([]string) error) error {
+		if err := write(header); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			row := make([]string, len(fields))
+			if elem.Kind() != reflect.Ptr || !elem.IsNil() {
+				elem = reflect.Indirect(elem)
+				for j, index := range fields {
+					row[j] = csvValue(elem.Field(index))
+				}
+			}
+			if err := write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// csvValue formats a field for a CSV cell.  Nil pointers are empty, and times
+// are formatted as RFC 3339.
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
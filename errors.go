@@ -2,6 +2,7 @@ package revel
 
 import (
 	"fmt"
+	"net/http"
 	"runtime/debug"
 	"strings"
 )
@@ -16,6 +17,44 @@ type Error struct {
 	MetaError                string   // Error that occurred producing the error page.
 }
 
+// An ErrorHandler returns the Result for an error response, in place of the
+// default error page, or nil to render the default.  See RegisterErrorHandler.
+type ErrorHandler func(c *Controller, err error) Result
+
+// The handlers registered by status code.  The handler for AnyStatus is used
+// for codes that have none of their own.
+var errorHandlers = map[int]ErrorHandler{}
+
+// AnyStatus registers a catch-all ErrorHandler, for the statuses that have no
+// handler of their own.
+const AnyStatus = 0
+
+// RegisterErrorHandler sets the handler for error responses with the given
+// status, rendered by RenderError or its helpers (NotFound, Forbidden,
+// InternalServerError, etc).  For example, to render JSON errors for the API:
+//   revel.RegisterErrorHandler(404, func(c *revel.Controller, err error) revel.Result {
+//     if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+//       c.Response.Status = 404
+//       return c.RenderJson(map[string]string{"error": err.Error()})
+//     }
+//     return nil
+//   })
+// Handlers must be registered on app start.
+func RegisterErrorHandler(status int, handler ErrorHandler) {
+	errorHandlers[status] = handler
+}
+
+// errorHandlerFor returns the handler for the status, if any.
+func errorHandlerFor(status int) ErrorHandler {
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if handler, ok := errorHandlers[status]; ok {
+		return handler
+	}
+	return errorHandlers[AnyStatus]
+}
+
 // An object to hold the per-source-line details.
 type sourceLine struct {
 	Source  string
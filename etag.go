@@ -0,0 +1,167 @@
+package revel
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Versioned value reports a version that changes whenever its rendered
+// content does, such as a revision number or update time.  Rendering it as
+// JSON sets an ETag derived from the version, which (unlike hashing the body)
+// allows a 304 Not Modified to be sent without marshaling the value.
+//
+// Instead of implementing Versioned, a struct may tag its version field:
+//   type Article struct {
+//     Id        int
+//     UpdatedAt time.Time `etag:"true"`
+//     Body      string
+//   }
+type Versioned interface {
+	ETagVersion() string
+}
+
+// hashETag returns a strong ETag for the body: a quoted hash of its contents.
+func hashETag(body []byte) string {
+	sum := sha1.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// checkETag sets the ETag header of the response.  If the request is a GET or
+// HEAD whose If-None-Match matches the tag, it writes a 304 Not Modified and
+// returns true, in which case the result must not write a body.
+func checkETag(req *Request, resp *Response, etag string) bool {
+	resp.Out.Header().Set("ETag", etag)
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	if !etagMatches(req.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	resp.Status = http.StatusNotModified
+	resp.Out.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether the If-None-Match header lists the tag.
+// As required for If-None-Match, weak tags match their strong equivalents.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// versionETag returns a strong ETag derived from the version of obj, if it is
+// Versioned or has a field tagged `etag:"true"`.  The tag also reflects the
+// type and the options, which change the representation of the same version.
+func versionETag(obj interface{}, variant ...interface{}) (string, bool) {
+	var version string
+	if versioned, ok := obj.(Versioned); ok {
+		version = versioned.ETagVersion()
+	} else {
+		v := reflect.ValueOf(obj)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return "", false
+		}
+		index, ok := etagField(v.Type())
+		if !ok {
+			return "", false
+		}
+		switch field := v.Field(index).Interface().(type) {
+		case time.Time:
+			version = field.UTC().Format(time.RFC3339Nano)
+		default:
+			version = fmt.Sprint(field)
+		}
+	}
+	key := fmt.Sprintf("%T %q %v", obj, version, variant)
+	return hashETag([]byte(key)), true
+}
+
+var (
+	etagFieldCache   = make(map[reflect.Type]int)
+	etagFieldCacheMu sync.Mutex
+)
+
+// etagField returns the index of the field of the struct type tagged
+// `etag:"true"`, if any.
+func etagField(t reflect.Type) (int, bool) {
+	etagFieldCacheMu.Lock()
+	defer etagFieldCacheMu.Unlock()
+	index, ok := etagFieldCache[t]
+	if !ok {
+		index = -1
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.PkgPath == "" && field.Tag.Get("etag") == "true" {
+				index = i
+				break
+			}
+		}
+		etagFieldCache[t] = index
+	}
+	return index, index != -1
+}
+
+// binaryETag returns a strong ETag for a binary result: derived from its
+// modification time and length when both are known, or else a hash of the
+// content when the reader is seekable.  The reader is left at its start.
+func binaryETag(r *BinaryResult) (string, bool) {
+	rs, seekable := r.Reader.(io.ReadSeeker)
+	length := r.Length
+	if length < 0 && seekable {
+		size, err := rs.Seek(0, os.SEEK_END)
+		if _, err2 := rs.Seek(0, os.SEEK_SET); err != nil || err2 != nil {
+			return "", false
+		}
+		length = size
+	}
+	if !r.ModTime.IsZero() && length >= 0 {
+		return fmt.Sprintf(`"%x-%x"`, r.ModTime.UnixNano(), length), true
+	}
+	if !seekable {
+		return "", false
+	}
+	hash := sha1.New()
+	_, err := io.Copy(hash, rs)
+	if _, err2 := rs.Seek(0, os.SEEK_SET); err != nil || err2 != nil {
+		return "", false
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`, true
+}
+
+// checkModifiedSince writes a 304 Not Modified and returns true if the request
+// is a GET or HEAD without If-None-Match whose If-Modified-Since is not before
+// modTime.  HTTP dates have one second resolution, so modTime is truncated.
+func checkModifiedSince(req *Request, resp *Response, modTime time.Time) bool {
+	if modTime.IsZero() || req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || modTime.Truncate(time.Second).After(since) {
+		return false
+	}
+	resp.Status = http.StatusNotModified
+	resp.Out.WriteHeader(http.StatusNotModified)
+	return true
+}
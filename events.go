@@ -0,0 +1,48 @@
+package revel
+
+// A LifecycleEvent is a point in the processing of a request at which
+// subscribers registered with OnLifecycleEvent are called.
+type LifecycleEvent int
+
+const (
+	// RequestStart fires once the Controller has been created, before any
+	// filters run.  The action is not yet known.
+	RequestStart LifecycleEvent = iota
+
+	// AfterBind fires once the action arguments have been bound (and
+	// normalized by PostBind), just before the action is invoked.
+	AfterBind
+
+	// BeforeRender fires once the filters have produced c.Result, just before
+	// it is applied to the response.
+	BeforeRender
+
+	// RequestEnd fires once the response is complete, or the request has
+	// failed.  It fires for every request that fired RequestStart.
+	RequestEnd
+)
+
+var lifecycleSubscribers = map[LifecycleEvent][]func(c *Controller){}
+
+// OnLifecycleEvent registers f to be called with the request's Controller when
+// the given event fires.  Subscribers are called in the order registered, and
+// should be registered at startup, like filters.
+//
+// Subscribers observe the request; they must not change c.Result or write to
+// the response, which is the job of filters and interceptors.  They may keep
+// their own per-request state in c.Args.  For example, to time requests:
+//   revel.OnLifecycleEvent(revel.RequestStart, func(c *revel.Controller) {
+//     c.Args["start"] = time.Now()
+//   })
+//   revel.OnLifecycleEvent(revel.RequestEnd, func(c *revel.Controller) {
+//     revel.INFO.Println(c.Action, time.Since(c.Args["start"].(time.Time)))
+//   })
+func OnLifecycleEvent(event LifecycleEvent, f func(c *Controller)) {
+	lifecycleSubscribers[event] = append(lifecycleSubscribers[event], f)
+}
+
+func fireLifecycleEvent(event LifecycleEvent, c *Controller) {
+	for _, f := range lifecycleSubscribers[event] {
+		f(c)
+	}
+}
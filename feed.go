@@ -0,0 +1,239 @@
+package revel
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// A Feed is a list of recent items (e.g. blog posts), rendered as RSS 2.0 by
+// Controller.RenderRss or as Atom by Controller.RenderAtom.
+type Feed struct {
+	Title       string // Required
+	Link        string // Required; the URL of the site.
+	Description string
+	Author      string
+	Updated     time.Time // Defaults to that of the most recent item.
+	Items       []FeedItem
+}
+
+type FeedItem struct {
+	Title       string // Required
+	Link        string // Required; the URL of the item.
+	Description string
+	Author      string
+	Id          string // A permanent, unique id.  Defaults to Link.
+	Published   time.Time
+	Updated     time.Time // Defaults to Published.
+}
+
+// validate returns an error describing the first missing required field.
+func (f Feed) validate() error {
+	missing := func(field string) error {
+		return &Error{
+			Title:       "Feed Render Error",
+			Description: fmt.Sprintf("The feed is missing its %s", field),
+		}
+	}
+	switch {
+	case f.Title == "":
+		return missing("Title")
+	case f.Link == "":
+		return missing("Link")
+	}
+	for i, item := range f.Items {
+		switch {
+		case item.Title == "":
+			return missing(fmt.Sprintf("Items[%d].Title", i))
+		case item.Link == "":
+			return missing(fmt.Sprintf("Items[%d].Link", i))
+		}
+	}
+	return nil
+}
+
+func (item FeedItem) id() string {
+	if item.Id != "" {
+		return item.Id
+	}
+	return item.Link
+}
+
+func (item FeedItem) updated() time.Time {
+	if !item.Updated.IsZero() {
+		return item.Updated
+	}
+	return item.Published
+}
+
+func (f Feed) updated() time.Time {
+	updated := f.Updated
+	if updated.IsZero() {
+		for _, item := range f.Items {
+			if item.updated().After(updated) {
+				updated = item.updated()
+			}
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return updated
+}
+
+// FeedResult renders a Feed as an RSS 2.0 or Atom document.
+type FeedResult struct {
+	Feed Feed
+	Atom bool // If false, the feed is rendered as RSS 2.0.
+}
+
+func (r FeedResult) Apply(req *Request, resp *Response) {
+	if err := r.Feed.validate(); err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+
+	var (
+		doc         interface{}
+		contentType string
+	)
+	if r.Atom {
+		doc, contentType = newAtomFeed(r.Feed), "application/atom+xml; charset=utf-8"
+	} else {
+		doc, contentType = newRssFeed(r.Feed), "application/rss+xml; charset=utf-8"
+	}
+
+	var b []byte
+	var err error
+	if Config.BoolDefault("results.pretty", false) {
+		b, err = xml.MarshalIndent(doc, "", "  ")
+	} else {
+		b, err = xml.Marshal(doc)
+	}
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK, contentType)
+	resp.Out.Write([]byte(xml.Header))
+	resp.Out.Write(b)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string    `xml:"title"`
+	Link           string    `xml:"link"`
+	Description    string    `xml:"description"`
+	ManagingEditor string    `xml:"managingEditor,omitempty"`
+	LastBuildDate  string    `xml:"lastBuildDate"`
+	Items          []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	Author      string  `xml:"author,omitempty"`
+	Guid        rssGuid `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func newRssFeed(f Feed) rssFeed {
+	channel := rssChannel{
+		Title:          f.Title,
+		Link:           f.Link,
+		Description:    f.Description,
+		ManagingEditor: f.Author,
+		LastBuildDate:  f.updated().Format(time.RFC1123Z),
+	}
+	for _, item := range f.Items {
+		rss := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Author:      item.Author,
+			Guid:        rssGuid{item.id() == item.Link, item.id()},
+		}
+		if !item.Published.IsZero() {
+			rss.PubDate = item.Published.Format(time.RFC1123Z)
+		}
+		channel.Items = append(channel.Items, rss)
+	}
+	return rssFeed{Version: "2.0", Channel: channel}
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Link     atomLink    `xml:"link"`
+	Id       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Author   *atomAuthor `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Id        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+	Author    *atomAuthor `xml:"author"`
+}
+
+func newAtomFeed(f Feed) atomFeed {
+	feed := atomFeed{
+		Title:    f.Title,
+		Link:     atomLink{f.Link},
+		Id:       f.Link,
+		Updated:  f.updated().Format(time.RFC3339),
+		Subtitle: f.Description,
+	}
+	if f.Author != "" {
+		feed.Author = &atomAuthor{f.Author}
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			Title:   item.Title,
+			Link:    atomLink{item.Link},
+			Id:      item.id(),
+			Summary: item.Description,
+		}
+		// Every entry must have an updated time; fall back to that of the feed.
+		if updated := item.updated(); !updated.IsZero() {
+			entry.Updated = updated.Format(time.RFC3339)
+		} else {
+			entry.Updated = feed.Updated
+		}
+		if !item.Published.IsZero() {
+			entry.Published = item.Published.Format(time.RFC3339)
+		}
+		if item.Author != "" {
+			entry.Author = &atomAuthor{item.Author}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
@@ -0,0 +1,77 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFeed = Feed{
+	Title: "News",
+	Link:  "http://example.com/",
+	Items: []FeedItem{{
+		Title:     "Launch & more",
+		Link:      "http://example.com/launch",
+		Published: time.Date(2014, 1, 2, 3, 4, 5, 0, time.UTC),
+	}},
+}
+
+func TestRenderRss(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderRss(testFeed).Apply(c.Request, c.Response)
+
+	if resp.Header().Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+		t.Errorf("Unexpected content type: %s", resp.Header().Get("Content-Type"))
+	}
+	body := resp.Body.String()
+	for _, expected := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<rss version="2.0"><channel><title>News</title>`,
+		`<item><title>Launch &amp; more</title><link>http://example.com/launch</link>`,
+		`<guid isPermaLink="true">http://example.com/launch</guid>`,
+		`<pubDate>Thu, 02 Jan 2014 03:04:05 +0000</pubDate>`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in:\n%s", expected, body)
+		}
+	}
+}
+
+func TestRenderAtom(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderAtom(testFeed).Apply(c.Request, c.Response)
+
+	if resp.Header().Get("Content-Type") != "application/atom+xml; charset=utf-8" {
+		t.Errorf("Unexpected content type: %s", resp.Header().Get("Content-Type"))
+	}
+	body := resp.Body.String()
+	for _, expected := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom"><title>News</title>`,
+		`<updated>2014-01-02T03:04:05Z</updated>`,
+		`<entry><title>Launch &amp; more</title><link href="http://example.com/launch"></link>`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in:\n%s", expected, body)
+		}
+	}
+}
+
+func TestRenderFeedMissingField(t *testing.T) {
+	startFakeBookingApp()
+	feed := testFeed
+	feed.Items = []FeedItem{{Title: "No link"}}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Request.Format = "txt"
+	c.RenderRss(feed).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a missing link to be an error, got %d", resp.Code)
+	}
+}
@@ -14,25 +14,59 @@ type Flash struct {
 	Data, Out map[string]string
 }
 
-func (f Flash) Error(msg string, args ...interface{}) {
+// The severities of flash messages, in the order they are listed by Messages.
+// They are also the keys that the messages are stored under.
+var FlashSeverities = []string{"error", "warning", "success", "info"}
+
+// A FlashMessage is a message set by Error, Warning, Success, or Info.
+type FlashMessage struct {
+	Severity string // e.g. "error"
+	Message  string
+}
+
+func (f Flash) set(severity, msg string, args []interface{}) {
 	if len(args) == 0 {
-		f.Out["error"] = msg
+		f.Out[severity] = msg
 	} else {
-		f.Out["error"] = fmt.Sprintf(msg, args...)
+		f.Out[severity] = fmt.Sprintf(msg, args...)
 	}
 }
 
+func (f Flash) Error(msg string, args ...interface{}) {
+	f.set("error", msg, args)
+}
+
+func (f Flash) Warning(msg string, args ...interface{}) {
+	f.set("warning", msg, args)
+}
+
 func (f Flash) Success(msg string, args ...interface{}) {
-	if len(args) == 0 {
-		f.Out["success"] = msg
-	} else {
-		f.Out["success"] = fmt.Sprintf(msg, args...)
+	f.set("success", msg, args)
+}
+
+func (f Flash) Info(msg string, args ...interface{}) {
+	f.set("info", msg, args)
+}
+
+// Messages returns the messages flashed by the previous request, by severity.
+// They are available to templates as {{.flashMessages}}, e.g.:
+//   {{range .flashMessages}}
+//     <div class="alert alert-{{.Severity}}">{{.Message}}</div>
+//   {{end}}
+func (f Flash) Messages() []FlashMessage {
+	var messages []FlashMessage
+	for _, severity := range FlashSeverities {
+		if msg, ok := f.Data[severity]; ok {
+			messages = append(messages, FlashMessage{severity, msg})
+		}
 	}
+	return messages
 }
 
 func FlashFilter(c *Controller, fc []Filter) {
 	c.Flash = restoreFlash(c.Request.Request)
 	c.RenderArgs["flash"] = c.Flash.Data
+	c.RenderArgs["flashMessages"] = c.Flash.Messages()
 
 	fc[0](c, fc[1:])
 
@@ -46,6 +80,7 @@ func FlashFilter(c *Controller, fc []Filter) {
 		Value:    url.QueryEscape(flashValue),
 		HttpOnly: CookieHttpOnly,
 		Secure:   CookieSecure,
+		SameSite: CookieSameSite,
 		Path:     "/",
 	})
 }
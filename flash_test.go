@@ -0,0 +1,38 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFlashMessages(t *testing.T) {
+	startFakeBookingApp()
+	req, _ := http.NewRequest("GET", "/", nil)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	FlashFilter(c, []Filter{func(c *Controller, _ []Filter) {
+		c.Flash.Success("Booked %d nights", 3)
+		c.Flash.Warning("Check-in is after %s", "3pm")
+		c.Flash.Out["custom"] = "kept"
+	}})
+
+	// The next request sees them by severity, and the custom key as before.
+	req, _ = http.NewRequest("GET", "/", nil)
+	for _, cookie := range (&http.Response{Header: resp.Header()}).Cookies() {
+		req.AddCookie(cookie)
+	}
+	c = NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+	FlashFilter(c, []Filter{func(c *Controller, _ []Filter) {}})
+	expected := []FlashMessage{
+		{"warning", "Check-in is after 3pm"},
+		{"success", "Booked 3 nights"},
+	}
+	if messages := c.RenderArgs["flashMessages"]; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %v, got %v", expected, messages)
+	}
+	if c.Flash.Data["custom"] != "kept" {
+		t.Errorf("Expected the custom flash value, got %v", c.Flash.Data)
+	}
+}
@@ -401,11 +401,27 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 		return
 	}
 
-	// Does it return a Result?
-	if funcDecl.Type.Results == nil || len(funcDecl.Type.Results.List) != 1 {
+	// Does it return a Result, or (Result, error)?
+	if funcDecl.Type.Results == nil {
 		return
 	}
-	selExpr, ok := funcDecl.Type.Results.List[0].Type.(*ast.SelectorExpr)
+	var results []ast.Expr
+	for _, field := range funcDecl.Type.Results.List {
+		results = append(results, field.Type)
+		for i := 1; i < len(field.Names); i++ {
+			results = append(results, field.Type)
+		}
+	}
+	switch len(results) {
+	case 1:
+	case 2:
+		if ident, ok := results[1].(*ast.Ident); !ok || ident.Name != "error" {
+			return
+		}
+	default:
+		return
+	}
+	selExpr, ok := results[0].(*ast.SelectorExpr)
 	if !ok {
 		return
 	}
@@ -459,8 +475,16 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 		}
 
 		// The type of the receiver is not easily available, so just store every
-		// call to any method called Render.
-		if selExpr.Sel.Name != "Render" {
+		// call to any method called Render (or RenderFormat, whose first
+		// argument is the format).
+		args := callExpr.Args
+		switch selExpr.Sel.Name {
+		case "Render":
+		case "RenderFormat":
+			if len(args) > 0 {
+				args = args[1:]
+			}
+		default:
 			return true
 		}
 
@@ -470,7 +494,7 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 			Line:  pos.Line,
 			Names: []string{},
 		}
-		for _, arg := range callExpr.Args {
+		for _, arg := range args {
 			argIdent, ok := arg.(*ast.Ident)
 			if !ok {
 				continue
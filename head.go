@@ -0,0 +1,67 @@
+package revel
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headResponseWriter answers a HEAD request with the status and headers that
+// a GET would have, without the body.  The body written by the result is
+// counted instead, to send the Content-Length (unless the result set one).
+// The headers are held until Close, or until the result flushes them.
+type headResponseWriter struct {
+	http.ResponseWriter
+	status int
+	length int
+	sent   bool
+}
+
+func newHeadResponseWriter(w http.ResponseWriter) *headResponseWriter {
+	return &headResponseWriter{ResponseWriter: w}
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.length += len(b)
+	return len(b), nil
+}
+
+// Flush sends the headers, e.g. for a stream that never ends, without the
+// Content-Length.
+func (w *headResponseWriter) Flush() {
+	w.send()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close sends the headers, if a result was written.
+func (w *headResponseWriter) Close() {
+	if w.status == 0 || w.sent {
+		return
+	}
+	header := w.Header()
+	if header.Get("Content-Length") == "" && bodyAllowedForStatus(w.status) {
+		header.Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.send()
+}
+
+func (w *headResponseWriter) send() {
+	if !w.sent {
+		w.sent = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// bodyAllowedForStatus returns false for the statuses that have no body, and
+// so no Content-Length.
+func bodyAllowedForStatus(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,48 @@
+package revel
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHeadRequests(t *testing.T) {
+	startFakeBookingApp()
+	for _, path := range []string{"/hotels/3", "/hotels/3/booking", "/hotels"} {
+		getReq, _ := http.NewRequest("GET", path, nil)
+		get := httptest.NewRecorder()
+		handle(get, getReq)
+
+		headReq, _ := http.NewRequest("HEAD", path, nil)
+		head := httptest.NewRecorder()
+		handle(head, headReq)
+
+		if head.Code != get.Code || head.Body.Len() != 0 ||
+			head.Header().Get("Content-Type") != get.Header().Get("Content-Type") ||
+			head.Header().Get("Content-Length") != strconv.Itoa(get.Body.Len()) {
+			t.Errorf("%s: expected the headers of a GET (%d %v, %d bytes), got %d %v %q",
+				path, get.Code, get.Header(), get.Body.Len(), head.Code, head.Header(), head.Body)
+		}
+	}
+}
+
+func TestHeadRequestBinary(t *testing.T) {
+	startFakeBookingApp()
+	content := []byte("0123456789")
+	// Both a seekable reader and a stream.
+	for _, r := range []io.Reader{bytes.NewReader(content), bytes.NewBuffer(content)} {
+		req, _ := http.NewRequest("HEAD", "/report.bin", nil)
+		resp := httptest.NewRecorder()
+		head := newHeadResponseWriter(resp)
+		c := NewController(NewRequest(req), NewResponse(head))
+		c.RenderBinaryWithLength(r, "report.bin", Attachment, time.Now(), int64(len(content))).Apply(c.Request, c.Response)
+		head.Close()
+		if resp.Code != http.StatusOK || resp.Body.Len() != 0 || resp.Header().Get("Content-Length") != "10" {
+			t.Errorf("%T: unexpected response %d %v %q", r, resp.Code, resp.Header(), resp.Body)
+		}
+	}
+}
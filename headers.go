@@ -0,0 +1,45 @@
+package revel
+
+import (
+	"net/http"
+)
+
+// DefaultHeaders are set on every response, before the filters run, so that a
+// filter or result may still override (or delete) any of them.  For example:
+//   func init() {
+//     revel.DefaultHeaders.Set("X-Frame-Options", "DENY")
+//   }
+var DefaultHeaders = http.Header{}
+
+// SecureHeaders are a preset of security headers, added to the DefaultHeaders
+// if "http.secureheaders" is set in app.conf (except where the app set them):
+//   X-Content-Type-Options: nosniff
+//   X-Frame-Options: SAMEORIGIN
+//   Referrer-Policy: strict-origin-when-cross-origin
+//   Strict-Transport-Security: max-age=31536000 (ignored by browsers over HTTP)
+var SecureHeaders = http.Header{
+	"X-Content-Type-Options":    {"nosniff"},
+	"X-Frame-Options":           {"SAMEORIGIN"},
+	"Referrer-Policy":           {"strict-origin-when-cross-origin"},
+	"Strict-Transport-Security": {"max-age=31536000"},
+}
+
+func init() {
+	OnAppStart(func() {
+		if !Config.BoolDefault("http.secureheaders", false) {
+			return
+		}
+		for name, values := range SecureHeaders {
+			if _, ok := DefaultHeaders[name]; !ok {
+				DefaultHeaders[name] = values
+			}
+		}
+	})
+}
+
+// setDefaultHeaders sets the DefaultHeaders on the response.
+func setDefaultHeaders(header http.Header) {
+	for name, values := range DefaultHeaders {
+		header[name] = append([]string(nil), values...)
+	}
+}
@@ -17,6 +17,10 @@ type Request struct {
 	AcceptLanguages AcceptLanguages
 	Locale          string
 	Websocket       *websocket.Conn
+
+	body     []byte // The body, once read by RawBody.
+	bodyErr  error
+	bodyRead bool
 }
 
 type Response struct {
@@ -24,6 +28,8 @@ type Response struct {
 	ContentType string
 
 	Out http.ResponseWriter
+
+	wroteHeader bool // Set by WriteHeader.
 }
 
 func NewResponse(w http.ResponseWriter) *Response {
@@ -51,6 +57,7 @@ func (resp *Response) WriteHeader(defaultStatusCode int, defaultContentType stri
 	}
 	resp.Out.Header().Set("Content-Type", resp.ContentType)
 	resp.Out.WriteHeader(resp.Status)
+	resp.wroteHeader = true
 }
 
 // Get the content type.
@@ -64,14 +71,32 @@ func ResolveContentType(req *http.Request) string {
 	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
 }
 
-// Resolve the accept request header.
+// ResolveFormat returns the format of the response the client wants: "html",
+// "xml", "json", or "txt".  In order of precedence, it is given by:
+//   1. the Accept header, e.g. "application/json" (or "application/vnd.api+json");
+//   2. otherwise, the Content-Type of the body, e.g. for an API client that
+//      posts JSON with "Accept: */*" or none;
+//   3. otherwise, "html".
+// (The extension of the URL path is not considered, since routes match it as
+// part of the path.)
 func ResolveFormat(req *http.Request) string {
-	accept := req.Header.Get("accept")
+	if format := acceptFormat(req.Header.Get("accept")); format != "" {
+		return format
+	}
+	if format := contentTypeFormat(req.Header.Get("Content-Type")); format != "" {
+		return format
+	}
+	return "html"
+}
 
+// acceptFormat returns the format named by the Accept header, or "" if it
+// names none (e.g. it is empty, or */*).
+func acceptFormat(accept string) string {
 	switch {
 	case accept == "",
-		strings.HasPrefix(accept, "*/*"), // */
-		strings.Contains(accept, "application/xhtml"),
+		strings.HasPrefix(accept, "*/*"): // */
+		return ""
+	case strings.Contains(accept, "application/xhtml"),
 		strings.Contains(accept, "text/html"):
 		return "html"
 	case strings.Contains(accept, "application/xml"),
@@ -82,9 +107,88 @@ func ResolveFormat(req *http.Request) string {
 	case strings.Contains(accept, "application/json"),
 		strings.Contains(accept, "text/javascript"):
 		return "json"
+	case acceptedSuffixType(accept, "+json") != "":
+		return "json"
+	case acceptedSuffixType(accept, "+xml") != "":
+		return "xml"
+	}
+	return ""
+}
+
+// contentTypeFormat returns the format of a request body of the given
+// Content-Type, or "" if it is not one (e.g. a form).
+func contentTypeFormat(contentType string) string {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return "xml"
+	case mediaType == "text/plain":
+		return "txt"
 	}
+	return ""
+}
 
-	return "html"
+// acceptedSuffixType returns the first media type in the Accept header that
+// has the given structured syntax suffix, e.g. "application/vnd.api+json" for
+// "+json", or "" if there is none.
+func acceptedSuffixType(accept, suffix string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.ToLower(strings.TrimSpace(strings.Split(mediaRange, ";")[0]))
+		if strings.HasSuffix(mediaType, suffix) {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// A single media range from the Accept HTTP header.
+type AcceptType struct {
+	MediaType string // e.g. "application/json", "text/*", or "*/*"
+	Quality   float32
+}
+
+// A collection of AcceptType instances, sortable by quality.
+type AcceptTypes []AcceptType
+
+func (at AcceptTypes) Len() int           { return len(at) }
+func (at AcceptTypes) Swap(i, j int)      { at[i], at[j] = at[j], at[i] }
+func (at AcceptTypes) Less(i, j int) bool { return at[i].Quality > at[j].Quality }
+
+// ResolveAcceptTypes returns the media ranges of the Accept header, most
+// preferred first.  Ranges of equal quality keep the order given.
+// e.g. "application/json;q=0.9, text/html" => [text/html (1), application/json (0.9)]
+func ResolveAcceptTypes(req *http.Request) AcceptTypes {
+	header := req.Header.Get("Accept")
+	if header == "" {
+		return nil
+	}
+
+	var acceptTypes AcceptTypes
+	for _, mediaRange := range strings.Split(header, ",") {
+		params := strings.Split(mediaRange, ";")
+		acceptType := AcceptType{strings.ToLower(strings.TrimSpace(params[0])), 1}
+		if acceptType.MediaType == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			quality, err := strconv.ParseFloat(param[2:], 32)
+			if err != nil {
+				WARN.Printf("Detected malformed Accept header quality in '%s', assuming quality is 1", mediaRange)
+				continue
+			}
+			acceptType.Quality = float32(quality)
+		}
+		acceptTypes = append(acceptTypes, acceptType)
+	}
+
+	sort.Stable(acceptTypes)
+	return acceptTypes
 }
 
 // A single language from the Accept-Language HTTP header.
@@ -0,0 +1,269 @@
+package revel
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// An IdempotencyStore holds the responses recorded for idempotency keys, and
+// tracks the keys whose requests are still being processed.
+type IdempotencyStore interface {
+	// Begin reserves the key for a new request.
+	// If a response was already stored for the key, it is returned.
+	// If another request holding the key is still in flight, inFlight is true.
+	// Otherwise, the key is reserved until Save or Release is called.
+	Begin(key string) (resp *RecordedResponse, inFlight bool)
+
+	// Save stores the response for the key and releases the reservation.
+	Save(key string, resp *RecordedResponse)
+
+	// Release drops the reservation without storing a response, allowing the
+	// request to be retried.
+	Release(key string)
+}
+
+// IdempotencyFilter returns a filter that makes POST and PATCH requests
+// bearing an Idempotency-Key header safe to retry:
+//
+//   - The first request with a given key is processed normally, and the
+//     rendered response is recorded in the store.
+//   - Later requests with the same key replay the recorded response without
+//     invoking the action.
+//   - A request whose key is held by another request still in flight gets a
+//     409 Conflict; the client is expected to retry once the first completes.
+//
+// Responses with a 5xx status are not recorded, so that failed requests may be
+// retried.  Cookies set by the original response are not replayed.
+//
+// Keys are scoped to the request path and to the client, as identified by
+// scopeFunc, so that clients may not see each other's responses by reusing a
+// key.  If scopeFunc is nil, the session ID is used.  Clients that do not keep
+// the session cookie (e.g. those of an API authenticated by token) need a
+// scopeFunc that identifies them.
+//
+// For example, to protect a payment action:
+//   revel.FilterAction(Payments.Charge).
+//     Add(revel.IdempotencyFilter(revel.NewInMemoryIdempotencyStore(24*time.Hour), nil))
+func IdempotencyFilter(store IdempotencyStore, scopeFunc func(c *Controller) string) Filter {
+	if scopeFunc == nil {
+		scopeFunc = func(c *Controller) string {
+			if c.Session == nil {
+				return ""
+			}
+			return c.Session.Id()
+		}
+	}
+	return func(c *Controller, fc []Filter) {
+		key := c.Request.Header.Get(IdempotencyKeyHeader)
+		if key == "" || (c.Request.Method != "POST" && c.Request.Method != "PATCH") {
+			fc[0](c, fc[1:])
+			return
+		}
+
+		key = c.Request.Method + " " + c.Request.URL.Path + " " + scopeFunc(c) + " " + key
+		prior, inFlight := store.Begin(key)
+		switch {
+		case prior != nil:
+			c.Result = prior
+			return
+		case inFlight:
+			c.Response.Status = http.StatusConflict
+			c.Result = c.RenderError(&Error{
+				Title:       "Conflict",
+				Description: "A request with this Idempotency-Key is already in progress",
+			})
+			return
+		}
+
+		// Release the key if the action panics, produces no result, or its
+		// result is replaced (e.g. by an interceptor) and never applied.
+		result := &idempotentResult{store: store, key: key}
+		c.addCleanup(func() {
+			if !result.done {
+				store.Release(key)
+			}
+		})
+
+		fc[0](c, fc[1:])
+		if c.Result != nil {
+			result.Result = c.Result
+			c.Result = result
+		}
+	}
+}
+
+// idempotentResult records the response of the wrapped Result as it is
+// applied, and stores it under the idempotency key.
+type idempotentResult struct {
+	Result
+	store IdempotencyStore
+	key   string
+	done  bool // Set once the key has been saved or released.
+}
+
+func (r *idempotentResult) Apply(req *Request, resp *Response) {
+	recorder := newResponseRecorder(resp.Out)
+	resp.Out = recorder
+	completed := false // An aborted response is not saved.
+	defer func() {
+		resp.Out = recorder.ResponseWriter
+		if recorded := recorder.SharedResponse(); completed && recorded.Status < http.StatusInternalServerError {
+			r.store.Save(r.key, recorded)
+		} else {
+			r.store.Release(r.key)
+		}
+		r.done = true
+	}()
+	r.Result.Apply(req, resp)
+	completed = true
+}
+
+// RecordedResponse is a captured HTTP response.
+// It is a Result that replays the response when applied.
+type RecordedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (r *RecordedResponse) Apply(req *Request, resp *Response) {
+	for key, values := range r.Header {
+		resp.Out.Header()[key] = values
+	}
+	resp.Status = r.Status
+	resp.Out.WriteHeader(r.Status)
+	resp.Out.Write(r.Body)
+}
+
+// responseRecorder passes writes through to the underlying ResponseWriter,
+// while keeping a copy of the status, headers, and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		// Snapshot the headers before the underlying writer (which may be
+		// compressing) adds its own.
+		r.header = make(http.Header, len(r.Header()))
+		for key, values := range r.Header() {
+			r.header[key] = append([]string(nil), values...)
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Flush() {
+	flushResponse(r.ResponseWriter)
+}
+
+// Response returns the response recorded so far.
+func (r *responseRecorder) Response() *RecordedResponse {
+	status, header := r.status, r.header
+	if status == 0 {
+		status, header = http.StatusOK, r.Header()
+	}
+	copied := make(http.Header, len(header))
+	for key, values := range header {
+		copied[key] = append([]string(nil), values...)
+	}
+	return &RecordedResponse{
+		Status: status,
+		Header: copied,
+		Body:   r.body.Bytes(),
+	}
+}
+
+// SharedResponse returns the response recorded so far, without the cookies it
+// set, so that it may be replayed to other requests without overwriting their
+// session and flash.
+func (r *responseRecorder) SharedResponse() *RecordedResponse {
+	resp := r.Response()
+	resp.Header.Del("Set-Cookie")
+	return resp
+}
+
+// InMemoryIdempotencyStore is an IdempotencyStore that keeps responses in
+// process memory for a fixed time.  It is suitable for a single server.
+type InMemoryIdempotencyStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	responses map[string]idempotencyEntry
+	inFlight  map[string]bool
+	nextSweep time.Time
+}
+
+type idempotencyEntry struct {
+	resp    *RecordedResponse
+	expires time.Time
+}
+
+// NewInMemoryIdempotencyStore returns a store that keeps each response for
+// the given time, after which the key may be used again.
+func NewInMemoryIdempotencyStore(ttl time.Duration) *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		ttl:       ttl,
+		responses: make(map[string]idempotencyEntry),
+		inFlight:  make(map[string]bool),
+	}
+}
+
+func (s *InMemoryIdempotencyStore) Begin(key string) (*RecordedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.responses[key]; ok {
+		if time.Now().Before(entry.expires) {
+			return entry.resp, false
+		}
+		delete(s.responses, key)
+	}
+	if s.inFlight[key] {
+		return nil, true
+	}
+	s.inFlight[key] = true
+	return nil, false
+}
+
+func (s *InMemoryIdempotencyStore) Save(key string, resp *RecordedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.responses[key] = idempotencyEntry{resp, now.Add(s.ttl)}
+	delete(s.inFlight, key)
+
+	// Drop expired responses, at most once a minute.
+	if now.After(s.nextSweep) {
+		for key, entry := range s.responses {
+			if now.After(entry.expires) {
+				delete(s.responses, key)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+}
+
+func (s *InMemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, key)
+}
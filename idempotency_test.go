@@ -0,0 +1,115 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyFilter(t *testing.T) {
+	startFakeBookingApp()
+	var (
+		store   = NewInMemoryIdempotencyStore(time.Hour)
+		filter  = IdempotencyFilter(store, func(c *Controller) string { return c.Request.Header.Get("X-User") })
+		charges = 0
+		charge  = func(c *Controller, _ []Filter) {
+			charges++
+			c.SetCookie(&http.Cookie{Name: "REVEL_FLASH", Value: "charged"})
+			c.Response.Status = http.StatusCreated
+			c.Result = c.RenderText("charged %d", charges)
+		}
+	)
+
+	serve := func(user, key string) *httptest.ResponseRecorder {
+		httpReq, _ := http.NewRequest("POST", "/charge", nil)
+		httpReq.Header.Set(IdempotencyKeyHeader, key)
+		httpReq.Header.Set("X-User", user)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		filter(c, []Filter{charge})
+		c.Result.Apply(c.Request, c.Response)
+		c.runCleanups()
+		return resp
+	}
+
+	first := serve("alice", "abc")
+	second := serve("alice", "abc")
+	if charges != 1 {
+		t.Errorf("Expected the action to be invoked once, got %d", charges)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != "charged 1" {
+		t.Errorf("Expected replayed response, got %d %q", second.Code, second.Body.String())
+	}
+	if first.Header().Get("Content-Type") != second.Header().Get("Content-Type") {
+		t.Errorf("Expected replayed headers, got %v", second.Header())
+	}
+	if second.Header().Get("Set-Cookie") != "" {
+		t.Errorf("Expected cookies not to be replayed, got %v", second.Header())
+	}
+
+	if serve("alice", "def").Body.String() != "charged 2" || charges != 2 {
+		t.Errorf("Expected a new key to invoke the action")
+	}
+	if serve("bob", "abc").Body.String() != "charged 3" || charges != 3 {
+		t.Errorf("Expected keys to be scoped to the client")
+	}
+}
+
+func TestIdempotencyFilterInFlight(t *testing.T) {
+	startFakeBookingApp()
+	store := NewInMemoryIdempotencyStore(time.Hour)
+	if _, inFlight := store.Begin("POST /charge  abc"); inFlight {
+		t.Fatal("Expected the key to be free")
+	}
+
+	httpReq, _ := http.NewRequest("POST", "/charge", nil)
+	httpReq.Header.Set(IdempotencyKeyHeader, "abc")
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(httpReq), NewResponse(resp))
+	IdempotencyFilter(store, nil)(c, []Filter{func(c *Controller, _ []Filter) {
+		t.Error("Expected the action not to be invoked")
+	}})
+	c.Result.Apply(c.Request, c.Response)
+	if resp.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an in-flight key, got %d", resp.Code)
+	}
+}
+
+func TestIdempotencyFilterReplacedResult(t *testing.T) {
+	startFakeBookingApp()
+	store := NewInMemoryIdempotencyStore(time.Hour)
+	httpReq, _ := http.NewRequest("POST", "/charge", nil)
+	httpReq.Header.Set(IdempotencyKeyHeader, "abc")
+	c := NewController(NewRequest(httpReq), NewResponse(httptest.NewRecorder()))
+	IdempotencyFilter(store, nil)(c, []Filter{func(c *Controller, _ []Filter) {
+		c.Result = c.RenderText("charged")
+	}})
+
+	// An interceptor replaces the result, so the recording one is never applied.
+	c.Result = c.RenderText("replaced")
+	c.Result.Apply(c.Request, c.Response)
+	c.runCleanups()
+	if _, inFlight := store.Begin("POST /charge  abc"); inFlight {
+		t.Errorf("Expected the key to be released")
+	}
+}
+
+func TestInMemoryIdempotencyStoreExpiry(t *testing.T) {
+	store := NewInMemoryIdempotencyStore(-time.Second)
+	store.Begin("key")
+	store.Save("key", &RecordedResponse{Status: http.StatusOK})
+	if resp, _ := store.Begin("key"); resp != nil {
+		t.Errorf("Expected the response to have expired")
+	}
+}
+
+func TestRecordedResponseStatus(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	recorded := &RecordedResponse{Status: http.StatusCreated, Header: http.Header{}, Body: []byte("ok")}
+	recorded.Apply(c.Request, c.Response)
+	if c.Response.Status != http.StatusCreated || resp.Code != http.StatusCreated {
+		t.Errorf("Expected the replayed status to be set on the response, got %d and %d", c.Response.Status, resp.Code)
+	}
+}
@@ -3,6 +3,7 @@ package revel
 import (
 	"log"
 	"reflect"
+	"strings"
 )
 
 // An "interceptor" is functionality invoked by the framework BEFORE or AFTER
@@ -60,6 +61,21 @@ type Interception struct {
 	callable     reflect.Value
 	target       reflect.Type
 	interceptAll bool
+	actions      []string // If set, the actions it applies to, e.g. "Hotels.Book".
+}
+
+// appliesTo returns true if the interception applies to the given action.
+// Actions are matched case-insensitively, as by the router.
+func (i Interception) appliesTo(action string) bool {
+	if len(i.actions) == 0 {
+		return true
+	}
+	for _, a := range i.actions {
+		if strings.EqualFold(a, action) {
+			return true
+		}
+	}
+	return false
 }
 
 // Perform the given interception.
@@ -110,6 +126,9 @@ func invokeInterceptors(when When, c *Controller) {
 		result Result
 	)
 	for _, intc := range getInterceptors(when, app) {
+		if !intc.appliesTo(c.Action) {
+			continue
+		}
 		resultValue := intc.Invoke(app)
 		if !resultValue.IsNil() {
 			result = resultValue.Interface().(Result)
@@ -157,6 +176,25 @@ func InterceptMethod(intc InterceptorMethod, when When) {
 	})
 }
 
+// Install an interceptor that applies only to the given actions, as resolved
+// by Controller.SetAction, for example to begin a transaction around just the
+// actions that write:
+//   revel.InterceptAction(beginTransaction, revel.BEFORE, "Hotels.Book", "Hotels.Cancel")
+//   revel.InterceptAction((*Hotels).commit, revel.AFTER, "Hotels.Book", "Hotels.Cancel")
+// The interceptor is either a func(*revel.Controller) revel.Result, or an
+// interceptor method of the actions' controller, as for InterceptMethod.
+func InterceptAction(intc interface{}, when When, actions ...string) {
+	switch f := intc.(type) {
+	case InterceptorFunc:
+		InterceptFunc(f, when, ALL_CONTROLLERS)
+	case func(*Controller) Result:
+		InterceptFunc(f, when, ALL_CONTROLLERS)
+	default:
+		InterceptMethod(intc, when)
+	}
+	interceptors[len(interceptors)-1].actions = actions
+}
+
 func getInterceptors(when When, val reflect.Value) []*Interception {
 	result := []*Interception{}
 	for _, intc := range interceptors {
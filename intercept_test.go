@@ -1,6 +1,8 @@
 package revel
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -79,3 +81,47 @@ func testInterception(t *testing.T, intc *Interception, arg reflect.Value) {
 		t.Errorf("Failed (%s): Expected nil got %s", intc, val)
 	}
 }
+
+func TestInterceptAction(t *testing.T) {
+	startFakeBookingApp()
+	defer func(saved []*Interception) { interceptors = saved }(interceptors)
+	interceptors = []*Interception{}
+
+	var intercepted []string
+	InterceptAction(func(c *Controller) Result {
+		intercepted = append(intercepted, "before "+c.Action)
+		return c.Forbidden("Not allowed")
+	}, BEFORE, "hotels.book")
+	InterceptAction(func(c *Controller) Result {
+		intercepted = append(intercepted, "after "+c.Action)
+		return nil
+	}, AFTER, "Hotels.Show", "Hotels.Book")
+	InterceptAction(func(c *Controller) Result {
+		panic("interceptor failed")
+	}, BEFORE, "Hotels.Index")
+
+	// The BEFORE interceptor short-circuits the action it applies to.
+	resp := httptest.NewRecorder()
+	handle(resp, jsonRequest)
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("Expected Hotels.Book to be forbidden, got %d", resp.Code)
+	}
+
+	// Only the AFTER interceptor applies to Hotels.Show.
+	resp = httptest.NewRecorder()
+	handle(resp, showRequest)
+	if resp.Code != http.StatusOK {
+		t.Errorf("Expected Hotels.Show to be rendered, got %d", resp.Code)
+	}
+	expected := []string{"before Hotels.Book", "after Hotels.Show"}
+	if !reflect.DeepEqual(intercepted, expected) {
+		t.Errorf("Expected interceptions %v, got %v", expected, intercepted)
+	}
+
+	// A panic in an interceptor is rendered as an error.
+	resp = httptest.NewRecorder()
+	handle(resp, plaintextRequest)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a panicking interceptor to render a 500, got %d", resp.Code)
+	}
+}
@@ -9,8 +9,57 @@ var (
 	controllerType    = reflect.TypeOf(Controller{})
 	controllerPtrType = reflect.TypeOf(&Controller{})
 	websocketType     = reflect.TypeOf((*websocket.Conn)(nil))
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
 )
 
+// ActionErrorRenderer returns the Result for the error returned by an action
+// that returns (revel.Result, error), in place of the action's Result.  By
+// default, it renders the error page.  It may be replaced on app start, e.g.
+// to map the app's own errors to statuses:
+//   revel.ActionErrorRenderer = func(c *revel.Controller, err error) revel.Result {
+//     if err == models.ErrNotFound {
+//       return c.NotFound("Not found")
+//     }
+//     return c.RenderError(err)
+//   }
+var ActionErrorRenderer = func(c *Controller, err error) Result {
+	return c.RenderError(err)
+}
+
+// PostBinder is implemented by app controllers that normalize their inputs
+// (e.g. lower-casing emails or stripping formatting from phone numbers) once
+// they are bound, before the action is invoked.
+//
+// PostBind is called after the action arguments have been bound successfully,
+// and before the action runs.  The arguments are available for modification in
+// c.BoundArgs.  Since the Validation of action arguments happens within the
+// action, it sees the normalized values.
+//
+// For example:
+//   func (c Users) PostBind() {
+//     if email, ok := c.BoundArgs["email"].(*string); ok {
+//       *email = strings.ToLower(strings.TrimSpace(*email))
+//     }
+//   }
+type PostBinder interface {
+	PostBind()
+}
+
+// RenderArgsDefaulter is implemented by app controllers whose actions share
+// render args (e.g. the navigation, or the current user).
+//
+// DefaultRenderArgs is called before each action of the controller runs, and
+// the values it returns are added to c.RenderArgs, except where a filter or
+// interceptor already set them.  The action may overwrite them.
+//
+// For example:
+//   func (c Admin) DefaultRenderArgs() map[string]interface{} {
+//     return map[string]interface{}{"section": "admin", "user": c.connected()}
+//   }
+type RenderArgsDefaulter interface {
+	DefaultRenderArgs() map[string]interface{}
+}
+
 func ActionInvoker(c *Controller, _ []Filter) {
 	// Instantiate the method.
 	methodValue := reflect.ValueOf(c.AppController).MethodByName(c.MethodType.Name)
@@ -25,17 +74,75 @@ func ActionInvoker(c *Controller, _ []Filter) {
 		} else {
 			TRACE.Println("Binding:", arg.Name, "as", arg.Type)
 			boundArg = Bind(c.Params, arg.Name, arg.Type)
+			if traceEnabled() {
+				TRACE.Println("Bound:", arg.Name, "=", Redact(boundArg.Interface()))
+			}
 		}
 		methodArgs = append(methodArgs, boundArg)
 	}
 
-	var resultValue reflect.Value
-	if methodValue.Type().IsVariadic() {
-		resultValue = methodValue.CallSlice(methodArgs)[0]
-	} else {
-		resultValue = methodValue.Call(methodArgs)[0]
+	// Respond with a 400 if any of the arguments could not be converted, rather
+	// than invoking the action with a zero value.  A slice is bound with the
+	// elements that could be converted, and the others are validation errors,
+	// for the action to check.
+	for _, arg := range c.MethodType.Args {
+		errs := c.Params.bindErrorsFor(arg.Name)
+		if len(errs) == 0 {
+			continue
+		}
+		if arg.Type.Kind() == reflect.Slice && c.Validation != nil {
+			for _, err := range errs {
+				c.Validation.Error("%s", err).Key(err.Name)
+			}
+			continue
+		}
+		c.Result = c.BadRequest("Invalid value for argument %s: %s", arg.Name, errs[0])
+		return
+	}
+
+	// Let the app controller normalize the arguments.
+	if postBinder, ok := c.AppController.(PostBinder); ok {
+		c.BoundArgs = make(map[string]interface{}, len(methodArgs))
+		for i, arg := range c.MethodType.Args {
+			ptr := reflect.New(arg.Type)
+			ptr.Elem().Set(methodArgs[i])
+			methodArgs[i] = ptr.Elem()
+			c.BoundArgs[arg.Name] = ptr.Interface()
+		}
+		postBinder.PostBind()
+	}
+	fireLifecycleEvent(AfterBind, c)
+
+	if defaulter, ok := c.AppController.(RenderArgsDefaulter); ok {
+		for key, value := range defaulter.DefaultRenderArgs() {
+			if _, ok := c.RenderArgs[key]; !ok {
+				c.RenderArgs[key] = value
+			}
+		}
+	}
+
+	invoke := func() Result {
+		var resultValues []reflect.Value
+		if methodValue.Type().IsVariadic() {
+			resultValues = methodValue.CallSlice(methodArgs)
+		} else {
+			resultValues = methodValue.Call(methodArgs)
+		}
+		if c.MethodType.ReturnsError && !resultValues[1].IsNil() {
+			return ActionErrorRenderer(c, resultValues[1].Interface().(error))
+		}
+		if resultValue := resultValues[0]; !resultValue.IsNil() {
+			return resultValue.Interface().(Result)
+		}
+		return nil
+	}
+
+	// Wrap the action in its middleware, the first outermost.
+	for i := len(c.MethodType.Middleware) - 1; i >= 0; i-- {
+		middleware, next := c.MethodType.Middleware[i], invoke
+		invoke = func() Result { return middleware(c, next) }
 	}
-	if resultValue.Kind() == reflect.Interface && !resultValue.IsNil() {
-		c.Result = resultValue.Interface().(Result)
+	if result := invoke(); result != nil {
+		c.Result = result
 	}
 }
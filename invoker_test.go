@@ -1,8 +1,12 @@
 package revel
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -78,6 +82,259 @@ func TestSetAction(t *testing.T) {
 	}
 }
 
+func TestAliasAction(t *testing.T) {
+	startFakeBookingApp()
+	AliasAction("Hotels.Show", "Display")
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	if err := c.SetAction("Hotels", "display"); err != nil {
+		t.Fatal(err)
+	}
+	if c.MethodType.Name != "Show" || c.Action != "Hotels.Show" {
+		t.Errorf("Expected the alias to invoke Hotels.Show, got %s", c.Action)
+	}
+}
+
+func TestInvokerBindFailure(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	if err := c.SetAction("Hotels", "Book"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{"id": {"abc"}}}
+
+	ActionInvoker(c, nil)
+	if c.Response.Status != http.StatusBadRequest {
+		t.Errorf("Expected a 400 for an unparseable id, got %d", c.Response.Status)
+	}
+	c.Result.Apply(c.Request, c.Response)
+	if !strings.Contains(resp.Body.String(), "argument id") {
+		t.Errorf("Expected the failed argument to be named, got:\n%s", resp.Body)
+	}
+}
+
+func TestInvokerBlankNumericArg(t *testing.T) {
+	startFakeBookingApp()
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	if err := c.SetAction("Hotels", "Show"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{"id": {""}}}
+
+	ActionInvoker(c, nil)
+	if c.Response.Status == http.StatusBadRequest {
+		t.Errorf("Expected a blank id to bind to zero, not fail")
+	}
+}
+
+type PostBindApp struct{ *Controller }
+
+func (c PostBindApp) PostBind() {
+	if email, ok := c.BoundArgs["email"].(*string); ok {
+		*email = strings.ToLower(*email)
+	}
+}
+
+func (c PostBindApp) Signup(email string) Result {
+	return c.RenderText("%s", email)
+}
+
+func TestInvokerPostBind(t *testing.T) {
+	RegisterController((*PostBindApp)(nil), []*MethodType{{
+		Name: "Signup",
+		Args: []*MethodArg{{Name: "email", Type: reflect.TypeOf((*string)(nil))}},
+	}})
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	if err := c.SetAction("PostBindApp", "Signup"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{"email": {"Bob@Example.com"}}}
+
+	ActionInvoker(c, nil)
+	c.Result.Apply(c.Request, c.Response)
+	if resp.Body.String() != "bob@example.com" {
+		t.Errorf("Expected the action to receive the normalized email, got %q", resp.Body.String())
+	}
+}
+
+type SliceArgApp struct{ *Controller }
+
+func (c SliceArgApp) Compare(ids []int) Result {
+	return c.RenderText("%v", ids)
+}
+
+func TestInvokerSliceArg(t *testing.T) {
+	RegisterController((*SliceArgApp)(nil), []*MethodType{{
+		Name: "Compare",
+		Args: []*MethodArg{{Name: "ids", Type: reflect.TypeOf((*[]int)(nil))}},
+	}})
+	for _, values := range []url.Values{
+		{"ids": {"1", "abc", "3"}},
+		{"ids[]": {"1", "abc", "3"}},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		if err := c.SetAction("SliceArgApp", "Compare"); err != nil {
+			t.Fatal(err)
+		}
+		c.Params = &Params{Values: values}
+		c.Validation = &Validation{}
+
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		if resp.Body.String() != "[1 3]" {
+			t.Errorf("Expected the valid ids to be bound from %v, got %q", values, resp.Body.String())
+		}
+		if len(c.Validation.Errors) != 1 || !strings.Contains(c.Validation.Errors[0].Message, `"abc"`) {
+			t.Errorf("Expected a validation error for abc, got %v", c.Validation.Errors)
+		}
+	}
+}
+
+type DefaultArgsApp struct{ *Controller }
+
+func (c DefaultArgsApp) DefaultRenderArgs() map[string]interface{} {
+	return map[string]interface{}{"section": "admin", "user": "guest", "title": "Admin"}
+}
+
+func (c DefaultArgsApp) Index() Result {
+	c.RenderArgs["title"] = "Dashboard"
+	return nil
+}
+
+func TestInvokerDefaultRenderArgs(t *testing.T) {
+	RegisterController((*DefaultArgsApp)(nil), []*MethodType{{Name: "Index"}})
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	if err := c.SetAction("DefaultArgsApp", "Index"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{}}
+	c.RenderArgs["user"] = "bob" // e.g. by an interceptor
+
+	ActionInvoker(c, nil)
+	for key, expected := range map[string]string{
+		"section": "admin",
+		"user":    "bob",
+		"title":   "Dashboard",
+	} {
+		if c.RenderArgs[key] != expected {
+			t.Errorf("Expected RenderArgs[%q] = %q, got %v", key, expected, c.RenderArgs[key])
+		}
+	}
+}
+
+type ErrorReturnApp struct{ *Controller }
+
+var errHotelNotFound = errors.New("hotel not found")
+
+func (c ErrorReturnApp) Show(id int) (Result, error) {
+	if id == 0 {
+		return nil, errHotelNotFound
+	}
+	return c.RenderText("hotel %d", id), nil
+}
+
+func TestInvokerErrorReturn(t *testing.T) {
+	startFakeBookingApp()
+	RegisterController((*ErrorReturnApp)(nil), []*MethodType{{
+		Name: "Show",
+		Args: []*MethodArg{{Name: "id", Type: reflect.TypeOf((*int)(nil))}},
+	}})
+	invoke := func(id string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		if err := c.SetAction("ErrorReturnApp", "Show"); err != nil {
+			t.Fatal(err)
+		}
+		if !c.MethodType.ReturnsError {
+			t.Fatal("Expected the method to be registered as returning an error")
+		}
+		c.Params = &Params{Values: url.Values{"id": {id}}}
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	if resp := invoke("3"); resp.Code != http.StatusOK || resp.Body.String() != "hotel 3" {
+		t.Errorf("Expected the action's result, got %d %q", resp.Code, resp.Body)
+	}
+	if resp := invoke("0"); resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the error to be rendered, got %d %q", resp.Code, resp.Body)
+	}
+
+	defer func(renderer func(*Controller, error) Result) { ActionErrorRenderer = renderer }(ActionErrorRenderer)
+	ActionErrorRenderer = func(c *Controller, err error) Result {
+		if err == errHotelNotFound {
+			return c.NotFound("Not found")
+		}
+		return c.RenderError(err)
+	}
+	if resp := invoke("0"); resp.Code != http.StatusNotFound {
+		t.Errorf("Expected the error to be mapped to a 404, got %d %q", resp.Code, resp.Body)
+	}
+}
+
+type MiddlewareApp struct{ *Controller }
+
+func (c MiddlewareApp) Book(hotel string) Result {
+	c.Args["trace"] = c.Args["trace"].(string) + " action(" + hotel + ")"
+	return c.RenderText("booked")
+}
+
+func TestInvokerMiddleware(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { methodMiddleware = make(map[string][]Middleware) }()
+
+	trace := func(name string) Middleware {
+		return func(c *Controller, next func() Result) Result {
+			c.Args["trace"] = c.Args["trace"].(string) + " " + name
+			result := next()
+			c.Args["trace"] = c.Args["trace"].(string) + " /" + name
+			return result
+		}
+	}
+	requireUser := func(c *Controller, next func() Result) Result {
+		if c.Session["user"] == "" {
+			return c.Forbidden("Log in first")
+		}
+		return next()
+	}
+	UseMiddleware("MiddlewareApp.Book", trace("auth"), requireUser)
+	RegisterController((*MiddlewareApp)(nil), []*MethodType{{
+		Name: "Book",
+		Args: []*MethodArg{{Name: "hotel", Type: reflect.TypeOf((*string)(nil))}},
+	}})
+	UseMiddleware("MiddlewareApp.Book", trace("log"))
+
+	invoke := func(user string) (*Controller, *httptest.ResponseRecorder) {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		if err := c.SetAction("MiddlewareApp", "Book"); err != nil {
+			t.Fatal(err)
+		}
+		c.Params = &Params{Values: url.Values{"hotel": {"3"}}}
+		c.Session = Session{"user": user}
+		c.Args["trace"] = ""
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		return c, resp
+	}
+
+	c, resp := invoke("bob")
+	if expected := " auth log action(3) /log /auth"; c.Args["trace"] != expected {
+		t.Errorf("Expected the middleware to wrap the action in order:\n%q\n%q", expected, c.Args["trace"])
+	}
+	if resp.Body.String() != "booked" {
+		t.Errorf("Expected the action's result, got %q", resp.Body)
+	}
+
+	c, resp = invoke("")
+	if resp.Code != http.StatusForbidden || c.Args["trace"] != " auth /auth" {
+		t.Errorf("Expected the middleware to stop the action, got %d %q", resp.Code, c.Args["trace"])
+	}
+}
+
 func BenchmarkSetAction(b *testing.B) {
 	type Mixin1 struct {
 		*Controller
@@ -0,0 +1,470 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JsonOptions control how a RenderJsonResult serializes its value.
+//
+// The app-wide options are read from app.conf (see DefaultJsonOptions), and
+// may be overridden for a single response with Controller.RenderJsonWith.
+type JsonOptions struct {
+	// KeyCase converts the keys of struct fields to the given convention,
+	// without having to re-tag structs.  The keys of maps are data (e.g.
+	// locales or user names), so they are left as-is, as is the output of types
+	// that marshal themselves.
+	// Configured by "results.json.keycase": "camel" or "snake".
+	KeyCase KeyCase
+
+	// MaxDepth limits how deeply objects and arrays may be nested, to guard
+	// against accidentally rendering large graphs of related records.
+	// Beyond it, objects and arrays are omitted if TruncateDepth is set, or
+	// else the response is an error.  Zero means unlimited.
+	// Configured by "results.json.maxdepth" and "results.json.truncatedepth".
+	MaxDepth      int
+	TruncateDepth bool
+
+	// FloatPrecision rounds floating point numbers to the given number of
+	// significant digits, e.g. 0.30000000000000004 to 0.3, for display-oriented
+	// APIs that do not need full precision.  Numbers without a fraction or
+	// exponent are not changed.  Zero means full precision.
+	// Since it changes values, it is not configurable app-wide.
+	FloatPrecision int
+
+	// Pretty indents the JSON by two spaces, for reading during development.
+	// Configured by "results.pretty", which defaults to true in dev mode.
+	Pretty bool
+
+	// NilBody is the body rendered for a nil value (a nil interface, pointer,
+	// map, or slice), e.g. "{}" for clients that expect an object.  If empty,
+	// nil values render as null.
+	// Configured by "results.json.nilbody".
+	NilBody string
+
+	// OmitNilPointers drops struct fields holding a nil pointer, rather than
+	// rendering them as null, whether or not they are tagged omitempty.
+	// Configured by "results.json.omitnilpointers".
+	OmitNilPointers bool
+
+	// ETag sets the ETag header to a hash of the rendered body, and responds
+	// 304 Not Modified to GET requests whose If-None-Match matches it.  Since
+	// hashing costs CPU on every request, it is off by default.  Values that
+	// are Versioned always get an ETag from their version instead.
+	// Configured by "results.json.etag".
+	ETag bool
+}
+
+// A KeyCase is a naming convention for JSON object keys.
+type KeyCase int
+
+const (
+	KeepCase  KeyCase = iota // Leave keys as marshaled.
+	CamelCase                // e.g. "userId"
+	SnakeCase                // e.g. "user_id"
+)
+
+// DefaultJsonOptions returns the app-wide JSON options configured in app.conf.
+// It is a convenient starting point for per-call options, for example:
+//   opts := revel.DefaultJsonOptions()
+//   opts.KeyCase = revel.SnakeCase
+//   return c.RenderJsonWith(legacyObj, opts)
+func DefaultJsonOptions() JsonOptions {
+	opts := JsonOptions{
+		MaxDepth:      Config.IntDefault("results.json.maxdepth", 0),
+		TruncateDepth: Config.BoolDefault("results.json.truncatedepth", false),
+		ETag:          Config.BoolDefault("results.json.etag", false),
+
+		OmitNilPointers: Config.BoolDefault("results.json.omitnilpointers", false),
+		NilBody:         Config.StringDefault("results.json.nilbody", ""),
+		Pretty:          Config.BoolDefault("results.pretty", DevMode),
+	}
+	switch keyCase := Config.StringDefault("results.json.keycase", ""); keyCase {
+	case "camel":
+		opts.KeyCase = CamelCase
+	case "snake":
+		opts.KeyCase = SnakeCase
+	case "":
+	default:
+		WARN.Println("Unknown results.json.keycase:", keyCase)
+	}
+	return opts
+}
+
+// rewrites returns true if transform may change the marshaled JSON.
+func (opts JsonOptions) rewrites() bool {
+	return opts.KeyCase != KeepCase || opts.MaxDepth != 0 || opts.FloatPrecision != 0 || opts.OmitNilPointers
+}
+
+// transform applies the options that rewrite b, the marshaled JSON of obj.
+func (opts JsonOptions) transform(obj interface{}, b []byte) ([]byte, error) {
+	t := jsonTransform{
+		root:      reflect.ValueOf(obj),
+		maxDepth:  opts.MaxDepth,
+		truncate:  opts.TruncateDepth,
+		precision: opts.FloatPrecision,
+		omitNil:   opts.OmitNilPointers,
+	}
+	switch opts.KeyCase {
+	case CamelCase:
+		t.key = toCamelCase
+	case SnakeCase:
+		t.key = toSnakeCase
+	}
+	if t.key == nil && t.maxDepth == 0 && t.precision == 0 && !t.omitNil {
+		return b, nil
+	}
+	return t.apply(b)
+}
+
+// jsonTransform rewrites marshaled JSON token by token, which (unlike
+// unmarshaling into a map) preserves the order of object keys.
+type jsonTransform struct {
+	root     reflect.Value       // The value that was marshaled.
+	key      func(string) string // If set, rewrites the keys of struct fields.
+	maxDepth int                 // If non-zero, the deepest allowed nesting of objects and arrays.
+	truncate bool                // If true, containers nested too deeply are omitted, else an error.
+
+	precision int  // If non-zero, the number of significant digits to round floats to.
+	omitNil   bool // If true, struct fields holding nil pointers are omitted.
+}
+
+// A jsonFrame tracks the object or array being rewritten.
+type jsonFrame struct {
+	object  bool   // true for an object, false for an array
+	written int    // Number of values written so far.
+	index   int    // Number of values read so far.
+	key     string // The key of the next value, if an object.
+	haveKey bool   // True once the key of the next value has been read.
+
+	// The Go value that was marshaled as this object or array, if known.
+	value reflect.Value
+}
+
+func (t jsonTransform) apply(b []byte) ([]byte, error) {
+	var (
+		out   bytes.Buffer
+		stack []*jsonFrame
+		dec   = json.NewDecoder(bytes.NewReader(b))
+	)
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Closing a container needs no separator.
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			out.WriteByte(byte(delim))
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		var frame *jsonFrame
+		if len(stack) > 0 {
+			frame = stack[len(stack)-1]
+		}
+
+		// Hold on to object keys until their value is known to be written.
+		if frame != nil && frame.object && !frame.haveKey {
+			frame.key, frame.haveKey = tok.(string), true
+			continue
+		}
+
+		// Omit (or reject) containers nested too deeply.
+		if delim, ok := tok.(json.Delim); ok && t.maxDepth > 0 && len(stack) >= t.maxDepth {
+			if !t.truncate {
+				return nil, &Error{
+					Title:       "JSON Render Error",
+					Description: fmt.Sprintf("Can not render %s as JSON: nested more than %d levels deep", jsonPath(stack), t.maxDepth),
+				}
+			}
+			if err = skipJsonContainer(dec, delim); err != nil {
+				return nil, err
+			}
+			if frame != nil {
+				frame.index++
+				frame.haveKey = false
+			}
+			continue
+		}
+
+		// Omit struct fields holding nil pointers.
+		if tok == nil && t.omitNil && frame != nil && frame.object && frame.value.Kind() == reflect.Struct {
+			if field := jsonChild(frame.value, frame.key, frame.index); field.Kind() == reflect.Ptr && field.IsNil() {
+				frame.index++
+				frame.haveKey = false
+				continue
+			}
+		}
+
+		// Find the Go value of a nested object or array, to tell which objects
+		// are structs.
+		var value reflect.Value
+		if _, ok := tok.(json.Delim); ok {
+			if frame == nil {
+				value = jsonIndirect(t.root)
+			} else if frame.value.IsValid() {
+				value = jsonIndirect(jsonChild(frame.value, frame.key, frame.index))
+			}
+		}
+
+		// Write the separator and key, if any.
+		if frame != nil {
+			if frame.written > 0 {
+				out.WriteByte(',')
+			}
+			if frame.object {
+				key := frame.key
+				if t.key != nil && frame.value.Kind() == reflect.Struct {
+					key = t.key(key)
+				}
+				s, _ := json.Marshal(key)
+				out.Write(s)
+				out.WriteByte(':')
+			}
+			frame.written++
+			frame.index++
+			frame.haveKey = false
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			stack = append(stack, &jsonFrame{object: v == '{', value: value})
+		case string:
+			s, _ := json.Marshal(v)
+			out.Write(s)
+		case json.Number:
+			out.WriteString(t.number(v))
+		case bool:
+			if v {
+				out.WriteString("true")
+			} else {
+				out.WriteString("false")
+			}
+		case nil:
+			out.WriteString("null")
+		}
+	}
+}
+
+// number returns the JSON for the number, rounded to the precision.
+func (t jsonTransform) number(n json.Number) string {
+	if t.precision == 0 || !strings.ContainsAny(string(n), ".eE") {
+		return string(n)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return string(n)
+	}
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', t.precision, 64), 64)
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+// jsonIndirect dereferences pointers and interfaces to the value that
+// encoding/json marshaled.  Values that marshal themselves are opaque, so they
+// are returned as invalid.
+func jsonIndirect(v reflect.Value) reflect.Value {
+	for v.IsValid() {
+		if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) ||
+			reflect.PtrTo(v.Type()).Implements(jsonMarshalerType) ||
+			reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+			return reflect.Value{}
+		}
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// jsonChild returns the value that encoding/json marshaled under the given key
+// (of a struct or map) or index (of a slice or array) of v, or an invalid
+// Value if it can not be determined.
+func jsonChild(v reflect.Value, key string, index int) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		if fieldIndex, ok := jsonFields(v.Type())[key]; ok {
+			for _, i := range fieldIndex {
+				if v.Kind() == reflect.Ptr {
+					if v.IsNil() {
+						return reflect.Value{}
+					}
+					v = v.Elem()
+				}
+				v = v.Field(i)
+			}
+			return v
+		}
+	case reflect.Map:
+		keyType := v.Type().Key()
+		switch keyType.Kind() {
+		case reflect.String:
+			return v.MapIndex(reflect.ValueOf(key).Convert(keyType))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n, err := strconv.ParseInt(key, 10, 64); err == nil {
+				return v.MapIndex(reflect.ValueOf(n).Convert(keyType))
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n, err := strconv.ParseUint(key, 10, 64); err == nil {
+				return v.MapIndex(reflect.ValueOf(n).Convert(keyType))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if index < v.Len() {
+			return v.Index(index)
+		}
+	}
+	return reflect.Value{}
+}
+
+var (
+	jsonFieldsCache   = make(map[reflect.Type]map[string][]int)
+	jsonFieldsCacheMu sync.Mutex
+)
+
+// jsonFields returns the index of each field of the struct type, keyed by the
+// name encoding/json gives it.  The fields of embedded structs are included,
+// unless a shallower field has the same name.
+func jsonFields(t reflect.Type) map[string][]int {
+	jsonFieldsCacheMu.Lock()
+	defer jsonFieldsCacheMu.Unlock()
+	if fields, ok := jsonFieldsCache[t]; ok {
+		return fields
+	}
+	fields := make(map[string][]int)
+	addJsonFields(fields, t, nil, make(map[string]int))
+	jsonFieldsCache[t] = fields
+	return fields
+}
+
+func addJsonFields(fields map[string][]int, t reflect.Type, index []int, depths map[string]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		// Promote the fields of embedded structs without a name.
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addJsonFields(fields, fieldType, fieldIndex, depths)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if depth, ok := depths[name]; ok && depth <= len(fieldIndex) {
+			continue
+		}
+		fields[name], depths[name] = fieldIndex, len(fieldIndex)
+	}
+}
+
+// skipJsonContainer reads past the rest of the object or array opened by delim.
+func skipJsonContainer(dec *json.Decoder, delim json.Delim) error {
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// jsonPath describes the location of the value being read, e.g. "user.friends[2]".
+func jsonPath(stack []*jsonFrame) string {
+	path := "value"
+	for _, frame := range stack {
+		if frame.object {
+			path += "." + frame.key
+		} else {
+			path += fmt.Sprintf("[%d]", frame.index)
+		}
+	}
+	return path
+}
+
+// splitWords splits an identifier into words at underscores, hyphens, spaces,
+// and changes of case, keeping acronyms together.
+// e.g. "user_name" => [user name], "UserID" => [User ID], "HTTPServer" => [HTTP Server]
+func splitWords(s string) []string {
+	var (
+		words []string
+		runes = []rune(s)
+		start = 0
+	)
+	for i := 0; i <= len(runes); i++ {
+		if i == len(runes) || runes[i] == '_' || runes[i] == '-' || runes[i] == ' ' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i > start && unicode.IsUpper(runes[i]) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			endOfAcronym := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || endOfAcronym {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+	}
+	return words
+}
+
+func toCamelCase(s string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			first, size := utf8.DecodeRuneInString(word)
+			word = string(unicode.ToUpper(first)) + word[size:]
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+func toSnakeCase(s string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
@@ -0,0 +1,157 @@
+package revel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	jsonSchemas   = make(map[string]map[string]interface{})
+	jsonSchemasMu sync.RWMutex
+)
+
+// RegisterJsonSchema registers a JSON Schema describing the JSON rendered by
+// the action, e.g. "Users.Show".  In dev mode, each JSON response of the
+// action is checked against it, and any mismatch is logged as a warning (the
+// response is sent regardless), to catch accidental changes to an API.
+// Outside of dev mode, the schemas are not used.
+//
+// The supported keywords are type, properties, required,
+// additionalProperties (false), items, and enum.  For example:
+//   revel.RegisterJsonSchema("Users.Show", `{
+//     "type": "object",
+//     "required": ["id", "name"],
+//     "properties": {
+//       "id":   {"type": "integer"},
+//       "name": {"type": "string"},
+//       "tags": {"type": "array", "items": {"type": "string"}}
+//     }
+//   }`)
+//
+// It panics if the schema is not a JSON object.
+func RegisterJsonSchema(action, schema string) {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		panic(fmt.Sprintf("revel: invalid JSON schema for %s: %s", action, err))
+	}
+	jsonSchemasMu.Lock()
+	defer jsonSchemasMu.Unlock()
+	jsonSchemas[action] = parsed
+}
+
+// checkJsonSchema logs how the rendered JSON of the action departs from its
+// registered schema, if it has one.
+func checkJsonSchema(action string, b []byte) {
+	jsonSchemasMu.RLock()
+	schema, ok := jsonSchemas[action]
+	jsonSchemasMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return
+	}
+	for _, mismatch := range validateJsonSchema(schema, value, "value") {
+		WARN.Printf("JSON response of %s does not match its schema: %s", action, mismatch)
+	}
+}
+
+// validateJsonSchema returns a description of each way that the value, as
+// decoded by encoding/json, does not match the schema.
+func validateJsonSchema(schema map[string]interface{}, value interface{}, path string) (mismatches []string) {
+	if types, ok := schema["type"]; ok && !jsonSchemaTypeMatches(types, value) {
+		return []string{fmt.Sprintf("%s is %s, expected %v", path, jsonSchemaType(value), types)}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %v, expected one of %v", path, value, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[fmt.Sprint(name)]; !ok {
+					mismatches = append(mismatches, fmt.Sprintf("%s.%v is missing", path, name))
+				}
+			}
+		}
+
+		// Check the properties in order, for a stable log.
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propertySchema, ok := properties[name].(map[string]interface{}); ok {
+				mismatches = append(mismatches, validateJsonSchema(propertySchema, v[name], path+"."+name)...)
+			} else if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				mismatches = append(mismatches, fmt.Sprintf("%s.%s is not in the schema", path, name))
+			}
+		}
+
+	case []interface{}:
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				mismatches = append(mismatches, validateJsonSchema(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return mismatches
+}
+
+// jsonSchemaTypeMatches returns true if the value has the schema type, or one
+// of the list of schema types.
+func jsonSchemaTypeMatches(types, value interface{}) bool {
+	actual := jsonSchemaType(value)
+	var allowed []interface{}
+	switch t := types.(type) {
+	case []interface{}:
+		allowed = t
+	default:
+		allowed = []interface{}{t}
+	}
+	for _, t := range allowed {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonSchemaType returns the schema type of a value decoded by encoding/json.
+func jsonSchemaType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if !strings.ContainsAny(fmt.Sprint(v), ".e") {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,74 @@
+package revel
+
+import (
+	"bytes"
+	"log"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testUserSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"additionalProperties": false,
+	"properties": {
+		"id":   {"type": "integer"},
+		"name": {"type": "string"},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"boss": {"type": ["object", "null"]}
+	}
+}`
+
+func TestValidateJsonSchema(t *testing.T) {
+	startFakeBookingApp()
+	RegisterJsonSchema("Test.User", testUserSchema)
+	defer delete(jsonSchemas, "Test.User")
+	schema := jsonSchemas["Test.User"]
+
+	var (
+		valid   = map[string]interface{}{"id": 1.0, "name": "Ann", "role": "admin", "tags": []interface{}{"a"}, "boss": nil}
+		invalid = map[string]interface{}{"id": 1.5, "role": "root", "tags": []interface{}{"a", 2.0}, "email": "x"}
+	)
+	if mismatches := validateJsonSchema(schema, valid, "value"); len(mismatches) != 0 {
+		t.Errorf("Expected no mismatches, got %v", mismatches)
+	}
+	expected := []string{
+		"value.name is missing",
+		"value.email is not in the schema",
+		"value.id is number, expected integer",
+		`value.role is root, expected one of [admin user]`,
+		"value.tags[1] is integer, expected string",
+	}
+	if mismatches := validateJsonSchema(schema, invalid, "value"); !reflect.DeepEqual(mismatches, expected) {
+		t.Errorf("Expected mismatches:\n%v\ngot:\n%v", expected, mismatches)
+	}
+}
+
+func TestRenderJsonSchemaInDevMode(t *testing.T) {
+	startFakeBookingApp()
+	RegisterJsonSchema("Hotels.Show", testUserSchema)
+	defer delete(jsonSchemas, "Hotels.Show")
+	var logged bytes.Buffer
+	defer func(logger *log.Logger) { WARN = logger }(WARN)
+	WARN = log.New(&logged, "", 0)
+
+	render := func(devMode bool) *httptest.ResponseRecorder {
+		defer func(devMode bool) { DevMode = devMode }(DevMode)
+		DevMode = devMode
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+		c.SetAction("Hotels", "Show")
+		c.RenderJson(map[string]interface{}{"id": 1}).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	if resp := render(false); logged.Len() != 0 || resp.Body.String() != `{"id":1}` {
+		t.Errorf("Expected no schema check outside of dev mode, got %q", logged.String())
+	}
+	if resp := render(true); !strings.Contains(logged.String(), "value.name is missing") || resp.Body.String() != "{\n  \"id\": 1\n}" {
+		t.Errorf("Expected the mismatch to be logged without changing the response, got %q", logged.String())
+	}
+}
@@ -1,9 +1,15 @@
 package revel
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"io"
 )
 
@@ -11,16 +17,84 @@ import (
 // If no secret key is set, returns the empty string.
 // Return the signature in base64 (URLEncoding).
 func Sign(message string) string {
-	if len(secretKey) == 0 {
+	return signWith(secretKey, message)
+}
+
+func signWith(key []byte, message string) string {
+	if len(key) == 0 {
 		return ""
 	}
-	mac := hmac.New(sha1.New, secretKey)
+	mac := hmac.New(sha1.New, key)
 	io.WriteString(mac, message)
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
 // Verify returns true if the given signature is correct for the given message.
 // e.g. it matches what we generate with Sign()
+//
+// Signatures made with a previous secret ("app.secret.previous") are accepted
+// as well, so that the secret may be rotated without invalidating cookies.
 func Verify(message, sig string) bool {
-	return hmac.Equal([]byte(sig), []byte(Sign(message)))
+	for _, key := range verificationKeys() {
+		if hmac.Equal([]byte(sig), []byte(signWith(key, message))) {
+			return true
+		}
+	}
+	return false
+}
+
+// verificationKeys returns the current secret key, followed by the previous.
+func verificationKeys() [][]byte {
+	return append([][]byte{secretKey}, previousSecretKeys...)
+}
+
+// Encrypt encrypts and authenticates the message with the app-configured
+// secret key (by AES-GCM, keyed by its SHA-256 hash), so that it can be
+// neither read nor altered by the client.  The result is base64 (URLEncoding).
+func Encrypt(message string) (string, error) {
+	if len(secretKey) == 0 {
+		return "", errors.New("revel: encryption requires app.secret")
+	}
+	aead, err := newAead(secretKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(message), nil)
+	return base64.URLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the message encrypted by Encrypt, with the current or a
+// previous secret key, or false if it was not, or was altered.
+func Decrypt(ciphertext string) (string, bool) {
+	sealed, err := base64.URLEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", false
+	}
+	for _, key := range verificationKeys() {
+		if len(key) == 0 {
+			continue
+		}
+		aead, err := newAead(key)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			continue
+		}
+		nonce, box := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if message, err := aead.Open(nil, nonce, box, nil); err == nil {
+			return string(message), true
+		}
+	}
+	return "", false
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	hash := sha256.Sum256(key)
+	block, err := aes.NewCipher(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
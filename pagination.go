@@ -0,0 +1,75 @@
+package revel
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RenderPage renders one page of a collection as JSON, describing the
+// collection in GitHub-style headers:
+//   X-Total-Count: 95
+//   Link: </hotels?page=3&per_page=20>; rel="next", </hotels?page=5&per_page=20>; rel="last", ...
+//
+// Pages are numbered from 1.  The links point to the current action by
+// reverse routing, with the query string of the request and its "page" param
+// replaced.  For example:
+//   func (c Hotels) List(page, per_page int) revel.Result {
+//     hotels, total := findHotels((page-1)*per_page, per_page)
+//     return c.RenderPage(hotels, page, per_page, total)
+//   }
+func (c *Controller) RenderPage(items interface{}, page, perPage, total int) Result {
+	lastPage := 1
+	if perPage > 0 && total > perPage {
+		lastPage = (total + perPage - 1) / perPage
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	pageUrl := c.pageUrlFunc()
+	var links []string
+	addLink := func(page int, rel string) {
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, pageUrl(page), rel))
+	}
+	if page > 1 {
+		addLink(1, "first")
+		addLink(page-1, "prev")
+	}
+	if page < lastPage {
+		addLink(page+1, "next")
+		addLink(lastPage, "last")
+	}
+
+	header := c.Response.Out.Header()
+	header.Set("X-Total-Count", strconv.Itoa(total))
+	if len(links) > 0 {
+		header.Set("Link", strings.Join(links, ", "))
+	}
+	return c.RenderJson(items)
+}
+
+// pageUrlFunc returns a function giving the URL of the current action for a
+// page number.
+func (c *Controller) pageUrlFunc() func(page int) string {
+	path := c.Request.URL.Path
+	if c.Action != "" && MainRouter != nil {
+		routeArgs := make(map[string]string)
+		for key := range c.Params.Route {
+			routeArgs[key] = c.Params.Route.Get(key)
+		}
+		if action := MainRouter.Reverse(c.Action, routeArgs); action != nil {
+			path = strings.SplitN(action.Url, "?", 2)[0]
+		}
+	}
+
+	query := make(url.Values)
+	for key, values := range c.Request.URL.Query() {
+		query[key] = values
+	}
+	return func(page int) string {
+		query.Set("page", strconv.Itoa(page))
+		return path + "?" + query.Encode()
+	}
+}
@@ -1,10 +1,16 @@
 package revel
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // Params provides a unified view of the request params.
@@ -17,7 +23,11 @@ import (
 type Params struct {
 	url.Values // A unified view of all the individual param maps below.
 
-	// Set by the router
+	// Set by the router.
+	// A trailing wildcard in the route, e.g. /wiki/*path, captures the rest of
+	// the path as a single param, slashes included: c.Params.Route.Get("path")
+	// is "guides/intro" for /wiki/guides/intro.  Route params are URL-decoded,
+	// so an escaped slash (%2F) can not be told apart from a separator.
 	Fixed url.Values // Fixed parameters from the route, e.g. App.Action("fixed param")
 	Route url.Values // Parameters extracted from the route,  e.g. /customers/{id}
 
@@ -27,26 +37,82 @@ type Params struct {
 
 	Files    map[string][]*multipart.FileHeader // Files uploaded in a multipart form
 	tmpFiles []*os.File                         // Temp files used during the request.
+	filesErr error                              // Why the files could not be read, if so.
+	formErr  error                              // Why the body could not be read, if so.
+
+	rawQuery string // The query string as received, without the "?".
+
+	bindErrors []*BindError // Values that could not be converted during binding.
+}
+
+// ErrFormTooLarge is the error for url-encoded form bodies larger than
+// "http.maxformsize".  The ParamsFilter responds to it (and ErrUploadTooLarge)
+// with a 413 Request Entity Too Large.
+var ErrFormTooLarge = errors.New("revel/params: form too large")
+
+// Limits on parsing request bodies, so that a huge form can not exhaust
+// memory.  Set in app.conf by:
+//   http.maxformsize   - the largest url-encoded form body (10MB by default;
+//                        0 for net/http's limit, which is also 10MB)
+//   http.maxformmemory - how much of a multipart form is held in memory; the
+//                        rest of its files are written to temp files (32MB)
+// The size of a multipart body is limited by "http.maxuploadsize", if set.
+var (
+	maxFormSize   int64 = 10 << 20
+	maxFormMemory int64 = 32 << 20
+)
+
+func init() {
+	OnAppStart(func() {
+		maxFormSize = int64(Config.IntDefault("http.maxformsize", 10<<20))
+		if maxFormMemory = int64(Config.IntDefault("http.maxformmemory", 32<<20)); maxFormMemory <= 0 {
+			panic(fmt.Errorf("http.maxformmemory invalid: %d", maxFormMemory))
+		}
+	})
 }
 
 func ParseParams(params *Params, req *Request) {
 	params.Query = req.URL.Query()
+	params.rawQuery = req.URL.RawQuery
 
 	// Parse the body depending on the content type.
 	switch req.ContentType {
 	case "application/x-www-form-urlencoded":
 		// Typical form.
+		if maxFormSize > 0 {
+			if req.ContentLength > maxFormSize {
+				params.formErr = ErrFormTooLarge
+				break
+			}
+			req.Body = http.MaxBytesReader(nil, req.Body, maxFormSize)
+		}
 		if err := req.ParseForm(); err != nil {
+			if isMaxBytesError(err) {
+				err = ErrFormTooLarge
+			}
 			WARN.Println("Error parsing request body:", err)
+			params.formErr = err
 		} else {
 			params.Form = req.Form
 		}
 
 	case "multipart/form-data":
 		// Multipart form.
-		// TODO: Extract the multipart form param so app can set it.
-		if err := req.ParseMultipartForm(32 << 20 /* 32 MB */); err != nil {
+		if maxUploadSize > 0 {
+			if req.ContentLength > maxUploadSize {
+				params.filesErr = ErrUploadTooLarge
+				params.formErr = ErrUploadTooLarge
+				break
+			}
+			req.Body = http.MaxBytesReader(nil, req.Body, maxUploadSize)
+		}
+		if err := req.ParseMultipartForm(maxFormMemory); err != nil {
+			if isMaxBytesError(err) {
+				err = ErrUploadTooLarge
+			}
 			WARN.Println("Error parsing request body:", err)
+			params.filesErr = err
+			params.formErr = err
 		} else {
 			params.Form = req.MultipartForm.Value
 			params.Files = req.MultipartForm.File
@@ -56,6 +122,121 @@ func ParseParams(params *Params, req *Request) {
 	params.Values = params.calcValues()
 }
 
+// isMaxBytesError returns true if err is from reading past the limit of an
+// http.MaxBytesReader.
+func isMaxBytesError(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// RawQuery returns the query string exactly as it was received, without the
+// leading "?".  Unlike re-encoding Query, it preserves the original order and
+// escaping of the parameters, e.g. for verifying a signature over them.
+func (p *Params) RawQuery() string {
+	return p.rawQuery
+}
+
+// ErrParamMissing is returned by the Required getters (e.g. RequiredInt) for
+// a param that is missing or blank.
+var ErrParamMissing = errors.New("revel/params: missing parameter")
+
+// GetString returns the named param, or def if it is missing or blank.
+// Like the other getters, it reads the first of the param's values in Values
+// (the query string, form, and route together).
+func (p *Params) GetString(name, def string) string {
+	if value, err := p.RequiredString(name); err == nil {
+		return value
+	}
+	return def
+}
+
+// GetInt returns the named param as an int, or def if it is missing, blank, or
+// not an int:
+//   page := c.Params.GetInt("page", 1)
+func (p *Params) GetInt(name string, def int) int {
+	if value, err := p.RequiredInt(name); err == nil {
+		return value
+	}
+	return def
+}
+
+// GetFloat returns the named param as a float64, or def if it is missing,
+// blank, or not a number.
+func (p *Params) GetFloat(name string, def float64) float64 {
+	if value, err := p.RequiredFloat(name); err == nil {
+		return value
+	}
+	return def
+}
+
+// GetBool returns the named param as a bool, or def if it is missing, blank,
+// or not a bool: "true", "on", "yes", and "1" are true, and "false", "off",
+// "no", and "0" are false, ignoring case.
+func (p *Params) GetBool(name string, def bool) bool {
+	if value, err := p.RequiredBool(name); err == nil {
+		return value
+	}
+	return def
+}
+
+// RequiredString returns the named param, or ErrParamMissing if it is missing
+// or blank.
+func (p *Params) RequiredString(name string) (string, error) {
+	if value := p.Get(name); strings.TrimSpace(value) != "" {
+		return value, nil
+	}
+	return "", ErrParamMissing
+}
+
+// RequiredInt returns the named param as an int.  The error is
+// ErrParamMissing if it is missing or blank, or a *BindError if it is not an
+// int:
+//   id, err := c.Params.RequiredInt("id")
+//   if err != nil {
+//     return c.BadRequest("Invalid id: %s", err)
+//   }
+func (p *Params) RequiredInt(name string) (int, error) {
+	value, err := p.RequiredString(name)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, &BindError{name, value, reflect.TypeOf(i)}
+	}
+	return i, nil
+}
+
+// RequiredFloat returns the named param as a float64, with errors as
+// RequiredInt.
+func (p *Params) RequiredFloat(name string) (float64, error) {
+	value, err := p.RequiredString(name)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, &BindError{name, value, reflect.TypeOf(f)}
+	}
+	return f, nil
+}
+
+// RequiredBool returns the named param as a bool, as GetBool reads it, with
+// errors as RequiredInt.
+func (p *Params) RequiredBool(name string) (bool, error) {
+	value, err := p.RequiredString(name)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "on", "yes", "1":
+		return true, nil
+	case "false", "off", "no", "0":
+		return false, nil
+	}
+	return false, &BindError{name, value, reflect.TypeOf(false)}
+}
+
 // Bind looks for the named parameter, converts it to the requested type, and
 // writes it into "dest", which must be settable.  If the value can not be
 // parsed, "dest" is set to the zero value.
@@ -71,6 +252,108 @@ func (p *Params) Bind(dest interface{}, name string) {
 	value.Set(Bind(p, name, value.Type()))
 }
 
+// BindStruct binds a whole form into the struct that dest points to.  Fields
+// are bound from the params under the prefix by name, ignoring case, or by a
+// form tag, and nested structs and slices are bound as well, e.g.:
+//   type User struct {
+//     Name      string
+//     Addresses []Address
+//     Password  string `form:"-"`
+//   }
+//   type Address struct {
+//     City string `form:"city"`
+//   }
+//   var user User
+//   errs := c.Params.BindStruct(&user, "user")  // user.name, user.addresses[0].city
+// Values are converted as action arguments are.  The returned errors are the
+// values that could not be converted, keyed by their param name (e.g.
+// "user.addresses[0].zip"); those fields are left zero.
+func (p *Params) BindStruct(dest interface{}, prefix string) []*BindError {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		panic("revel/params: non-struct pointer passed to BindStruct: " + prefix)
+	}
+	p.Bind(dest, prefix)
+	return p.bindErrorsFor(prefix)
+}
+
+// BindJsonField decodes the JSON held by the named parameter into dest, for
+// clients that post a form with a JSON blob in a field, e.g. "payload={...}".
+// If the JSON can not be decoded, an error keyed by the param name is
+// recorded in v.  If the param is absent, dest is left unchanged.
+//   var payload Payload
+//   c.Params.BindJsonField(&payload, "payload", c.Validation)
+func (p *Params) BindJsonField(dest interface{}, name string, v *Validation) {
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		panic("revel/params: non-pointer passed to BindJsonField: " + name)
+	}
+	value := p.Get(name)
+	if value == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(value), dest); err != nil {
+		v.Error("Invalid JSON: %s", err).Key(name)
+	}
+}
+
+// bindErrorsFor returns the errors encountered binding the named param,
+// including any of its fields or elements (e.g. "user.Age" or "ids[0]").
+// A param bound more than once (e.g. an element of a slice of structs, which
+// is bound for each of its fields) is reported once.
+func (p *Params) bindErrorsFor(name string) (errs []*BindError) {
+	seen := make(map[string]bool)
+	for _, err := range p.bindErrors {
+		if seen[err.Name] {
+			continue
+		}
+		if err.Name == name ||
+			strings.HasPrefix(err.Name, name+".") ||
+			strings.HasPrefix(err.Name, name+"[") {
+			errs = append(errs, err)
+			seen[err.Name] = true
+		}
+	}
+	return errs
+}
+
+// ToMap returns all params as a generic map, for logging or processing without
+// a target struct.  Keys with a single value map to a string, and keys with
+// several values map to a []string.
+//
+// A key supplied by more than one source takes the values of only one of them,
+// in order of precedence: route, fixed, form, query.  For example, given the
+// route /users/:id and the request /users/5?id=6, "id" maps to "5".
+//
+// Uploaded files are included under their field name as a
+// *multipart.FileHeader, or a []*multipart.FileHeader if several files were
+// uploaded with that name.  A file never replaces a form value of the same name.
+func (p *Params) ToMap() map[string]interface{} {
+	result := make(map[string]interface{}, len(p.Values)+len(p.Files))
+	for _, source := range []url.Values{p.Route, p.Fixed, p.Form, p.Query} {
+		for key, vals := range source {
+			if _, ok := result[key]; ok || len(vals) == 0 {
+				continue
+			}
+			if len(vals) == 1 {
+				result[key] = vals[0]
+			} else {
+				result[key] = append([]string(nil), vals...)
+			}
+		}
+	}
+	for key, fileHeaders := range p.Files {
+		if _, ok := result[key]; ok || len(fileHeaders) == 0 {
+			continue
+		}
+		if len(fileHeaders) == 1 {
+			result[key] = fileHeaders[0]
+		} else {
+			result[key] = fileHeaders
+		}
+	}
+	return result
+}
+
 // calcValues returns a unified view of the component param maps.
 func (p *Params) calcValues() url.Values {
 	numParams := len(p.Query) + len(p.Fixed) + len(p.Route) + len(p.Form)
@@ -109,6 +392,19 @@ func (p *Params) calcValues() url.Values {
 	return values
 }
 
+// QueryParamsFilter is an alternative to ParamsFilter that parses only the
+// query string, leaving the request body unread so that the action may stream
+// it (e.g. with Controller.StreamMultipartUpload).  To use it for an action:
+//   revel.FilterAction(App.Upload).
+//     Insert(revel.QueryParamsFilter, revel.BEFORE, revel.ParamsFilter).
+//     Remove(revel.ParamsFilter)
+func QueryParamsFilter(c *Controller, fc []Filter) {
+	c.Params.Query = c.Request.URL.Query()
+	c.Params.rawQuery = c.Request.URL.RawQuery
+	c.Params.Values = c.Params.calcValues()
+	fc[0](c, fc[1:])
+}
+
 func ParamsFilter(c *Controller, fc []Filter) {
 	ParseParams(c.Params, c.Request)
 
@@ -130,5 +426,14 @@ func ParamsFilter(c *Controller, fc []Filter) {
 		}
 	}()
 
+	if err := c.Params.formErr; err == ErrFormTooLarge || err == ErrUploadTooLarge {
+		c.Response.Status = http.StatusRequestEntityTooLarge
+		c.Result = c.RenderError(&Error{
+			Title:       "Request Entity Too Large",
+			Description: "The request body is too large",
+		})
+		return
+	}
+
 	fc[0](c, fc[1:])
 }
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -133,6 +137,232 @@ func TestBind(t *testing.T) {
 	}
 }
 
+func TestToMap(t *testing.T) {
+	c := Controller{
+		Request: NewRequest(getMultipartRequest()),
+		Params:  &Params{},
+	}
+	ParamsFilter(&c, NilChain)
+
+	m := c.Params.ToMap()
+	if m["text1"] != "data1" {
+		t.Errorf("Expected single value to be a string, got %#v", m["text1"])
+	}
+	if !reflect.DeepEqual(m["text2"], []string{"data2", "data3"}) {
+		t.Errorf("Expected multiple values to be a slice, got %#v", m["text2"])
+	}
+	if fileHeader, ok := m["file1"].(*multipart.FileHeader); !ok || fileHeader.Filename != "test.txt" {
+		t.Errorf("Expected single file to be a *multipart.FileHeader, got %#v", m["file1"])
+	}
+	if fileHeaders, ok := m["file2[]"].([]*multipart.FileHeader); !ok || len(fileHeaders) != 2 {
+		t.Errorf("Expected multiple files to be a slice, got %#v", m["file2[]"])
+	}
+}
+
+func TestToMapPrecedence(t *testing.T) {
+	p := &Params{
+		Route: url.Values{"id": {"5"}},
+		Query: url.Values{"id": {"6"}, "tag": {"a", "b"}},
+	}
+	p.Values = p.calcValues()
+
+	m := p.ToMap()
+	if m["id"] != "5" {
+		t.Errorf("Expected the route param to take precedence over the query, got %#v", m["id"])
+	}
+	if !reflect.DeepEqual(m["tag"], []string{"a", "b"}) {
+		t.Errorf("Expected the query param values, got %#v", m["tag"])
+	}
+}
+
+func TestRawQuery(t *testing.T) {
+	const rawQuery = "sig=a%2Fb&z=1&a=%7E2"
+	req, _ := http.NewRequest("GET", "http://localhost/hook?"+rawQuery, nil)
+	c := Controller{
+		Request: NewRequest(req),
+		Params:  &Params{},
+	}
+	ParamsFilter(&c, NilChain)
+
+	if actual := c.Params.RawQuery(); actual != rawQuery {
+		t.Errorf("Expected the raw query %q, got %q", rawQuery, actual)
+	}
+	if c.Params.Get("sig") != "a/b" {
+		t.Errorf("Expected the parsed param to be unchanged, got %q", c.Params.Get("sig"))
+	}
+}
+
+func TestFormTooLarge(t *testing.T) {
+	startFakeBookingApp()
+	defer func(size int64) { maxFormSize = size }(maxFormSize)
+	maxFormSize = 10
+
+	for _, test := range []struct {
+		body        string
+		knownLength bool
+		status      int
+	}{
+		{"a=1&b=2", true, 0},
+		{"a=1&b=2&c=3", true, http.StatusRequestEntityTooLarge},
+		{"a=1&b=2&c=3", false, http.StatusRequestEntityTooLarge},
+	} {
+		req, _ := http.NewRequest("POST", "http://localhost/hotels", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if !test.knownLength {
+			req.ContentLength = -1
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		ran := false
+		ParamsFilter(c, []Filter{func(c *Controller, _ []Filter) { ran = true }})
+		if c.Response.Status != test.status {
+			t.Errorf("Expected status %d for %q, got %d", test.status, test.body, c.Response.Status)
+		}
+		if test.status != 0 {
+			c.Result.Apply(c.Request, c.Response)
+			if resp.Code != test.status || !strings.Contains(resp.Body.String(), "<title>Request Entity Too Large</title>") {
+				t.Errorf("Expected the 413 error page for %q, got %d:\n%s", test.body, resp.Code, resp.Body)
+			}
+		}
+		if ran != (test.status == 0) {
+			t.Errorf("Expected the action to run: %v, for %q", test.status == 0, test.body)
+		}
+		if test.status == 0 && c.Params.Get("b") != "2" {
+			t.Errorf("Expected the form to be parsed, got %v", c.Params.Values)
+		}
+	}
+}
+
+func TestParamsTypedGetters(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"page":  {"3", "4"},
+		"blank": {""},
+		"space": {" "},
+		"bad":   {"3x"},
+		"price": {" 9.5 "},
+		"on":    {"On"},
+		"off":   {"no"},
+		"name":  {" Bob "},
+	}}
+
+	if v := params.GetInt("page", 1); v != 3 {
+		t.Errorf("Expected the first page value, got %d", v)
+	}
+	for _, name := range []string{"missing", "blank", "space", "bad", "price"} {
+		if v := params.GetInt(name, 1); v != 1 {
+			t.Errorf("Expected the default for %s, got %d", name, v)
+		}
+	}
+	if v := params.GetFloat("price", 0); v != 9.5 {
+		t.Errorf("Expected 9.5, got %v", v)
+	}
+	if v := params.GetFloat("bad", 1.5); v != 1.5 {
+		t.Errorf("Expected the default for a malformed float, got %v", v)
+	}
+	if !params.GetBool("on", false) || params.GetBool("off", true) || !params.GetBool("bad", true) {
+		t.Errorf("Unexpected bools")
+	}
+	if v := params.GetString("name", "x"); v != " Bob " {
+		t.Errorf("Expected the string as given, got %q", v)
+	}
+	if v := params.GetString("blank", "x"); v != "x" {
+		t.Errorf("Expected the default for a blank string, got %q", v)
+	}
+
+	if _, err := params.RequiredInt("missing"); err != ErrParamMissing {
+		t.Errorf("Expected ErrParamMissing, got %v", err)
+	}
+	if _, err := params.RequiredInt("blank"); err != ErrParamMissing {
+		t.Errorf("Expected ErrParamMissing for a blank param, got %v", err)
+	}
+	if _, err := params.RequiredInt("bad"); err == nil || err.Error() != `"3x" is not a valid int` {
+		t.Errorf("Expected a BindError, got %v", err)
+	}
+	if _, err := params.RequiredBool("bad"); err == nil {
+		t.Errorf("Expected an error for a malformed bool")
+	}
+	if v, err := params.RequiredFloat("price"); err != nil || v != 9.5 {
+		t.Errorf("Expected 9.5, got %v (%v)", v, err)
+	}
+}
+
+func TestBindJsonField(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"payload": {`{"id": 5, "tags": ["a", "b"]}`},
+		"broken":  {`{"id": `},
+	}}
+	validation := &Validation{}
+
+	var payload struct {
+		Id   int
+		Tags []string
+	}
+	params.BindJsonField(&payload, "payload", validation)
+	if payload.Id != 5 || !reflect.DeepEqual(payload.Tags, []string{"a", "b"}) || validation.HasErrors() {
+		t.Errorf("Expected the payload to be decoded, got %+v %v", payload, validation.Errors)
+	}
+
+	params.BindJsonField(&payload, "broken", validation)
+	if err := validation.ErrorMap()["broken"]; err == nil || !strings.HasPrefix(err.Message, "Invalid JSON") {
+		t.Errorf("Expected a validation error for invalid JSON, got %v", validation.Errors)
+	}
+}
+
+type bindStructAddress struct {
+	City string `form:"city"`
+	Zip  int
+}
+
+type bindStructUser struct {
+	Name      string
+	Age       int
+	Addresses []bindStructAddress
+	Password  string `form:"-"`
+}
+
+func TestBindStruct(t *testing.T) {
+	startFakeBookingApp()
+	params := &Params{Values: url.Values{
+		"user.name":               {"Ann"},
+		"user.age":                {"old"},
+		"user.addresses[0].city":  {"Paris"},
+		"user.addresses[1].city":  {"Lyon"},
+		"user.addresses[1].zip":   {"69001"},
+		"user.addresses[0].zip":   {"x"},
+		"user.Password":           {"hunter2"},
+		"other.addresses[0].city": {"Rome"},
+	}}
+
+	var user bindStructUser
+	errs := params.BindStruct(&user, "user")
+	expected := bindStructUser{
+		Name:      "Ann",
+		Addresses: []bindStructAddress{{City: "Paris"}, {City: "Lyon", Zip: 69001}},
+	}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, user)
+	}
+
+	var failed []string
+	for _, err := range errs {
+		failed = append(failed, err.Name)
+	}
+	sort.Strings(failed)
+	if !reflect.DeepEqual(failed, []string{"user.addresses[0].zip", "user.age"}) {
+		t.Errorf("Expected the fields that failed to bind, got %v", failed)
+	}
+
+	// Tagged fields are unbound by their tag.
+	output := make(map[string]string)
+	Unbind(output, "user", expected)
+	if output["user.Addresses[0].city"] != "Paris" || output["user.Name"] != "Ann" {
+		t.Errorf("Unexpected unbound values: %v", output)
+	}
+	if _, ok := output["user.Password"]; ok {
+		t.Errorf("Expected the untagged password not to be unbound: %v", output)
+	}
+}
+
 func TestResolveAcceptLanguage(t *testing.T) {
 	request := buildHttpRequestWithAcceptLanguage("")
 	if result := ResolveAcceptLanguage(request); result != nil {
@@ -0,0 +1,94 @@
+package revel
+
+import (
+	"sync"
+	"time"
+)
+
+// A RateLimitStore counts the requests made under each key, in fixed windows
+// of time.  Applications with several processes need a shared store, e.g. one
+// backed by Redis.
+type RateLimitStore interface {
+	// Increment counts a request under the key, and returns the number of
+	// requests counted in the current window (including this one) and the time
+	// the window ends.  A window begins with the first request after the last
+	// one ended.
+	Increment(key string, window time.Duration) (count int, reset time.Time, err error)
+}
+
+// RateLimitStorage is the store that RateLimit counts requests in.  It keeps
+// them in memory unless replaced, e.g. on app start.
+var RateLimitStorage RateLimitStore = NewMemoryRateLimitStore()
+
+// RateLimit throttles an action to limit requests per window from each client,
+// for example:
+//   revel.RateLimit(Hotels.Search, 10, time.Minute, nil)
+// Requests beyond the limit get a 429 Too Many Requests, with a Retry-After
+// header giving the end of the window, instead of invoking the action.
+//
+// Clients are told apart by keyFunc, or by their IP address (see
+// Controller.ClientIP) if it is nil.
+// For example, to limit each user instead:
+//   revel.RateLimit(Exports.Create, 5, time.Hour, func(c *revel.Controller) string {
+//     return c.Session["userId"]
+//   })
+//
+// It is applied by an interceptor, so it must be called on app start, like
+// InterceptAction.  If the store fails, the request is allowed.
+func RateLimit(methodRef interface{}, limit int, window time.Duration, keyFunc func(c *Controller) string) {
+	if keyFunc == nil {
+		keyFunc = (*Controller).ClientIP
+	}
+	action := FilterAction(methodRef).key
+	InterceptAction(func(c *Controller) Result {
+		count, reset, err := RateLimitStorage.Increment(action+" "+keyFunc(c), window)
+		if err != nil {
+			ERROR.Println("Failed to count request for rate limit:", err)
+			return nil
+		}
+		if count > limit {
+			return c.TooManyRequestsUntil(reset, "Too many requests; try again later")
+		}
+		return nil
+	}, BEFORE, action)
+}
+
+// MemoryRateLimitStore counts requests in memory, for a single process.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	windows map[string]*rateLimitWindow
+	swept   time.Time
+}
+
+type rateLimitWindow struct {
+	count int
+	reset time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]*rateLimitWindow), swept: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Increment(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+
+	// Clients that stop making requests would otherwise be kept forever.
+	if now.Sub(s.swept) > time.Minute {
+		for key, w := range s.windows {
+			if !now.Before(w.reset) {
+				delete(s.windows, key)
+			}
+		}
+		s.swept = now
+	}
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.reset) {
+		w = &rateLimitWindow{reset: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+	return w.count, w.reset, nil
+}
@@ -0,0 +1,57 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	startFakeBookingApp()
+	defer func(saved []*Interception) { interceptors = saved }(interceptors)
+	defer func(saved RateLimitStore) { RateLimitStorage = saved }(RateLimitStorage)
+	interceptors = []*Interception{}
+	RateLimitStorage = NewMemoryRateLimitStore()
+
+	RateLimit(Hotels.Index, 2, time.Minute, nil)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		req.RemoteAddr = remoteAddr
+		resp := httptest.NewRecorder()
+		handle(resp, req)
+		return resp
+	}
+	for i, expected := range []int{http.StatusOK, http.StatusOK, 429} {
+		if resp := request("10.0.0.1:5000"); resp.Code != expected {
+			t.Errorf("Request %d: expected %d, got %d", i+1, expected, resp.Code)
+		} else if expected == 429 && resp.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected a Retry-After header")
+		}
+	}
+
+	// Other clients, and other actions, are not limited.
+	if resp := request("10.0.0.2:5000"); resp.Code != http.StatusOK {
+		t.Errorf("Expected another client to be allowed, got %d", resp.Code)
+	}
+	resp := httptest.NewRecorder()
+	showRequest.RemoteAddr = "10.0.0.1:5000"
+	handle(resp, showRequest)
+	showRequest.RemoteAddr = ""
+	if resp.Code != http.StatusOK {
+		t.Errorf("Expected another action to be allowed, got %d", resp.Code)
+	}
+}
+
+func TestMemoryRateLimitStoreWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	store.Increment("key", time.Millisecond)
+	if count, _, _ := store.Increment("key", time.Millisecond); count != 2 {
+		t.Errorf("Expected the second request to be counted, got %d", count)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if count, _, _ := store.Increment("key", time.Millisecond); count != 1 {
+		t.Errorf("Expected a new window, got %d", count)
+	}
+}
@@ -0,0 +1,121 @@
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// The text that replaces redacted values.
+const redactedText = "***"
+
+// Limits how far Redact descends, which also protects it from cyclic data.
+const maxRedactDepth = 16
+
+// Redact returns a value that formats v like fmt's %+v, except that struct
+// fields tagged `log:"redact"` (e.g. passwords and tokens) are replaced by
+// "***".  It is used when the framework logs rendered payloads and bound
+// params, and may be used for application logging as well:
+//   type Login struct {
+//     Name     string
+//     Password string `log:"redact"`
+//   }
+//   revel.INFO.Println("Login:", revel.Redact(login))  // Login: {Name:bob Password:***}
+//
+// The value is formatted along with the message, so the framework only calls
+// Redact when TRACE is on.
+// Only what is logged is affected; rendered responses are not.
+func Redact(v interface{}) fmt.Stringer {
+	return redacted{v}
+}
+
+type redacted struct {
+	v interface{}
+}
+
+func (r redacted) String() string {
+	var b bytes.Buffer
+	writeRedacted(&b, reflect.ValueOf(r.v), 0)
+	return b.String()
+}
+
+func writeRedacted(b *bytes.Buffer, v reflect.Value, depth int) {
+	if !v.IsValid() {
+		b.WriteString("<nil>")
+		return
+	}
+	if depth > maxRedactDepth {
+		b.WriteString("...")
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			b.WriteByte('&')
+		}
+		writeRedacted(b, v.Elem(), depth+1)
+
+	case reflect.Struct:
+		b.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(field.Name)
+			b.WriteByte(':')
+			if field.Tag.Get("log") == "redact" {
+				b.WriteString(redactedText)
+			} else {
+				writeRedacted(b, v.Field(i), depth+1)
+			}
+		}
+		b.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(b, "%v", v)
+			return
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeRedacted(b, v.Index(i), depth+1)
+		}
+		b.WriteByte(']')
+
+	case reflect.Map:
+		// Sort the keys by their formatting, for a stable result.
+		keys := v.MapKeys()
+		keyStrings := make([]string, len(keys))
+		byString := make(map[string]reflect.Value, len(keys))
+		for i, key := range keys {
+			keyStrings[i] = fmt.Sprint(key)
+			byString[keyStrings[i]] = key
+		}
+		sort.Strings(keyStrings)
+		b.WriteString("map[")
+		for i, key := range keyStrings {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(key)
+			b.WriteByte(':')
+			writeRedacted(b, v.MapIndex(byString[key]), depth+1)
+		}
+		b.WriteByte(']')
+
+	default:
+		// fmt formats the value held, even that of an unexported field (which
+		// can not be had through Interface).
+		fmt.Fprintf(b, "%+v", v)
+	}
+}
@@ -0,0 +1,74 @@
+package revel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type redactTestLogin struct {
+	Name     string
+	Password string `log:"redact"`
+	Tokens   []redactTestToken
+}
+
+type redactTestToken struct {
+	Value string `log:"redact"`
+	Scope string
+}
+
+func TestRedact(t *testing.T) {
+	login := &redactTestLogin{"bob", "hunter2", []redactTestToken{{"abc", "read"}}}
+	expected := "&{Name:bob Password:*** Tokens:[{Value:*** Scope:read}]}"
+	if actual := Redact(login).String(); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+
+	// Unexported fields are redacted as well.
+	wrapper := struct {
+		login *redactTestLogin
+		count int
+	}{login, 2}
+	expected = "{login:&{Name:bob Password:*** Tokens:[{Value:*** Scope:read}]} count:2}"
+	if actual := Redact(wrapper).String(); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+
+	m := map[string]interface{}{"b": redactTestToken{"abc", "write"}, "a": 1}
+	expected = "map[a:1 b:{Value:*** Scope:write}]"
+	if actual := Redact(m).String(); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestRenderJsonLogsRedacted(t *testing.T) {
+	startFakeBookingApp()
+	var logged bytes.Buffer
+	defer func(logger *log.Logger) { TRACE = logger }(TRACE)
+	TRACE = log.New(&logged, "", 0)
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJson(redactTestLogin{Name: "bob", Password: "hunter2"}).Apply(c.Request, c.Response)
+	if strings.Contains(logged.String(), "hunter2") || !strings.Contains(logged.String(), "Password:***") {
+		t.Errorf("Expected the password to be redacted from the log:\n%s", logged.String())
+	}
+	if !strings.Contains(resp.Body.String(), "hunter2") {
+		t.Errorf("Expected the response to be unaffected:\n%s", resp.Body)
+	}
+}
+
+func TestTraceEnabled(t *testing.T) {
+	defer func(logger *log.Logger) { TRACE = logger }(TRACE)
+	TRACE = log.New(ioutil.Discard, "", 0)
+	if traceEnabled() {
+		t.Error("Expected TRACE to be off when it discards its output")
+	}
+	TRACE = log.New(&bytes.Buffer{}, "", 0)
+	if !traceEnabled() {
+		t.Error("Expected TRACE to be on when it has an output")
+	}
+}
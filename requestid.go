@@ -0,0 +1,68 @@
+package revel
+
+import (
+	"fmt"
+	"github.com/streadway/simpleuuid"
+	"log"
+	"regexp"
+	"time"
+)
+
+// RequestIdHeader carries the ID of a request: it is read from the request,
+// if the client (or a proxy) gave one, and written to every response.
+const RequestIdHeader = "X-Request-Id"
+
+// An incoming request ID is only used if it matches this, so that it can not
+// forge log lines; otherwise a new one is generated.
+var requestIdPattern = regexp.MustCompile(`^[\w\-.:+=/]{1,128}$`)
+
+// RequestID returns the ID of the request, for correlating its log lines (see
+// Log) with those of other services that handled it.  It is the request's
+// X-Request-Id, if valid, or else a new UUID.
+func (c *Controller) RequestID() string {
+	if c.requestId == "" {
+		if id := c.Request.Header.Get(RequestIdHeader); requestIdPattern.MatchString(id) {
+			c.requestId = id
+		} else if uuid, err := simpleuuid.NewTime(time.Now()); err == nil {
+			c.requestId = uuid.String()
+		}
+	}
+	return c.requestId
+}
+
+// RequestLogger writes to the app's loggers, prefixing each line with the ID
+// of the request.
+type RequestLogger struct {
+	c *Controller
+}
+
+// Log returns a logger for the request, whose lines are prefixed with its
+// RequestID, so that those of one request may be picked out:
+//   c.Log().Infof("Booking hotel %d", id)
+//   => INFO  2013/07/01 12:00:00 hotels.go:42: [5c2f...] Booking hotel 3
+func (c *Controller) Log() RequestLogger {
+	return RequestLogger{c}
+}
+
+func (l RequestLogger) output(logger *log.Logger, line string) {
+	// The call depth skips output and the RequestLogger method.
+	logger.Output(3, "["+l.c.RequestID()+"] "+line)
+}
+
+func (l RequestLogger) Trace(v ...interface{}) { l.output(TRACE, fmt.Sprintln(v...)) }
+func (l RequestLogger) Info(v ...interface{})  { l.output(INFO, fmt.Sprintln(v...)) }
+func (l RequestLogger) Warn(v ...interface{})  { l.output(WARN, fmt.Sprintln(v...)) }
+func (l RequestLogger) Error(v ...interface{}) { l.output(ERROR, fmt.Sprintln(v...)) }
+
+func (l RequestLogger) Tracef(format string, v ...interface{}) {
+	l.output(TRACE, fmt.Sprintf(format, v...))
+}
+func (l RequestLogger) Infof(format string, v ...interface{}) {
+	l.output(INFO, fmt.Sprintf(format, v...))
+}
+func (l RequestLogger) Warnf(format string, v ...interface{}) {
+	l.output(WARN, fmt.Sprintf(format, v...))
+}
+func (l RequestLogger) Errorf(format string, v ...interface{}) {
+	l.output(ERROR, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,49 @@
+package revel
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		header   string
+		expected string // "" for a generated ID
+	}{
+		{"", ""},
+		{"abc-123", "abc-123"},
+		// An ID that could forge log lines is replaced.
+		{"abc\n[x] forged", ""},
+		{strings.Repeat("a", 129), ""},
+	} {
+		req, _ := http.NewRequest("GET", "http://localhost/hotels/3", nil)
+		if test.header != "" {
+			req.Header.Set(RequestIdHeader, test.header)
+		}
+		resp := httptest.NewRecorder()
+		handle(resp, req)
+		id := resp.Header().Get(RequestIdHeader)
+		if test.expected != "" && id != test.expected {
+			t.Errorf("Expected request ID %q, got %q", test.expected, id)
+		}
+		if test.expected == "" && (id == "" || id == test.header) {
+			t.Errorf("Expected a generated request ID for %q, got %q", test.header, id)
+		}
+	}
+
+	var buf bytes.Buffer
+	defer func(logger *log.Logger) { INFO = logger }(INFO)
+	INFO = log.New(&buf, "INFO ", log.Lshortfile)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIdHeader, "abc-123")
+	c := NewController(NewRequest(req), nil)
+	c.Log().Infof("Booking hotel %d", 3)
+	if expected := "INFO requestid_test.go:45: [abc-123] Booking hotel 3\n"; buf.String() != expected {
+		t.Errorf("Expected log line %q, got %q", expected, buf.String())
+	}
+}
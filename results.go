@@ -1,16 +1,26 @@
 package revel
 
 import (
+	"bufio"
 	"bytes"
+	"encoding"
+	"encoding/binary"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"gopkg.in/vmihailenco/msgpack.v4"
+	"gopkg.in/yaml.v2"
+	"html/template"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -109,7 +119,9 @@ func (r PlaintextErrorResult) Apply(req *Request, resp *Response) {
 // Action methods return this result to request a template be rendered.
 type RenderTemplateResult struct {
 	Template   Template
+	Layout     Template // If set, it wraps the output of Template.
 	RenderArgs map[string]interface{}
+	ModTime    time.Time // If set, the Last-Modified time; see Controller.RenderStatic.
 }
 
 func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
@@ -122,11 +134,20 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 		}
 	}()
 
+	// Let clients that already have the page revalidate it without rendering.
+	if !r.ModTime.IsZero() {
+		resp.Out.Header().Set("Last-Modified", r.ModTime.UTC().Format(http.TimeFormat))
+		if checkModifiedSince(req, resp, r.ModTime) {
+			return
+		}
+	}
+
 	chunked := Config.BoolDefault("results.chunked", false)
 
-	// If it's a HEAD request, throw away the bytes.
+	// If it's a HEAD request, throw away the bytes.  (They are still counted
+	// by the server for the Content-Length, if chunked.)
 	out := io.Writer(resp.Out)
-	if req.Method == "HEAD" {
+	if req.Method == "HEAD" && !chunked {
 		out = ioutil.Discard
 	}
 
@@ -135,7 +156,7 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 	// error pages distorted by HTML already written)
 	if chunked && !DevMode {
 		resp.WriteHeader(http.StatusOK, "text/html; charset=utf-8")
-		r.render(req, resp, out)
+		r.renderLayout(req, resp, out)
 		return
 	}
 
@@ -144,7 +165,9 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 	// Otherwise, template render errors may result in unpredictable HTML (and
 	// would carry a 200 status code)
 	var b bytes.Buffer
-	r.render(req, resp, &b)
+	if !r.renderLayout(req, resp, &b) {
+		return
+	}
 	if !chunked {
 		resp.Out.Header().Set("Content-Length", strconv.Itoa(b.Len()))
 	}
@@ -152,17 +175,31 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 	b.WriteTo(out)
 }
 
-func (r *RenderTemplateResult) render(req *Request, resp *Response, wr io.Writer) {
-	err := r.Template.Render(wr, r.RenderArgs)
+// renderLayout renders the template, wrapped in the layout if there is one.
+// If rendering fails, it renders the error instead and returns false.
+func (r *RenderTemplateResult) renderLayout(req *Request, resp *Response, wr io.Writer) bool {
+	if r.Layout == nil {
+		return r.render(r.Template, req, resp, wr)
+	}
+	var content bytes.Buffer
+	if !r.render(r.Template, req, resp, &content) {
+		return false
+	}
+	r.RenderArgs[LayoutContentRenderArg] = template.HTML(content.String())
+	return r.render(r.Layout, req, resp, wr)
+}
+
+func (r *RenderTemplateResult) render(tmpl Template, req *Request, resp *Response, wr io.Writer) bool {
+	err := tmpl.Render(wr, r.RenderArgs)
 	if err == nil {
-		return
+		return true
 	}
 
 	var templateContent []string
 	templateName, line, description := parseTemplateError(err)
 	if templateName == "" {
-		templateName = r.Template.Name()
-		templateContent = r.Template.Content()
+		templateName = tmpl.Name()
+		templateContent = tmpl.Content()
 	} else {
 		if tmpl, err := MainTemplateLoader.Template(templateName); err == nil {
 			templateContent = tmpl.Content()
@@ -178,6 +215,7 @@ func (r *RenderTemplateResult) render(req *Request, resp *Response, wr io.Writer
 	resp.Status = 500
 	ERROR.Printf("Template Execution Error (in %s): %s", templateName, description)
 	ErrorResult{r.RenderArgs, compileError}.Apply(req, resp)
+	return false
 }
 
 type RenderHtmlResult struct {
@@ -189,47 +227,528 @@ func (r RenderHtmlResult) Apply(req *Request, resp *Response) {
 	resp.Out.Write([]byte(r.html))
 }
 
+// RenderJsonResult encodes its value straight to the response, unless an
+// option needs the whole body first (e.g. to hash it for an ETag, or to
+// indent it), in which case it is marshaled before being written.  For large
+// collections, RenderJsonStream writes them as they are produced.
 type RenderJsonResult struct {
 	obj      interface{}
 	callback string
+	options  *JsonOptions // If nil, DefaultJsonOptions() are used.
+	action   string       // The rendering action, for its schema; see RegisterJsonSchema.
 }
 
 func (r RenderJsonResult) Apply(req *Request, resp *Response) {
-	var b []byte
-	var err error
-	if Config.BoolDefault("results.pretty", false) {
-		b, err = json.MarshalIndent(r.obj, "", "  ")
-	} else {
-		b, err = json.Marshal(r.obj)
+	// An explicit 204 No Content has no body.
+	if resp.Status == http.StatusNoContent {
+		resp.Out.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Look for values that can not be marshaled, to give a clearer error than
+	// encoding/json does.  The (more expensive) deep check is only done in dev.
+	if err := checkJsonMarshalable(reflect.ValueOf(r.obj), "", DevMode, 0); err != nil {
+		renderJsonError(req, resp, err)
+		return
+	}
+
+	if traceEnabled() {
+		TRACE.Println("Rendering JSON:", Redact(r.obj))
+	}
+
+	options := r.options
+	if options == nil {
+		defaults := DefaultJsonOptions()
+		options = &defaults
+	}
+
+	// A versioned value has a cheap ETag, which may save marshaling it.
+	etag, versioned := versionETag(r.obj, r.callback, *options)
+	if versioned && checkETag(req, resp, etag) {
+		return
+	}
+
+	// Unless an option needs the whole body, encode the value to the response.
+	nilBody := options.NilBody != "" && isNilJson(r.obj)
+	if r.callback == "" && !nilBody && !options.Pretty && !options.rewrites() &&
+		(versioned || !options.ETag) && !(DevMode && r.action != "") {
+		r.encode(req, resp)
+		return
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if nilBody {
+		b = []byte(options.NilBody)
+	} else if b, err = json.Marshal(r.obj); err == nil {
+		b, err = options.transform(r.obj, b)
+	}
+	if err == nil && options.Pretty {
+		var indented bytes.Buffer
+		if err = json.Indent(&indented, b, "", "  "); err == nil {
+			b = indented.Bytes()
+		}
 	}
 
 	if err != nil {
-		ErrorResult{Error: err}.Apply(req, resp)
+		renderJsonError(req, resp, err)
 		return
 	}
 
-	if r.callback == "" {
-		resp.WriteHeader(http.StatusOK, "application/json; charset=utf-8")
-		resp.Out.Write(b)
+	if DevMode && r.action != "" {
+		checkJsonSchema(r.action, b)
+	}
+
+	if r.callback != "" {
+		b = []byte(r.callback + "(" + string(b) + ");")
+	}
+	if !versioned && options.ETag && checkETag(req, resp, hashETag(b)) {
 		return
 	}
 
-	resp.WriteHeader(http.StatusOK, "application/javascript; charset=utf-8")
-	resp.Out.Write([]byte(r.callback + "("))
+	if r.callback == "" {
+		resp.WriteHeader(http.StatusOK, "application/json; charset=utf-8")
+	} else {
+		resp.WriteHeader(http.StatusOK, "application/javascript; charset=utf-8")
+	}
 	resp.Out.Write(b)
-	resp.Out.Write([]byte(");"))
+}
+
+// encode writes the value to the response with a json.Encoder.  The encoder
+// writes nothing if the value fails to encode, so that the error may still be
+// rendered, unless the response has been started.
+func (r RenderJsonResult) encode(req *Request, resp *Response) {
+	w := &jsonBodyWriter{resp: resp}
+	if err := json.NewEncoder(w).Encode(r.obj); err != nil {
+		if !w.wroteHeader {
+			renderJsonError(req, resp, err)
+			return
+		}
+		ERROR.Println("Failed to render JSON:", err)
+		abortResponse()
+	}
+}
+
+// jsonBodyWriter writes the header of a JSON response just before the body.
+// The encoder ends the value with a newline, which is dropped so that the body
+// is the same as when it is marshaled.
+type jsonBodyWriter struct {
+	resp        *Response
+	wroteHeader bool
+}
+
+func (w *jsonBodyWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.resp.WriteHeader(http.StatusOK, "application/json; charset=utf-8")
+		w.wroteHeader = true
+	}
+	if _, err := w.resp.Out.Write(bytes.TrimSuffix(b, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// isNilJson returns true if obj is a value that encoding/json renders as null.
+func isNilJson(obj interface{}) bool {
+	v := reflect.ValueOf(obj)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// renderJsonError shows a 500 for a value that failed to render as JSON.
+// The details are only shown in dev mode; they are always logged.
+func renderJsonError(req *Request, resp *Response, err error) {
+	renderEncodingError(req, resp, "JSON", err)
+}
+
+// renderEncodingError shows a 500 for a value that failed to render in the
+// named format, as renderJsonError.
+func renderEncodingError(req *Request, resp *Response, format string, err error) {
+	ERROR.Println("Failed to render "+format+":", err)
+	resp.Status = http.StatusInternalServerError
+	if !DevMode {
+		err = &Error{
+			Title:       "Server Error",
+			Description: "The response could not be rendered",
+		}
+	}
+	ErrorResult{Error: err}.Apply(req, resp)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Limits how far checkJsonMarshalable descends, which also protects it from
+// cyclic data structures.
+const maxJsonCheckDepth = 32
+
+// checkJsonMarshalable returns an error naming the first value found within v
+// that encoding/json can not marshal (channels, funcs, and complex numbers).
+// Only the top-level value is checked, unless deep is true.
+func checkJsonMarshalable(v reflect.Value, path string, deep bool, depth int) error {
+	if !v.IsValid() || depth > maxJsonCheckDepth {
+		return nil
+	}
+
+	// Types that marshal themselves are not inspected further.
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		if path == "" {
+			path = "the value"
+		}
+		return &Error{
+			Title:       "JSON Render Error",
+			Description: fmt.Sprintf("Can not render %s as JSON: unsupported type %s", path, v.Type()),
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkJsonMarshalable(v.Elem(), path, deep, depth)
+	}
+
+	if !deep {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if err := checkJsonMarshalable(v.Field(i), path+"."+field.Name, deep, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkJsonMarshalable(v.Index(i), fmt.Sprintf("%s[%d]", path, i), deep, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if err := checkJsonMarshalable(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()), deep, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// A KV is a key and value of a JSON object streamed by RenderJsonObjectStream.
+type KV struct {
+	Key   string
+	Value interface{}
+}
+
+// StreamOptions tune how a streamed response is sent.
+type StreamOptions struct {
+	// BufferSize is the number of bytes held before they are sent to the
+	// client.  Larger buffers mean fewer, larger writes, at the cost of
+	// latency.  Zero sends each member as soon as it is written.
+	BufferSize int
+
+	// Done, if set, is closed when the stream is cut short because the client
+	// went away or a write failed, so that the producer may stop.  Producers
+	// should select on it when sending:
+	//   select {
+	//   case ch <- revel.KV{id, row}:
+	//   case <-done:
+	//     return
+	//   }
+	Done chan struct{}
+}
+
+// errClientGone is returned when the client disconnects during a stream.
+var errClientGone = errors.New("revel: client disconnected")
+
+// RenderJsonObjectStreamResult writes a JSON object whose members are received
+// from a channel, as they arrive.
+//
+// The channel is read only as fast as the client accepts the response, so a
+// producer sending on an unbuffered channel is held back by a slow client,
+// rather than the response being buffered in memory.
+type RenderJsonObjectStreamResult struct {
+	ch      <-chan KV
+	tee     ResponseTee // Optional; receives a copy of the object.
+	options StreamOptions
+}
+
+func (r RenderJsonObjectStreamResult) Apply(req *Request, resp *Response) {
+	applyJsonStream(req, resp, r.tee, r.options, r.stream, func() {
+		for _ = range r.ch {
+		}
+	})
+}
+
+func (r RenderJsonObjectStreamResult) stream(w *jsonStreamWriter, gone <-chan struct{}) error {
+	if err := w.write([]byte("{")); err != nil {
+		return err
+	}
+	for {
+		var (
+			kv KV
+			ok bool
+		)
+		select {
+		case kv, ok = <-r.ch:
+		case <-gone:
+			return errClientGone
+		}
+		if !ok {
+			break
+		}
+
+		value, err := json.Marshal(kv.Value)
+		if err != nil {
+			return fmt.Errorf("failed to render member %q: %s", kv.Key, err)
+		}
+		key, _ := json.Marshal(kv.Key)
+		if err = w.element(append(append(key, ':'), value...)); err != nil {
+			return err
+		}
+	}
+	return w.end([]byte("}"))
+}
+
+// RenderJsonStreamResult writes a JSON array whose elements are received from a
+// channel, as they arrive.  Like RenderJsonObjectStreamResult, it reads the
+// channel only as fast as the client accepts the response.
+type RenderJsonStreamResult struct {
+	ch      <-chan interface{}
+	options StreamOptions
+}
+
+func (r RenderJsonStreamResult) Apply(req *Request, resp *Response) {
+	applyJsonStream(req, resp, nil, r.options, r.stream, func() { discard(r.ch) })
+}
+
+func (r RenderJsonStreamResult) stream(w *jsonStreamWriter, gone <-chan struct{}) error {
+	if err := w.write([]byte("[")); err != nil {
+		return err
+	}
+	for {
+		var (
+			value interface{}
+			ok    bool
+		)
+		select {
+		case value, ok = <-r.ch:
+		case <-gone:
+			return errClientGone
+		}
+		if !ok {
+			break
+		}
+
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to render element %d: %s", w.elements, err)
+		}
+		if err = w.element(b); err != nil {
+			return err
+		}
+	}
+	return w.end([]byte("]"))
+}
+
+// applyJsonStream sends a streamed JSON response, copying it to the tee (if
+// any).  If the stream fails, the connection is closed so that the client
+// can tell the response is incomplete, the producer is told to stop, and the
+// channel is drained in the background so that the producer is not blocked
+// forever.
+func applyJsonStream(req *Request, resp *Response, tee ResponseTee, options StreamOptions,
+	stream func(w *jsonStreamWriter, gone <-chan struct{}) error, drain func()) {
+	var (
+		out      io.Writer = resp.Out
+		asyncTee *asyncTee
+	)
+	if tee != nil {
+		asyncTee = newAsyncTee(tee)
+		out = io.MultiWriter(resp.Out, asyncTee)
+	}
+
+	// Detect the client going away while waiting for the next element.
+	gone := req.Context().Done()
+
+	resp.WriteHeader(http.StatusOK, "application/json; charset=utf-8")
+	w := &jsonStreamWriter{
+		resp:     resp,
+		buffered: bufio.NewWriterSize(out, options.BufferSize),
+		flushAll: options.BufferSize == 0,
+	}
+	err := stream(w, gone)
+	if err != nil {
+		if err == errClientGone {
+			WARN.Println("Client disconnected during JSON stream")
+		} else {
+			ERROR.Printf("JSON stream failed after %d bytes: %s", w.written, err)
+		}
+		if options.Done != nil {
+			close(options.Done)
+		}
+		// The producer may take a while to stop, or (told by Done) stop without
+		// closing the channel, so the request does not wait for the drain.
+		go drain()
+	}
+	if asyncTee != nil {
+		asyncTee.Close(err)
+	}
+	if err != nil && err != errClientGone {
+		abortResponse()
+	}
+}
+
+// jsonStreamWriter writes a streamed JSON object or array, sending it to the
+// client as each element is written, or once its buffer fills.
+type jsonStreamWriter struct {
+	resp     *Response
+	buffered *bufio.Writer
+	flushAll bool // Whether to send each element as it is written.
+	written  int  // The number of bytes written so far.
+	elements int  // The number of elements written so far.
+}
+
+// element writes the next element, preceded by a separator if necessary.
+func (w *jsonStreamWriter) element(b []byte) error {
+	if w.elements > 0 {
+		if err := w.write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	w.elements++
+	return w.write(b)
+}
+
+// end writes the closing delimiter and sends whatever remains in the buffer.
+func (w *jsonStreamWriter) end(b []byte) error {
+	if err := w.write(b); err != nil {
+		return err
+	}
+	return w.buffered.Flush()
+}
+
+func (w *jsonStreamWriter) write(b []byte) error {
+	held := w.buffered.Buffered() + len(b)
+	n, err := w.buffered.Write(b)
+	w.written += n
+	if err == nil && w.flushAll {
+		err = w.buffered.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if w.buffered.Buffered() < held {
+		flushResponse(w.resp.Out)
+	}
+	return nil
+}
+
+// abortResponse closes the connection of a response that can not be completed,
+// so that the client does not mistake the part already sent for all of it.
+// It panics with http.ErrAbortHandler, on which net/http aborts the response
+// whatever wraps the ResponseWriter (e.g. a CompressResponseWriter, which would
+// otherwise finish a valid gzip stream), after the cleanups have run.
+func abortResponse() {
+	panic(http.ErrAbortHandler)
+}
+
+type RenderEventStreamResult struct {
+	snapshot func() interface{} // Optional; sent as the first event.
+	updates  <-chan interface{}
+}
+
+func (r *RenderEventStreamResult) Apply(req *Request, resp *Response) {
+	resp.Out.Header().Set("Cache-Control", "no-cache")
+	resp.WriteHeader(http.StatusOK, "text/event-stream")
+	flushResponse(resp.Out)
+
+	if r.snapshot != nil {
+		if err := writeEvent(resp, r.snapshot()); err != nil {
+			go discard(r.updates)
+			return
+		}
+	}
+	gone := req.Context().Done()
+	for {
+		select {
+		case update, ok := <-r.updates:
+			if !ok {
+				return
+			}
+			if err := writeEvent(resp, update); err != nil {
+				// Let the sender finish, without holding up the request.
+				go discard(r.updates)
+				return
+			}
+		case <-gone:
+			WARN.Println("Client disconnected during event stream")
+			go discard(r.updates)
+			return
+		}
+	}
+}
+
+// writeEvent sends the JSON of obj to the client as a server-sent event.
+func writeEvent(resp *Response, obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		ERROR.Println("Failed to render event as JSON:", err)
+		return err
+	}
+	if _, err = fmt.Fprintf(resp.Out, "data: %s\n\n", b); err != nil {
+		WARN.Println("Failed to send event:", err)
+		return err
+	}
+	flushResponse(resp.Out)
+	return nil
+}
+
+// discard drains the channel, so that its sender is not blocked forever.
+func discard(ch <-chan interface{}) {
+	for _ = range ch {
+	}
+}
+
+// flushResponse sends any buffered data to the client, if the writer supports it.
+func flushResponse(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
 
 type RenderXmlResult struct {
-	obj interface{}
+	obj     interface{}
+	options *XmlOptions // If nil, obj is marshaled as-is.
+	header  string      // Written before the XML, e.g. xml.Header.
 }
 
 func (r RenderXmlResult) Apply(req *Request, resp *Response) {
 	var b []byte
 	var err error
-	if Config.BoolDefault("results.pretty", false) {
+	pretty := Config.BoolDefault("results.pretty", DevMode)
+	switch {
+	case r.options != nil:
+		b, err = r.options.marshal(r.obj, pretty)
+	case pretty:
 		b, err = xml.MarshalIndent(r.obj, "", "  ")
-	} else {
+	default:
 		b, err = xml.Marshal(r.obj)
 	}
 
@@ -239,18 +758,231 @@ func (r RenderXmlResult) Apply(req *Request, resp *Response) {
 	}
 
 	resp.WriteHeader(http.StatusOK, "application/xml; charset=utf-8")
+	if r.header != "" {
+		io.WriteString(resp.Out, r.header)
+		if !strings.HasSuffix(r.header, "\n") {
+			io.WriteString(resp.Out, "\n")
+		}
+	}
 	resp.Out.Write(b)
 }
 
+// RenderYamlResult renders its value as YAML, in block style.  Struct fields
+// are named and omitted by their yaml tags, as with gopkg.in/yaml.v2.
+type RenderYamlResult struct {
+	obj interface{}
+}
+
+func (r RenderYamlResult) Apply(req *Request, resp *Response) {
+	b, err := marshalYaml(r.obj)
+	if err != nil {
+		renderEncodingError(req, resp, "YAML", err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK, "application/x-yaml; charset=utf-8")
+	resp.Out.Write(b)
+}
+
+// marshalYaml is yaml.Marshal, but returns an error for an unsupported type
+// (e.g. a channel), for which yaml.Marshal panics.
+func marshalYaml(v interface{}) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("yaml: %v", r)
+		}
+	}()
+	return yaml.Marshal(v)
+}
+
+// RenderMsgPackResult streams its value to the response as MessagePack,
+// naming struct fields by their msgpack tags, or else their json tags.  Since
+// the status has been sent by the time the value is encoded, an error is
+// logged and the connection closed, as for a failed JSON stream, so that the
+// client does not mistake a truncated body for all of it.
+type RenderMsgPackResult struct {
+	obj interface{}
+}
+
+func (r RenderMsgPackResult) Apply(req *Request, resp *Response) {
+	// An explicit 204 No Content has no body.
+	if resp.Status == http.StatusNoContent {
+		resp.Out.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if traceEnabled() {
+		TRACE.Println("Rendering MessagePack:", Redact(r.obj))
+	}
+
+	resp.WriteHeader(http.StatusOK, "application/msgpack")
+	encoder := msgpack.NewEncoder(resp.Out).UseJSONTag(true).SortMapKeys(true).UseCompactEncoding(true)
+	if err := encoder.Encode(r.obj); err != nil {
+		ERROR.Println("Failed to render MessagePack:", err)
+		abortResponse()
+	}
+}
+
+// statusResult applies a result with a status; see Controller.RenderWithStatus.
+type statusResult struct {
+	status int
+	result Result
+}
+
+func (r statusResult) Apply(req *Request, resp *Response) {
+	resp.Status = r.status
+	r.result.Apply(req, resp)
+}
+
+// cacheControlResult sets the caching headers of the response, for
+// RenderCached and NoCache.
+type cacheControlResult struct {
+	cacheControl string
+	expires      time.Time
+	result       Result
+}
+
+func (r cacheControlResult) Apply(req *Request, resp *Response) {
+	resp.Out.Header().Set("Cache-Control", r.cacheControl)
+	resp.Out.Header().Set("Expires", r.expires.UTC().Format(http.TimeFormat))
+	r.result.Apply(req, resp)
+}
+
 type RenderTextResult struct {
-	text string
+	text        string
+	contentType string // Defaults to "text/plain; charset=utf-8"
 }
 
 func (r RenderTextResult) Apply(req *Request, resp *Response) {
-	resp.WriteHeader(http.StatusOK, "text/plain; charset=utf-8")
+	contentType := r.contentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	resp.WriteHeader(http.StatusOK, contentType)
 	resp.Out.Write([]byte(r.text))
 }
 
+// A ProtoMarshaler is a protobuf message that marshals itself, as the types
+// generated by gogo/protobuf do.  Messages of other generators may be adapted
+// by wrapping them, e.g. in a type whose Marshal calls proto.Marshal.
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// RenderProtobufResult writes a protobuf message in its binary encoding.
+type RenderProtobufResult struct {
+	msg ProtoMarshaler
+}
+
+func (r RenderProtobufResult) Apply(req *Request, resp *Response) {
+	// A nil message is sent as an empty one, whose encoding is empty.
+	var data []byte
+	if !isNilMessage(r.msg) {
+		var err error
+		if data, err = r.msg.Marshal(); err != nil {
+			ErrorResult{Error: err}.Apply(req, resp)
+			return
+		}
+	}
+
+	resp.WriteHeader(http.StatusOK, "application/x-protobuf")
+	resp.Out.Write(data)
+}
+
+// isNilMessage returns true if msg is nil, or a nil pointer.
+func isNilMessage(msg ProtoMarshaler) bool {
+	v := reflect.ValueOf(msg)
+	return msg == nil || v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// GrpcOK is the gRPC status code for success.  The other codes are defined by
+// the google.golang.org/grpc/codes package, and may be passed as ints.
+const GrpcOK = 0
+
+// grpcInternal is the gRPC status code for a server error (codes.Internal).
+const grpcInternal = 13
+
+// RenderGrpcWebResult writes a protobuf message framed in the gRPC-Web wire
+// format: a length-prefixed data frame holding the message, followed by a
+// trailer frame holding the grpc-status and grpc-message.
+type RenderGrpcWebResult struct {
+	msg     ProtoMarshaler
+	status  int
+	message string
+}
+
+const (
+	grpcWebDataFrame    byte = 0x00
+	grpcWebTrailerFrame byte = 0x80
+)
+
+func (r RenderGrpcWebResult) Apply(req *Request, resp *Response) {
+	var b bytes.Buffer
+
+	// A message is only sent with an OK status.  Other statuses are
+	// "trailers-only" responses.  A nil message is sent as an empty one, whose
+	// encoding is empty, as by RenderProtobuf.  A message that fails to marshal
+	// is reported to the client as an internal error, as a gRPC server would.
+	if r.status == GrpcOK {
+		var (
+			data []byte
+			err  error
+		)
+		if !isNilMessage(r.msg) {
+			data, err = r.msg.Marshal()
+		}
+		if err != nil {
+			ERROR.Println("Failed to render gRPC-Web:", err)
+			r.status, r.message = grpcInternal, "The response could not be rendered"
+			if DevMode {
+				r.message = err.Error()
+			}
+		} else {
+			writeGrpcWebFrame(&b, grpcWebDataFrame, data)
+		}
+	}
+
+	trailers := fmt.Sprintf("grpc-status:%d\r\n", r.status)
+	if r.status != GrpcOK {
+		resp.Out.Header().Set("grpc-status", strconv.Itoa(r.status))
+	}
+	if r.message != "" {
+		message := grpcWebEncodeMessage(r.message)
+		trailers += "grpc-message:" + message + "\r\n"
+		resp.Out.Header().Set("grpc-message", message)
+	}
+	writeGrpcWebFrame(&b, grpcWebTrailerFrame, []byte(trailers))
+
+	// gRPC reports errors in the trailers; the HTTP status is always 200.
+	resp.Status = http.StatusOK
+	resp.WriteHeader(http.StatusOK, "application/grpc-web+proto")
+	b.WriteTo(resp.Out)
+}
+
+// writeGrpcWebFrame writes a frame: a flag byte, the 4-byte big-endian length
+// of the payload, and the payload itself.
+func writeGrpcWebFrame(b *bytes.Buffer, flag byte, payload []byte) {
+	var header [5]byte
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	b.Write(header[:])
+	b.Write(payload)
+}
+
+// grpcWebEncodeMessage percent-encodes the grpc-message value, as required by
+// the gRPC spec for bytes outside of printable ASCII (and '%' itself).
+func grpcWebEncodeMessage(msg string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c < 0x20 || c > 0x7E || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
 type ContentDisposition string
 
 var (
@@ -258,6 +990,74 @@ var (
 	Inline     ContentDisposition = "inline"
 )
 
+// header returns the Content-Disposition header suggesting the filename, if it
+// is not empty.  Names that are not plain tokens are quoted, with non-ASCII
+// characters replaced for legacy clients, and also given in full, percent
+// encoded as UTF-8, in the filename* parameter of RFC 5987:
+//   attachment; filename="R_sum_.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9.pdf
+func (d ContentDisposition) header(filename string) string {
+	if filename == "" {
+		return string(d)
+	}
+	if isHttpToken(filename) {
+		return string(d) + "; filename=" + filename
+	}
+
+	var fallback, encoded bytes.Buffer
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			fallback.WriteRune('\\')
+			fallback.WriteRune(r)
+		case r < ' ' || r > '~':
+			fallback.WriteRune('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, d, fallback.String(), encoded.String())
+}
+
+// isHttpToken returns true if s is a token, which needs no quotes as the value
+// of a header parameter.
+func isHttpToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAttrChar(s[i]) && !strings.ContainsRune("'*%", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAttrChar returns true for the characters that RFC 5987 allows unencoded.
+func isAttrChar(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// The number of bytes examined to detect the content type of a binary result.
+const sniffLen = 512
+
+// binaryContentType returns the content type for the named file, according to
+// mime.TypeByExtension or else the mime-types.conf table, or "" if neither
+// knows the extension.
+func binaryContentType(name string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType
+	}
+	if contentType := ContentTypeByFilename(name); contentType != DefaultFileContentType {
+		return contentType
+	}
+	return ""
+}
+
 type BinaryResult struct {
 	Reader   io.Reader
 	Name     string
@@ -267,17 +1067,45 @@ type BinaryResult struct {
 }
 
 func (r *BinaryResult) Apply(req *Request, resp *Response) {
-	disposition := string(r.Delivery)
-	if r.Name != "" {
-		disposition += fmt.Sprintf("; filename=%s", r.Name)
+	resp.Out.Header().Set("Content-Disposition", r.Delivery.header(r.Name))
+
+	// The size and modification time of a file are known without reading it,
+	// so it need not be hashed for its ETag (e.g. for each Range request of a
+	// PDF viewer).
+	if file, ok := r.Reader.(interface {
+		Stat() (os.FileInfo, error)
+	}); ok && (r.ModTime.IsZero() || r.Length < 0) {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			if r.ModTime.IsZero() {
+				r.ModTime = info.ModTime()
+			}
+			if r.Length < 0 {
+				r.Length = info.Size()
+			}
+		}
+	}
+
+	// Let clients that already have the content revalidate it cheaply.
+	if !r.ModTime.IsZero() {
+		resp.Out.Header().Set("Last-Modified", r.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if etag, ok := binaryETag(r); ok && resp.Out.Header().Get("ETag") == "" {
+		if checkETag(req, resp, etag) {
+			r.close()
+			return
+		}
+	}
+	if checkModifiedSince(req, resp, r.ModTime) {
+		r.close()
+		return
 	}
-	resp.Out.Header().Set("Content-Disposition", disposition)
 
 	// If we have a ReadSeeker, delegate to http.ServeContent
 	if rs, ok := r.Reader.(io.ReadSeeker); ok {
 		// http.ServeContent doesn't know about response.ContentType, so we set the respective header.
-		if resp.ContentType != "" {
-			resp.Out.Header().Set("Content-Type", resp.ContentType)
+		// If neither it nor the extension gives a type, ServeContent sniffs the content.
+		if contentType := FirstNonEmpty(resp.ContentType, binaryContentType(r.Name)); contentType != "" {
+			resp.Out.Header().Set("Content-Type", contentType)
 		}
 		http.ServeContent(resp.Out, req.Request, r.Name, r.ModTime, rs)
 	} else {
@@ -285,23 +1113,112 @@ func (r *BinaryResult) Apply(req *Request, resp *Response) {
 		if r.Length != -1 {
 			resp.Out.Header().Set("Content-Length", strconv.FormatInt(r.Length, 10))
 		}
-		resp.WriteHeader(http.StatusOK, ContentTypeByFilename(r.Name))
-		io.Copy(resp.Out, r.Reader)
+		reader := r.Reader
+		contentType := binaryContentType(r.Name)
+		if contentType == "" && resp.ContentType == "" {
+			// Sniff the content, as http.ServeContent would.
+			buffered := bufio.NewReaderSize(r.Reader, sniffLen)
+			head, _ := buffered.Peek(sniffLen)
+			contentType, reader = http.DetectContentType(head), buffered
+		}
+		resp.WriteHeader(http.StatusOK, contentType)
+		if r.Length == -1 {
+			io.Copy(resp.Out, reader)
+		} else if req.Method == "HEAD" {
+			// The Content-Length is known, so there is no need to read it.
+		} else {
+			r.copyLength(resp.Out, reader)
+		}
 	}
 
-	// Close the Reader if we can
+	r.close()
+}
+
+// ReaderResult streams a reader to the client as the given content type.
+type ReaderResult struct {
+	Reader      io.Reader
+	ContentType string
+}
+
+func (r *ReaderResult) Apply(req *Request, resp *Response) {
+	if v, ok := r.Reader.(io.Closer); ok {
+		defer v.Close()
+	}
+	if r.ContentType != "" {
+		resp.ContentType = r.ContentType
+	}
+	resp.WriteHeader(http.StatusOK, DefaultFileContentType)
+	if _, err := io.Copy(resp.Out, r.Reader); err != nil {
+		WARN.Println("RenderReader: failed to send the response:", err)
+	}
+}
+
+// copyLength writes exactly Length bytes of the reader, as promised by the
+// Content-Length header, and warns if the reader has more or fewer.
+func (r *BinaryResult) copyLength(w io.Writer, reader io.Reader) {
+	n, err := io.CopyN(w, reader, r.Length)
+	switch {
+	case n < r.Length:
+		WARN.Printf("RenderBinary %s: expected %d bytes, but the reader produced %d (%v)",
+			r.Name, r.Length, n, err)
+	case err == nil:
+		if extra, _ := io.Copy(ioutil.Discard, reader); extra > 0 {
+			WARN.Printf("RenderBinary %s: expected %d bytes, but the reader produced %d more",
+				r.Name, r.Length, extra)
+		}
+	}
+}
+
+// close closes the Reader if we can.
+func (r *BinaryResult) close() {
 	if v, ok := r.Reader.(io.Closer); ok {
 		v.Close()
 	}
 }
 
+// RenderStatusResult writes a status with no body.  Content-Length: 0 is set,
+// except for the statuses that may not have a body at all (1xx, 204, and 304),
+// for which it is not allowed.
+type RenderStatusResult struct {
+	Status int
+}
+
+func (r RenderStatusResult) Apply(req *Request, resp *Response) {
+	resp.Status = r.Status
+	if bodyAllowedForStatus(r.Status) {
+		resp.Out.Header().Set("Content-Length", "0")
+	}
+	resp.Out.WriteHeader(r.Status)
+}
+
+// AcceptedResult points the client to the status resource of work that was
+// accepted for asynchronous processing.
+type AcceptedResult struct {
+	Location string      // URL of the status resource.
+	Body     interface{} // Optional; rendered as JSON if non-nil.
+}
+
+func (r *AcceptedResult) Apply(req *Request, resp *Response) {
+	resp.Out.Header().Set("Location", r.Location)
+	resp.Out.Header().Set("Content-Location", r.Location)
+	if resp.Status == 0 {
+		resp.Status = http.StatusAccepted
+	}
+	if r.Body == nil {
+		// Without a body, there is no Content-Type to send.
+		resp.Out.WriteHeader(resp.Status)
+		return
+	}
+	RenderJsonResult{obj: r.Body}.Apply(req, resp)
+}
+
 type RedirectToUrlResult struct {
 	url string
 }
 
 func (r *RedirectToUrlResult) Apply(req *Request, resp *Response) {
 	resp.Out.Header().Set("Location", r.url)
-	resp.WriteHeader(http.StatusFound, "")
+	resp.Out.WriteHeader(redirectStatus(resp))
 }
 
 type RedirectToActionResult struct {
@@ -316,7 +1233,16 @@ func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
 		return
 	}
 	resp.Out.Header().Set("Location", url)
-	resp.WriteHeader(http.StatusFound, "")
+	resp.Out.WriteHeader(redirectStatus(resp))
+}
+
+// redirectStatus returns the status for a redirect: 302 Found, unless the
+// application set another redirection status (e.g. 301 or 307).
+func redirectStatus(resp *Response) int {
+	if resp.Status < 300 || resp.Status >= 400 {
+		resp.Status = http.StatusFound
+	}
+	return resp.Status
 }
 
 func getRedirectUrl(item interface{}) (string, error) {
@@ -333,22 +1259,37 @@ func getRedirectUrl(item interface{}) (string, error) {
 		recvType := typ.In(0)
 		method := FindMethod(recvType, val)
 		if method == nil {
-			return "", errors.New("couldn't find method")
+			return "", fmt.Errorf("revel: redirect to %s: not a method of %s", typ, recvType)
 		}
 
 		// Construct the action string (e.g. "Controller.Method")
 		if recvType.Kind() == reflect.Ptr {
 			recvType = recvType.Elem()
 		}
-		action := recvType.Name() + "." + method.Name
-		actionDef := MainRouter.Reverse(action, make(map[string]string))
-		if actionDef == nil {
-			return "", errors.New("no route for action " + action)
-		}
-
-		return actionDef.String(), nil
+		return actionUrl(recvType.Name(), method.Name, nil)
 	}
 
 	// Out of guesses
 	return "", errors.New("didn't recognize type: " + typ.String())
 }
+
+// actionUrl returns the URL of the registered action, with the args in its
+// route or query string.
+func actionUrl(controllerName, methodName string, args map[string]interface{}) (string, error) {
+	var c Controller
+	if err := c.SetAction(controllerName, methodName); err != nil {
+		return "", fmt.Errorf("revel: redirect to %s.%s: not a registered action (%s)",
+			controllerName, methodName, err)
+	}
+
+	argsByName := make(map[string]string)
+	for name, value := range args {
+		Unbind(argsByName, name, value)
+	}
+	action := c.Name + "." + c.MethodType.Name
+	actionDef := MainRouter.Reverse(action, argsByName)
+	if actionDef == nil {
+		return "", errors.New("revel: no route for action " + action)
+	}
+	return actionDef.String(), nil
+}
@@ -1,9 +1,22 @@
 package revel
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test that the render response is as expected.
@@ -19,6 +32,22 @@ func TestBenchmarkRender(t *testing.T) {
 	}
 }
 
+func TestRenderNamed(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.SetAction("Hotels", "Show")
+	hotel := &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+	// Called from a closure, where Render could not find the names.
+	render := func() Result {
+		return c.RenderNamed(map[string]interface{}{"title": "View Hotel", "hotel": hotel})
+	}
+	render().Apply(c.Request, c.Response)
+	if !strings.Contains(resp.Body.String(), "300 Main St.") {
+		t.Errorf("Failed to find hotel address in action response:\n%s", resp.Body)
+	}
+}
+
 func BenchmarkRenderChunked(b *testing.B) {
 	startFakeBookingApp()
 	resp := httptest.NewRecorder()
@@ -48,3 +77,1681 @@ func BenchmarkRenderNotChunked(b *testing.B) {
 		hotels.Show(3).Apply(c.Request, c.Response)
 	}
 }
+
+type grpcWebTestMessage string
+
+func (m grpcWebTestMessage) Marshal() ([]byte, error) { return []byte(m), nil }
+
+func TestRenderGrpcWeb(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderGrpcWeb(grpcWebTestMessage("hi"), GrpcOK, "").Apply(c.Request, c.Response)
+
+	expected := "\x00\x00\x00\x00\x02hi" + "\x80\x00\x00\x00\x0fgrpc-status:0\r\n"
+	if resp.Body.String() != expected {
+		t.Errorf("Unexpected gRPC-Web body: %q", resp.Body.String())
+	}
+	if resp.Header().Get("Content-Type") != "application/grpc-web+proto" {
+		t.Errorf("Unexpected content type: %s", resp.Header().Get("Content-Type"))
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderGrpcWeb(grpcWebTestMessage("hi"), 5, "no such hotel").Apply(c.Request, c.Response)
+	expected = "\x80\x00\x00\x00\x2bgrpc-status:5\r\ngrpc-message:no such hotel\r\n"
+	if resp.Code != 200 || resp.Body.String() != expected {
+		t.Errorf("Unexpected trailers-only response: %d %q", resp.Code, resp.Body.String())
+	}
+
+	// A nil message is sent as an empty one.
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderGrpcWeb((*protobufErrorMessage)(nil), GrpcOK, "").Apply(c.Request, c.Response)
+	expected = "\x00\x00\x00\x00\x00" + "\x80\x00\x00\x00\x0fgrpc-status:0\r\n"
+	if resp.Code != 200 || resp.Body.String() != expected {
+		t.Errorf("Unexpected response for a nil message: %d %q", resp.Code, resp.Body.String())
+	}
+
+	// A message that fails to marshal is an internal error.
+	startFakeBookingApp()
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderGrpcWeb(&protobufErrorMessage{}, GrpcOK, "").Apply(c.Request, c.Response)
+	if resp.Code != 200 || resp.Header().Get("grpc-status") != "13" ||
+		!strings.HasPrefix(resp.Body.String(), "\x80") || strings.Contains(resp.Body.String(), "cannot marshal") {
+		t.Errorf("Unexpected response for a marshal error: %d %v %q", resp.Code, resp.Header(), resp.Body.String())
+	}
+}
+
+type protobufErrorMessage struct{}
+
+func (m *protobufErrorMessage) Marshal() ([]byte, error) { return nil, fmt.Errorf("cannot marshal") }
+
+func TestRenderProtobuf(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		msg          ProtoMarshaler
+		code         int
+		expectedBody string
+	}{
+		{grpcWebTestMessage("\x08\x96\x01"), http.StatusOK, "\x08\x96\x01"},
+		{nil, http.StatusOK, ""},
+		{(*protobufErrorMessage)(nil), http.StatusOK, ""},
+		{&protobufErrorMessage{}, http.StatusInternalServerError, ""},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.RenderProtobuf(test.msg).Apply(c.Request, c.Response)
+		if resp.Code != test.code {
+			t.Errorf("%#v: expected %d, got %d", test.msg, test.code, resp.Code)
+		}
+		if test.code != http.StatusOK {
+			continue
+		}
+		if resp.Header().Get("Content-Type") != "application/x-protobuf" || resp.Body.String() != test.expectedBody {
+			t.Errorf("%#v: unexpected response %q %q", test.msg, resp.Header().Get("Content-Type"), resp.Body)
+		}
+	}
+}
+
+func TestRenderStatus(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		render        func(c *Controller) Result
+		code          int
+		contentLength string
+	}{
+		{func(c *Controller) Result { return c.NoContent() }, http.StatusNoContent, ""},
+		{func(c *Controller) Result { return c.RenderStatus(http.StatusAccepted) }, http.StatusAccepted, "0"},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		test.render(c).Apply(c.Request, c.Response)
+		if resp.Code != test.code || resp.Body.Len() != 0 {
+			t.Errorf("Expected an empty %d, got %d %q", test.code, resp.Code, resp.Body)
+		}
+		if resp.Header().Get("Content-Length") != test.contentLength || resp.Header().Get("Content-Type") != "" {
+			t.Errorf("%d: unexpected headers %v", test.code, resp.Header())
+		}
+	}
+}
+
+func TestRenderJsonUnsupportedType(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { DevMode = false }()
+
+	value := struct {
+		Name     string
+		Callback func()
+	}{"x", func() {}}
+
+	for _, devMode := range []bool{true, false} {
+		DevMode = devMode
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+		c.Request.Format = "json"
+		c.RenderJson(value).Apply(c.Request, c.Response)
+		if resp.Code != 500 {
+			t.Errorf("Expected a 500 for an unsupported type, got %d", resp.Code)
+		}
+		if mentions := strings.Contains(resp.Body.String(), ".Callback"); mentions != devMode {
+			t.Errorf("Expected field to be named only in dev mode (dev=%v):\n%s", devMode, resp.Body)
+		}
+	}
+}
+
+func TestRenderTextAs(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(plaintextRequest), NewResponse(resp))
+	c.RenderTextAs("text/csv", "%s,%d", "a", 1).Apply(c.Request, c.Response)
+	if resp.Header().Get("Content-Type") != "text/csv" || resp.Body.String() != "a,1" {
+		t.Errorf("Unexpected response: %s %q", resp.Header().Get("Content-Type"), resp.Body.String())
+	}
+}
+
+func TestRenderJsonKeyCase(t *testing.T) {
+	startFakeBookingApp()
+	type audit struct {
+		CreatedAt string
+	}
+	type address struct {
+		StreetName string
+		ZipCode    string `json:"zip_code"`
+	}
+	obj := struct {
+		audit
+		UserID    int
+		Address   *address
+		Addresses map[string]address
+		Tags      []interface{}
+	}{
+		audit{"today"}, 1, &address{"Main St.", "10001"},
+		map[string]address{"en-US": {"High St.", "SW1"}},
+		[]interface{}{map[string]bool{"is_admin": true}},
+	}
+
+	render := func(result Result) string {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+		result.Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+
+	c := NewController(NewRequest(jsonRequest), NewResponse(httptest.NewRecorder()))
+	Config.SetOption("results.json.keycase", "snake")
+	defer Config.SetOption("results.json.keycase", "")
+	expected := `{"created_at":"today","user_id":1,` +
+		`"address":{"street_name":"Main St.","zip_code":"10001"},` +
+		`"addresses":{"en-US":{"street_name":"High St.","zip_code":"SW1"}},` +
+		`"tags":[{"is_admin":true}]}`
+	if body := render(c.RenderJson(obj)); body != expected {
+		t.Errorf("Expected app-wide snake case keys:\n%s\n%s", expected, body)
+	}
+
+	expected = `{"createdAt":"today","userId":1,` +
+		`"address":{"streetName":"Main St.","zipCode":"10001"},` +
+		`"addresses":{"en-US":{"streetName":"High St.","zipCode":"SW1"}},` +
+		`"tags":[{"is_admin":true}]}`
+	if body := render(c.RenderJsonWith(obj, JsonOptions{KeyCase: CamelCase})); body != expected {
+		t.Errorf("Expected per-call camel case keys, leaving map keys as-is:\n%s\n%s", expected, body)
+	}
+}
+
+func TestKeyCaseConversion(t *testing.T) {
+	for _, test := range []struct {
+		key, camel, snake string
+	}{
+		{"user_name", "userName", "user_name"},
+		{"UserID", "userId", "user_id"},
+		{"HTTPServer", "httpServer", "http_server"},
+		{"user_été", "userÉté", "user_été"},
+		{"UserÉté", "userÉté", "user_été"},
+	} {
+		if actual := toCamelCase(test.key); actual != test.camel {
+			t.Errorf("%q: expected camel case %q, got %q", test.key, test.camel, actual)
+		}
+		if actual := toSnakeCase(test.key); actual != test.snake {
+			t.Errorf("%q: expected snake case %q, got %q", test.key, test.snake, actual)
+		}
+	}
+}
+
+func TestRenderAccepted(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderAccepted("/jobs/7", map[string]int{"id": 7}).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusAccepted {
+		t.Errorf("Expected 202, got %d", resp.Code)
+	}
+	if resp.Header().Get("Location") != "/jobs/7" || resp.Header().Get("Content-Location") != "/jobs/7" {
+		t.Errorf("Expected the status location in the headers, got %v", resp.Header())
+	}
+	if resp.Body.String() != `{"id":7}` {
+		t.Errorf("Unexpected body: %s", resp.Body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderAccepted("/jobs/7").Apply(c.Request, c.Response)
+	if resp.Code != http.StatusAccepted || resp.Header().Get("Location") != "/jobs/7" {
+		t.Errorf("Expected 202 with the status location, got %d %v", resp.Code, resp.Header())
+	}
+	if _, ok := resp.Header()["Content-Type"]; ok || resp.Body.Len() != 0 {
+		t.Errorf("Expected no Content-Type or body, got %v %q", resp.Header(), resp.Body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.Request.Format = "json"
+	c.RenderAccepted("").Apply(c.Request, c.Response)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a missing location to be an error, got %d", resp.Code)
+	}
+}
+
+func TestRenderJsonMaxDepth(t *testing.T) {
+	startFakeBookingApp()
+	DevMode = true
+	defer func() { DevMode = false }()
+	obj := map[string]interface{}{
+		"id":   1,
+		"tags": []string{"a"},
+		"owner": map[string]interface{}{
+			"name":    "x",
+			"friends": []interface{}{map[string]int{"id": 2}},
+		},
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonWith(obj, JsonOptions{MaxDepth: 2, TruncateDepth: true}).Apply(c.Request, c.Response)
+	if expected := `{"id":1,"owner":{"name":"x"},"tags":["a"]}`; resp.Body.String() != expected {
+		t.Errorf("Expected deep values to be omitted:\n%s\n%s", expected, resp.Body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.Request.Format = "json"
+	c.RenderJsonWith(obj, JsonOptions{MaxDepth: 2}).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected deep values to be an error, got %d", resp.Code)
+	}
+	if !strings.Contains(resp.Body.String(), "value.owner.friends") {
+		t.Errorf("Expected the error to name the value:\n%s", resp.Body)
+	}
+}
+
+func TestRenderXmlWith(t *testing.T) {
+	startFakeBookingApp()
+	type point struct {
+		XMLName xml.Name `xml:"wpt"`
+		Lat     float64  `xml:"lat,attr"`
+	}
+	render := func(result Result) string {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		result.Apply(c.Request, c.Response)
+		return strings.TrimPrefix(resp.Body.String(), xml.Header)
+	}
+
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	body := render(c.RenderXmlWith(point{Lat: 1.5}, XmlOptions{
+		Namespaces: map[string]string{"": "http://www.topografix.com/GPX/1/1", "xsi": "urn:xsi"},
+	}))
+	if expected := `<wpt xmlns="http://www.topografix.com/GPX/1/1" xmlns:xsi="urn:xsi" lat="1.5"></wpt>`; body != expected {
+		t.Errorf("Expected namespaces on the root:\n%s\n%s", expected, body)
+	}
+
+	type nsPoint struct {
+		XMLName xml.Name `xml:"http://a wpt"`
+		Lat     float64  `xml:"lat,attr"`
+	}
+	body = render(c.RenderXmlWith(nsPoint{Lat: 1.5}, XmlOptions{
+		Namespaces: map[string]string{"": "http://b"},
+	}))
+	if expected := `<wpt xmlns="http://b" lat="1.5"></wpt>`; body != expected {
+		t.Errorf("Expected the declared default namespace to replace the tagged one:\n%s\n%s", expected, body)
+	}
+
+	body = render(c.RenderXmlWith(point{Lat: 1.5}, XmlOptions{RootName: "gpx:wpt"}))
+	if expected := `<gpx:wpt lat="1.5"></gpx:wpt>`; body != expected {
+		t.Errorf("Expected the root to be renamed:\n%s\n%s", expected, body)
+	}
+}
+
+func TestRenderXmlHeader(t *testing.T) {
+	startFakeBookingApp()
+	defer Config.SetOption("results.pretty", "false")
+	type point struct {
+		XMLName xml.Name `xml:"wpt"`
+		Lat     float64  `xml:"lat"`
+	}
+	tests := []struct {
+		render   func(c *Controller) Result
+		pretty   string
+		expected string
+	}{
+		{func(c *Controller) Result { return c.RenderXml(point{Lat: 1.5}) }, "false",
+			xml.Header + "<wpt><lat>1.5</lat></wpt>"},
+		{func(c *Controller) Result { return c.RenderXml(point{Lat: 1.5}) }, "true",
+			xml.Header + "<wpt>\n  <lat>1.5</lat>\n</wpt>"},
+		{func(c *Controller) Result { return c.RenderXmlWithHeader(point{Lat: 1.5}, `<?xml version="1.0"?>`) }, "false",
+			"<?xml version=\"1.0\"?>\n<wpt><lat>1.5</lat></wpt>"},
+		{func(c *Controller) Result { return c.RenderXmlWithHeader(point{Lat: 1.5}, "") }, "false",
+			"<wpt><lat>1.5</lat></wpt>"},
+	}
+	for _, test := range tests {
+		Config.SetOption("results.pretty", test.pretty)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		test.render(c).Apply(c.Request, c.Response)
+		if resp.Body.String() != test.expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", test.expected, resp.Body)
+		}
+		if ct := resp.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+			t.Errorf("Expected application/xml; charset=utf-8, got %s", ct)
+		}
+	}
+}
+
+func TestRenderJsonFloatPrecision(t *testing.T) {
+	startFakeBookingApp()
+	obj := map[string]interface{}{
+		"ratio":  0.1 + 0.2,
+		"mean":   1234.5678,
+		"count":  123456789,
+		"values": []float64{2.0 / 3, 1e-7},
+	}
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonWith(obj, JsonOptions{FloatPrecision: 3}).Apply(c.Request, c.Response)
+	if expected := `{"count":123456789,"mean":1230,"ratio":0.3,"values":[0.667,1e-7]}`; resp.Body.String() != expected {
+		t.Errorf("Expected rounded floats:\n%s\n%s", expected, resp.Body)
+	}
+}
+
+type nilPointerTestUser struct {
+	Name    string
+	Manager *nilPointerTestUser
+	Email   *string `json:"email,omitempty"`
+	Tags    []string
+}
+
+func TestRenderJsonOmitNilPointers(t *testing.T) {
+	startFakeBookingApp()
+	obj := []interface{}{
+		nilPointerTestUser{Name: "Ann", Manager: &nilPointerTestUser{Name: "Bob"}},
+		map[string]*string{"nickname": nil},
+	}
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonWith(obj, JsonOptions{OmitNilPointers: true}).Apply(c.Request, c.Response)
+	expected := `[{"Name":"Ann","Manager":{"Name":"Bob","Tags":null},"Tags":null},{"nickname":null}]`
+	if resp.Body.String() != expected {
+		t.Errorf("Expected nil pointer fields to be omitted:\n%s\n%s", expected, resp.Body)
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		render   func(c *Controller) Result
+		expected int
+	}{
+		{func(c *Controller) Result { return c.RenderJson("ok") }, http.StatusCreated},
+		{func(c *Controller) Result { return c.RenderXml("ok") }, http.StatusCreated},
+		{func(c *Controller) Result { return c.RenderText("ok") }, http.StatusCreated},
+		{func(c *Controller) Result { return c.RenderHtml("ok") }, http.StatusCreated},
+		{func(c *Controller) Result { return c.Redirect("/hotels") }, http.StatusFound},
+		{func(c *Controller) Result { return c.RenderGrpcWeb(nil, GrpcOK, "") }, http.StatusOK},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.SetStatus(http.StatusCreated)
+		test.render(c).Apply(c.Request, c.Response)
+		if resp.Code != test.expected {
+			t.Errorf("Expected %d, got %d", test.expected, resp.Code)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.SetStatus(http.StatusMovedPermanently)
+	c.Redirect("/hotels").Apply(c.Request, c.Response)
+	if resp.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected a redirect to keep a 3xx status, got %d", resp.Code)
+	}
+}
+
+type UnregisteredApp struct{ *Controller }
+
+func (c UnregisteredApp) Index() Result { return nil }
+
+func TestRedirectToAction(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		redirect func(c *Controller) Result
+		status   int
+		location string
+	}{
+		{func(c *Controller) Result {
+			return c.RedirectToAction("Hotels", "Show", map[string]interface{}{"id": 3})
+		}, http.StatusFound, "/hotels/3"},
+		{func(c *Controller) Result {
+			return c.RedirectToAction("hotels", "show", map[string]interface{}{"id": 3, "ref": "home"})
+		}, http.StatusFound, "/hotels/3?ref=home"},
+		{func(c *Controller) Result { return c.Redirect(Hotels.Index) }, http.StatusFound, "/hotels"},
+		{func(c *Controller) Result {
+			return c.RedirectToAction("Hotels", "Shwo", nil)
+		}, http.StatusInternalServerError, ""},
+		{func(c *Controller) Result { return c.Redirect(UnregisteredApp.Index) }, http.StatusInternalServerError, ""},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		test.redirect(c).Apply(c.Request, c.Response)
+		if resp.Code != test.status || resp.Header().Get("Location") != test.location {
+			t.Errorf("Expected %d to %q, got %d to %q", test.status, test.location,
+				resp.Code, resp.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRedirectStatus(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		code, expected int
+	}{
+		{http.StatusMovedPermanently, http.StatusMovedPermanently},
+		{http.StatusPermanentRedirect, http.StatusPermanentRedirect},
+		{http.StatusNotModified, http.StatusFound},
+		{http.StatusOK, http.StatusFound},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.RedirectStatus(test.code, "/hotels/%d", 3).Apply(c.Request, c.Response)
+		if resp.Code != test.expected || resp.Header().Get("Location") != "/hotels/3" {
+			t.Errorf("%d: expected a %d redirect, got %d %v", test.code, test.expected, resp.Code, resp.Header())
+		}
+	}
+}
+
+func TestRenderJsonObjectStream(t *testing.T) {
+	ch := make(chan KV)
+	go func() {
+		defer close(ch)
+		ch <- KV{"a", 1}
+		ch <- KV{`quote"d`, []string{"x"}}
+		ch <- KV{"<b>", nil}
+	}()
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonObjectStream(ch).Apply(c.Request, c.Response)
+	expected := `{"a":1,"quote\"d":["x"],"\u003cb\u003e":null}`
+	if resp.Body.String() != expected {
+		t.Errorf("Unexpected streamed object:\n%s\n%s", expected, resp.Body)
+	}
+	if !resp.Flushed {
+		t.Errorf("Expected the members to be flushed as they arrive")
+	}
+}
+
+func TestRenderJsonObjectStreamWith(t *testing.T) {
+	ch := make(chan KV)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 3; i++ {
+			ch <- KV{fmt.Sprint(i), i}
+		}
+	}()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonObjectStreamWith(ch, StreamOptions{BufferSize: 1 << 10}).Apply(c.Request, c.Response)
+	if expected := `{"0":0,"1":1,"2":2}`; resp.Body.String() != expected {
+		t.Errorf("Unexpected streamed object:\n%s\n%s", expected, resp.Body)
+	}
+	if resp.Flushed {
+		t.Errorf("Expected the members to be held in the buffer")
+	}
+
+	// The client goes away after the first member.
+	ch, done := make(chan KV), make(chan struct{})
+	ctx, disconnect := context.WithCancel(context.Background())
+	recorder := httptest.NewRecorder()
+	go func() {
+		defer close(ch)
+		ch <- KV{"first", 1}
+		disconnect()
+		for {
+			select {
+			case ch <- KV{"more", 2}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	c = NewController(NewRequest(jsonRequest.WithContext(ctx)), NewResponse(recorder))
+	c.RenderJsonObjectStreamWith(ch, StreamOptions{Done: done}).Apply(c.Request, c.Response)
+	select {
+	case <-done:
+	default:
+		t.Errorf("Expected the producer to be told to stop")
+	}
+	if !strings.HasPrefix(recorder.Body.String(), `{"first":1`) {
+		t.Errorf("Expected the stream to be cut short, got %s", recorder.Body)
+	}
+
+	// A producer that stops when told, without closing the channel, does not
+	// hold up the request.
+	ch, done = make(chan KV), make(chan struct{})
+	ctx, disconnect = context.WithCancel(context.Background())
+	go func() {
+		ch <- KV{"first", 1}
+		disconnect()
+		<-done
+	}()
+	applied := make(chan bool)
+	go func() {
+		c := NewController(NewRequest(jsonRequest.WithContext(ctx)), NewResponse(httptest.NewRecorder()))
+		c.RenderJsonObjectStreamWith(ch, StreamOptions{Done: done}).Apply(c.Request, c.Response)
+		close(applied)
+	}()
+	select {
+	case <-applied:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the stream to return without waiting for the channel to close")
+	}
+}
+
+func TestRenderJsonStream(t *testing.T) {
+	startFakeBookingApp()
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		ch <- 1
+		ch <- map[string]string{"a": "b"}
+		ch <- nil
+	}()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonStream(ch).Apply(c.Request, c.Response)
+	if expected := `[1,{"a":"b"},null]`; resp.Body.String() != expected {
+		t.Errorf("Unexpected streamed array:\n%s\n%s", expected, resp.Body)
+	}
+}
+
+func TestRenderJsonStreamFailure(t *testing.T) {
+	startFakeBookingApp()
+	defer Config.SetOption("results.compressed", "false")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			ch <- strings.Repeat("a", 2048)
+			ch <- func() {} // Can not be marshaled.
+			ch <- 3
+		}()
+		c := NewController(NewRequest(r), NewResponse(w))
+		defer c.runCleanups()
+		CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.RenderJsonStream(ch).Apply(c.Request, c.Response)
+		}})
+	}))
+	defer server.Close()
+
+	// Even gzipped, the client must not get a complete response.
+	for _, compressed := range []string{"false", "true"} {
+		Config.SetOption("results.compressed", compressed)
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, err := ioutil.ReadAll(resp.Body); err == nil {
+			t.Errorf("Expected the connection to be closed (compressed=%s), got %q", compressed, body)
+		}
+		resp.Body.Close()
+	}
+}
+
+type failingJsonMarshaler struct{}
+
+func (failingJsonMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("not today")
+}
+
+func TestRenderJsonEncodeError(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.Request.Format = "json"
+	c.RenderJson(failingJsonMarshaler{}).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 for a value that fails to encode, got %d", resp.Code)
+	}
+}
+
+func TestRenderJsonETag(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.json.etag", "true")
+	defer Config.SetOption("results.json.etag", "false")
+
+	render := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/hotels/3.json", nil)
+		if ifNoneMatch != "" {
+			httpReq.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		c.RenderJson(map[string]int{"id": 3}).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	first := render("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected a 200 with an ETag, got %d %v", first.Code, first.Header())
+	}
+	if resp := render(`"other", ` + etag); resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+		t.Errorf("Expected a 304 for a matching If-None-Match, got %d %q", resp.Code, resp.Body)
+	}
+	if resp := render(`"other"`); resp.Code != http.StatusOK {
+		t.Errorf("Expected a 200 for a stale If-None-Match, got %d", resp.Code)
+	}
+}
+
+type versionedArticle struct {
+	Id      int
+	Version int `etag:"true"`
+	Body    *marshalCounter
+}
+
+type marshalCounter struct {
+	count int
+}
+
+func (m *marshalCounter) MarshalJSON() ([]byte, error) {
+	m.count++
+	return []byte(`"body"`), nil
+}
+
+func TestRenderJsonVersionETag(t *testing.T) {
+	startFakeBookingApp()
+	body := &marshalCounter{}
+	render := func(version int, ifNoneMatch string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/articles/1", nil)
+		httpReq.Header.Set("If-None-Match", ifNoneMatch)
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		c.RenderJson(&versionedArticle{1, version, body}).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	etag := render(1, "").Header().Get("ETag")
+	if etag == "" || body.count != 1 {
+		t.Fatalf("Expected an ETag from the version, got %q", etag)
+	}
+	if resp := render(1, etag); resp.Code != http.StatusNotModified || body.count != 1 {
+		t.Errorf("Expected a 304 without marshaling, got %d after %d marshals", resp.Code, body.count)
+	}
+	if resp := render(2, etag); resp.Code != http.StatusOK || resp.Header().Get("ETag") == etag {
+		t.Errorf("Expected a new version to get a new ETag, got %d %v", resp.Code, resp.Header())
+	}
+}
+
+type renderAutoHotel struct {
+	Name string
+}
+
+func TestResolveFormat(t *testing.T) {
+	for _, test := range []struct {
+		accept, contentType, format string
+	}{
+		{"", "", "html"},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "application/json", "html"},
+		{"application/json", "", "json"},
+		{"application/xml", "application/json", "xml"},
+		{"", "application/json; charset=utf-8", "json"},
+		{"*/*", "application/json", "json"},
+		{"*/*", "application/vnd.api+json", "json"},
+		{"", "text/xml", "xml"},
+		{"*/*", "text/plain", "txt"},
+		{"*/*", "application/x-www-form-urlencoded", "html"},
+		{"image/png", "application/json", "json"},
+	} {
+		httpReq, _ := http.NewRequest("POST", "/hotels", nil)
+		httpReq.Header.Set("Accept", test.accept)
+		httpReq.Header.Set("Content-Type", test.contentType)
+		if format := ResolveFormat(httpReq); format != test.format {
+			t.Errorf("Accept %q, Content-Type %q: expected %s, got %s", test.accept, test.contentType, test.format, format)
+		}
+	}
+}
+
+func TestRenderAutoVendorTypes(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		accept, format, contentType string
+	}{
+		{"application/vnd.api+json", "json", "application/vnd.api+json; charset=utf-8"},
+		{"application/vnd.example.v2+json; q=0.9, */*;q=0.1", "json", "application/vnd.example.v2+json; charset=utf-8"},
+		{"application/hal+json", "json", "application/hal+json; charset=utf-8"},
+		{"application/vnd.example.v1+xml", "xml", "application/vnd.example.v1+xml; charset=utf-8"},
+		{"application/atom+xml", "xml", "application/atom+xml; charset=utf-8"},
+		{"application/json", "json", "application/json; charset=utf-8"},
+		{"text/xml", "xml", "application/xml; charset=utf-8"},
+	} {
+		httpReq, _ := http.NewRequest("GET", "/hotels/3", nil)
+		httpReq.Header.Set("Accept", test.accept)
+		if format := ResolveFormat(httpReq); format != test.format {
+			t.Errorf("%s: expected format %s, got %s", test.accept, test.format, format)
+			continue
+		}
+
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		c.RenderAuto(renderAutoHotel{"Hotel"}).Apply(c.Request, c.Response)
+		if contentType := resp.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s: expected Content-Type %s, got %s", test.accept, test.contentType, contentType)
+		}
+		if !strings.Contains(resp.Body.String(), "Hotel") {
+			t.Errorf("%s: expected the object to be rendered, got %s", test.accept, resp.Body)
+		}
+	}
+}
+
+func TestRenderAutoHtml(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.SetAction("Hotels", "Show")
+	c.RenderArgs["hotel"] = &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+	c.RenderAuto(nil).Apply(c.Request, c.Response)
+	if !strings.Contains(resp.Body.String(), "300 Main St.") {
+		t.Errorf("Expected the action's template to be rendered, got:\n%s", resp.Body)
+	}
+}
+
+func TestRenderBinaryContentType(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		name, override, content, contentType string
+	}{
+		{"icon.svg", "", "<svg/>", "image/svg+xml"},
+		{"app.wasm", "", "\x00asm", "application/wasm"},
+		{"page", "", "<html><body>hi</body></html>", "text/html; charset=utf-8"},
+		{"icon.svg", "text/plain", "<svg/>", "text/plain"},
+	} {
+		// A stream, and a ReadSeeker that is delegated to http.ServeContent.
+		for _, reader := range []io.Reader{bytes.NewBufferString(test.content), strings.NewReader(test.content)} {
+			resp := httptest.NewRecorder()
+			c := NewController(NewRequest(showRequest), NewResponse(resp))
+			c.Response.ContentType = test.override
+			c.RenderBinary(reader, test.name, Attachment, time.Now()).Apply(c.Request, c.Response)
+			if contentType := resp.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("%s (%T): expected Content-Type %s, got %s", test.name, reader, test.contentType, contentType)
+			}
+			if resp.Body.String() != test.content {
+				t.Errorf("%s (%T): expected the content, got %q", test.name, reader, resp.Body)
+			}
+		}
+	}
+}
+
+func TestRenderBinaryConditional(t *testing.T) {
+	startFakeBookingApp()
+	modTime := time.Date(2014, 1, 2, 3, 4, 5, 0, time.UTC)
+	render := func(reader io.Reader, modTime time.Time, header, value string) *httptest.ResponseRecorder {
+		httpReq, _ := http.NewRequest("GET", "/avatar.png", nil)
+		if header != "" {
+			httpReq.Header.Set(header, value)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		c.RenderBinary(reader, "avatar.png", Inline, modTime).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	// Seekable content is tagged from its modification time and length.
+	resp := render(strings.NewReader("avatar"), modTime, "", "")
+	etag := resp.Header().Get("ETag")
+	if resp.Code != http.StatusOK || etag == "" || resp.Body.String() != "avatar" {
+		t.Fatalf("Expected a tagged 200, got %d, ETag %q, body %q", resp.Code, etag, resp.Body)
+	}
+	resp = render(strings.NewReader("avatar"), modTime, "If-None-Match", etag)
+	if resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+		t.Errorf("Expected 304 for a matching If-None-Match, got %d %q", resp.Code, resp.Body)
+	}
+	resp = render(strings.NewReader("avatar2"), modTime, "If-None-Match", etag)
+	if resp.Code != http.StatusOK {
+		t.Errorf("Expected 200 when the length changed, got %d", resp.Code)
+	}
+
+	// Without a modification time, seekable content is tagged by hashing it.
+	resp = render(strings.NewReader("avatar"), time.Time{}, "", "")
+	hashed := resp.Header().Get("ETag")
+	if hashed == "" || resp.Body.String() != "avatar" {
+		t.Fatalf("Expected a hashed ETag and the whole body, got %q %q", hashed, resp.Body)
+	}
+	if resp = render(strings.NewReader("avatar"), time.Time{}, "If-None-Match", hashed); resp.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching content hash, got %d", resp.Code)
+	}
+
+	// If-Modified-Since is honored, for streams as well.
+	since := modTime.Add(time.Minute).Format(http.TimeFormat)
+	if resp = render(bytes.NewBufferString("avatar"), modTime, "If-Modified-Since", since); resp.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unmodified stream, got %d", resp.Code)
+	}
+	since = modTime.Add(-time.Minute).Format(http.TimeFormat)
+	if resp = render(bytes.NewBufferString("avatar"), modTime, "If-Modified-Since", since); resp.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a modified stream, got %d", resp.Code)
+	}
+}
+
+func TestRenderBinaryRange(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.compressed", "true")
+	Config.SetOption("results.compressed.minsize", "10")
+	defer Config.SetOption("results.compressed", "false")
+
+	content := strings.Repeat("0123456789", 10)
+	for _, test := range []struct {
+		reader   io.Reader
+		code     int
+		expected string
+	}{
+		{strings.NewReader(content), http.StatusPartialContent, "23456"},
+		{bytes.NewBufferString(content[:5]), http.StatusOK, content[:5]}, // Too small to compress.
+	} {
+		httpReq, _ := http.NewRequest("GET", "/media/clip.txt", nil)
+		httpReq.Header.Set("Range", "bytes=2-6")
+		httpReq.Header.Set("Accept-Encoding", "gzip")
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		// The range is not compressed, even though the type is compressible.
+		CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.RenderBinary(test.reader, "clip.txt", Inline, time.Time{}).Apply(c.Request, c.Response)
+		}})
+		c.runCleanups()
+		if resp.Code != test.code || resp.Body.String() != test.expected {
+			t.Errorf("%T: expected %d %q, got %d %q", test.reader, test.code, test.expected, resp.Code, resp.Body)
+		}
+		if resp.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%T: expected no compression, got %q", test.reader, resp.Header().Get("Content-Encoding"))
+		}
+		if test.code == http.StatusPartialContent && resp.Header().Get("Content-Range") != "bytes 2-6/100" {
+			t.Errorf("Unexpected Content-Range %q", resp.Header().Get("Content-Range"))
+		}
+	}
+}
+
+// A file is served in ranges, without hashing the whole of it for the ETag.
+func TestRenderFileInlineRange(t *testing.T) {
+	startFakeBookingApp()
+	file, err := ioutil.TempFile("", "revel-inline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("%PDF-1.4 " + strings.Repeat("0123456789", 10))
+	modTime := time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)
+	file.Close()
+	os.Chtimes(file.Name(), modTime, modTime)
+
+	for _, rangeHeader := range []string{"", "bytes=9-13"} {
+		file, err := os.Open(file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("GET", "/docs/report.pdf", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.RenderBinary(file, "report.pdf", Inline, time.Time{}).Apply(c.Request, c.Response)
+
+		if rangeHeader == "" && (resp.Code != http.StatusOK || resp.Body.Len() != 109) {
+			t.Errorf("Expected the whole file, got %d (%d bytes)", resp.Code, resp.Body.Len())
+		}
+		if rangeHeader != "" && (resp.Code != http.StatusPartialContent || resp.Body.String() != "01234") {
+			t.Errorf("Expected the range, got %d %q", resp.Code, resp.Body)
+		}
+		for header, expected := range map[string]string{
+			"Content-Disposition": "inline; filename=report.pdf",
+			"Content-Type":        "application/pdf",
+			"Accept-Ranges":       "bytes",
+			"Last-Modified":       modTime.Format(http.TimeFormat),
+			"ETag":                fmt.Sprintf(`"%x-%x"`, modTime.UnixNano(), 109),
+		} {
+			if actual := resp.Header().Get(header); actual != expected {
+				t.Errorf("Expected %s: %q, got %q", header, expected, actual)
+			}
+		}
+	}
+}
+
+func TestRenderBinaryWithLength(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		content  string
+		length   int64
+		expected string
+	}{
+		{"0123456789", 10, "0123456789"},
+		{"0123456789abc", 10, "0123456789"},
+		{"01234", 10, "01234"},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		// A stream, so that it is not delegated to http.ServeContent.
+		reader := bytes.NewBufferString(test.content)
+		c.RenderBinaryWithLength(reader, "report.bin", Attachment, time.Now(), test.length).Apply(c.Request, c.Response)
+		if resp.Header().Get("Content-Length") != "10" || resp.Body.String() != test.expected {
+			t.Errorf("%q: unexpected response %v %q", test.content, resp.Header(), resp.Body)
+		}
+	}
+}
+
+// closeRecorder records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestRenderReader(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	reader := &closeRecorder{Reader: strings.NewReader("\x89PNG...")}
+	c.RenderReader(reader, "image/png").Apply(c.Request, c.Response)
+	if resp.Code != http.StatusOK || resp.Header().Get("Content-Type") != "image/png" || resp.Body.String() != "\x89PNG..." {
+		t.Errorf("Unexpected response: %d %v %q", resp.Code, resp.Header(), resp.Body)
+	}
+	if disposition := resp.Header().Get("Content-Disposition"); disposition != "" {
+		t.Errorf("Expected no Content-Disposition, got %q", disposition)
+	}
+	if !reader.closed {
+		t.Error("Expected the reader to be closed")
+	}
+}
+
+func TestRenderJsonError(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.Validation = &Validation{}
+	c.Validation.Required("").Key("booking.CheckInDate")
+	c.Validation.Error("Too long").Key("booking.Name")
+
+	c.RenderJsonError(0).Apply(c.Request, c.Response)
+	expected := `{"errors":[{"field":"booking.CheckInDate","message":"Required"},` +
+		`{"field":"booking.Name","message":"Too long"}]}`
+	if resp.Code != 422 || !strings.HasPrefix(resp.Header().Get("Content-Type"), "application/json") ||
+		strings.Join(strings.Fields(resp.Body.String()), "") != strings.Replace(expected, " ", "", -1) {
+		t.Errorf("Unexpected response: %d %v %s", resp.Code, resp.Header(), resp.Body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonError(http.StatusBadRequest, &ValidationError{Key: "id", Message: "Unknown hotel"}).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), `"Unknown hotel"`) {
+		t.Errorf("Unexpected response: %d %s", resp.Code, resp.Body)
+	}
+}
+
+func TestContentDispositionFilename(t *testing.T) {
+	for _, test := range []struct{ name, expected string }{
+		{"", "attachment"},
+		{"report.pdf", "attachment; filename=report.pdf"},
+		{"annual report.pdf", `attachment; filename="annual report.pdf"; filename*=UTF-8''annual%20report.pdf`},
+		{`say "hi".txt`, `attachment; filename="say \"hi\".txt"; filename*=UTF-8''say%20%22hi%22.txt`},
+		{"報告.pdf", `attachment; filename="__.pdf"; filename*=UTF-8''%E5%A0%B1%E5%91%8A.pdf`},
+	} {
+		if actual := Attachment.header(test.name); actual != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestRenderLive(t *testing.T) {
+	startFakeBookingApp()
+	live := func(accept string) *httptest.ResponseRecorder {
+		updates := make(chan interface{})
+		go func() {
+			defer close(updates)
+			updates <- map[string]int{"visitors": 2}
+			updates <- map[string]int{"visitors": 3}
+		}()
+		httpReq, _ := http.NewRequest("GET", "/stats", nil)
+		httpReq.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		snapshot := func() interface{} { return map[string]int{"visitors": 1} }
+		c.RenderLive(snapshot, updates).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := live("application/json")
+	if resp.Body.String() != `{"visitors":1}` || resp.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("Expected a JSON snapshot, got %v %s", resp.Header(), resp.Body)
+	}
+
+	resp = live("text/event-stream")
+	expected := "data: {\"visitors\":1}\n\ndata: {\"visitors\":2}\n\ndata: {\"visitors\":3}\n\n"
+	if resp.Body.String() != expected || resp.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %v %q", resp.Header(), resp.Body)
+	}
+}
+
+func TestRenderLiveDisconnect(t *testing.T) {
+	startFakeBookingApp()
+	updates := make(chan interface{})
+	ctx, disconnect := context.WithCancel(context.Background())
+	go func() {
+		updates <- map[string]int{"visitors": 2}
+		disconnect() // The stream goes quiet, without being closed.
+	}()
+	httpReq, _ := http.NewRequest("GET", "/stats", nil)
+	httpReq.Header.Set("Accept", "text/event-stream")
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(httpReq.WithContext(ctx)), NewResponse(resp))
+
+	applied := make(chan bool)
+	go func() {
+		c.RenderLive(nil, updates).Apply(c.Request, c.Response)
+		close(applied)
+	}()
+	select {
+	case <-applied:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the event stream to end when the client disconnects")
+	}
+	if expected := "data: {\"visitors\":2}\n\n"; resp.Body.String() != expected {
+		t.Errorf("Expected the events sent before the disconnect, got %q", resp.Body)
+	}
+}
+
+func TestRenderPage(t *testing.T) {
+	startFakeBookingApp()
+	page := func(rawUrl string, page, perPage, total int) *httptest.ResponseRecorder {
+		httpReq, _ := http.NewRequest("GET", rawUrl, nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		c.SetAction("Hotels", "Index")
+		ParseParams(c.Params, c.Request)
+		c.RenderPage([]int{1, 2}, page, perPage, total).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := page("/hotels?page=3&per_page=20&q=inn", 3, 20, 95)
+	expected := `</hotels?page=1&per_page=20&q=inn>; rel="first", ` +
+		`</hotels?page=2&per_page=20&q=inn>; rel="prev", ` +
+		`</hotels?page=4&per_page=20&q=inn>; rel="next", ` +
+		`</hotels?page=5&per_page=20&q=inn>; rel="last"`
+	if link := resp.Header().Get("Link"); link != expected {
+		t.Errorf("Expected Link:\n%s\ngot:\n%s", expected, link)
+	}
+	if resp.Header().Get("X-Total-Count") != "95" || resp.Body.String() != "[1,2]" {
+		t.Errorf("Expected the total and the page, got %v %s", resp.Header(), resp.Body)
+	}
+
+	resp = page("/hotels", 1, 20, 20)
+	if link, ok := resp.Header()["Link"]; ok {
+		t.Errorf("Expected no links for a single page, got %v", link)
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.Request.Format = "json"
+	c.TooManyRequests(1500*time.Millisecond, "Limit of %d per minute", 60).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusTooManyRequests || resp.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected a 429 with Retry-After 2, got %d %v", resp.Code, resp.Header())
+	}
+	if !strings.Contains(resp.Body.String(), "retryAfter: 2") ||
+		!strings.Contains(resp.Body.String(), "Limit of 60 per minute") {
+		t.Errorf("Expected the retry info in the body, got %s", resp.Body)
+	}
+
+	retryAt := time.Now().Add(time.Minute)
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.TooManyRequestsUntil(retryAt, "Slow down").Apply(c.Request, c.Response)
+	if resp.Header().Get("Retry-After") != retryAt.UTC().Format(http.TimeFormat) {
+		t.Errorf("Expected an HTTP-date Retry-After, got %v", resp.Header())
+	}
+	if !strings.Contains(resp.Body.String(), "retry in 60 seconds") {
+		t.Errorf("Expected the retry info in the page, got %s", resp.Body)
+	}
+}
+
+func TestRenderJsonNilBody(t *testing.T) {
+	startFakeBookingApp()
+	var nilMap map[string]int
+	for _, test := range []struct {
+		obj      interface{}
+		nilBody  string
+		expected string
+	}{
+		{nil, "", "null"},
+		{nil, "{}", "{}"},
+		{nilMap, "{}", "{}"},
+		{(*Hotel)(nil), "null", "null"},
+		{map[string]int{}, "null", "{}"},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+		c.RenderJsonWith(test.obj, JsonOptions{NilBody: test.nilBody}).Apply(c.Request, c.Response)
+		if resp.Body.String() != test.expected {
+			t.Errorf("%#v: expected %s, got %s", test.obj, test.expected, resp.Body)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.SetStatus(http.StatusNoContent)
+	c.RenderJsonWith(nil, JsonOptions{NilBody: "{}"}).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusNoContent || resp.Body.Len() != 0 {
+		t.Errorf("Expected an empty 204, got %d %q", resp.Code, resp.Body)
+	}
+}
+
+func TestRenderJsonPCallback(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		callback string
+		status   int
+	}{
+		{"cb", http.StatusOK},
+		{"jQuery1102_$", http.StatusOK},
+		{"app.handlers.onLoad", http.StatusOK},
+		{"", http.StatusBadRequest},
+		{"alert(1);cb", http.StatusBadRequest},
+		{"cb</script><script>", http.StatusBadRequest},
+		{"1cb", http.StatusBadRequest},
+		{"cb\n", http.StatusBadRequest},
+	} {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+		c.RenderJsonP(test.callback, map[string]int{"id": 1}).Apply(c.Request, c.Response)
+		if resp.Code != test.status {
+			t.Errorf("%q: expected %d, got %d", test.callback, test.status, resp.Code)
+		}
+		if test.status == http.StatusOK && !strings.HasPrefix(resp.Header().Get("Content-Type"), "application/javascript") {
+			t.Errorf("%q: expected application/javascript, got %s", test.callback, resp.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestRenderJsonPretty(t *testing.T) {
+	startFakeBookingApp()
+	render := func(render func(c *Controller) Result) string {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+		render(c).Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+	obj := map[string]int{"id": 1}
+
+	if body := render(func(c *Controller) Result { return c.RenderJson(obj) }); body != `{"id":1}` {
+		t.Errorf("Expected compact JSON in production, got %s", body)
+	}
+	if body := render(func(c *Controller) Result { return c.RenderJsonPretty(obj) }); body != "{\n  \"id\": 1\n}" {
+		t.Errorf("Expected indented JSON, got %s", body)
+	}
+
+	defer func(devMode bool) { DevMode = devMode }(DevMode)
+	DevMode = true
+	if body := render(func(c *Controller) Result { return c.RenderJsonP("cb", obj) }); body != "cb({\n  \"id\": 1\n});" {
+		t.Errorf("Expected indented JSONP in dev mode, got %s", body)
+	}
+}
+
+func TestRenderNegotiated(t *testing.T) {
+	startFakeBookingApp()
+	for _, test := range []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"application/json;q=0.9, text/html;q=1.0", http.StatusOK, "text/html; charset=utf-8"},
+		{"text/html;q=0.5, application/json", http.StatusOK, "application/json; charset=utf-8"},
+		{"image/png, application/xml;q=0.1", http.StatusOK, "application/xml; charset=utf-8"},
+		{"application/json;q=0, */*;q=0.1", http.StatusOK, "text/html; charset=utf-8"},
+		{"", http.StatusOK, "text/html; charset=utf-8"},
+		{"image/png", http.StatusNotAcceptable, "text/html; charset=utf-8"},
+	} {
+		httpReq, _ := http.NewRequest("GET", "/hotels/3", nil)
+		httpReq.Header.Set("Accept", test.accept)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httpReq), NewResponse(resp))
+		c.SetAction("Hotels", "Show")
+		hotel := &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+		c.RenderArgs["hotel"] = hotel
+		c.RenderNegotiated(hotel).Apply(c.Request, c.Response)
+		if resp.Code != test.status || resp.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("%q: expected %d %s, got %d %s", test.accept, test.status, test.contentType,
+				resp.Code, resp.Header().Get("Content-Type"))
+		}
+	}
+}
+
+type csvBooking struct {
+	Id      int       `csv:"id"`
+	Hotel   *string   `csv:"hotel"`
+	CheckIn time.Time `csv:"check_in"`
+	CardCvc string    `csv:"-"`
+	Nights  int
+}
+
+func TestRenderCsv(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderCsvWith([][]string{{"a", "b;c"}, {"1", "2"}}, CsvOptions{Comma: ';'}).Apply(c.Request, c.Response)
+	if resp.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Errorf("Unexpected Content-Type %q", resp.Header().Get("Content-Type"))
+	}
+	if resp.Header().Get("Content-Disposition") != "attachment" {
+		t.Errorf("Unexpected Content-Disposition %q", resp.Header().Get("Content-Disposition"))
+	}
+	if resp.Body.String() != "a;\"b;c\"\n1;2\n" {
+		t.Errorf("Unexpected body %q", resp.Body)
+	}
+
+	hotel := "Hilton"
+	checkIn := time.Date(2014, 1, 2, 0, 0, 0, 0, time.UTC)
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderCsv([]*csvBooking{
+		{Id: 1, Hotel: &hotel, CheckIn: checkIn, CardCvc: "123", Nights: 2},
+		nil,
+		{Id: 2, CheckIn: checkIn, Nights: 1},
+	}, "bookings.csv").Apply(c.Request, c.Response)
+	if resp.Header().Get("Content-Disposition") != "attachment; filename=bookings.csv" {
+		t.Errorf("Unexpected Content-Disposition %q", resp.Header().Get("Content-Disposition"))
+	}
+	expected := "id,hotel,check_in,Nights\n" +
+		"1,Hilton,2014-01-02T00:00:00Z,2\n" +
+		",,,\n" +
+		"2,,2014-01-02T00:00:00Z,1\n"
+	if resp.Body.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, resp.Body)
+	}
+
+	// The error is only described in dev mode.
+	defer func(devMode bool) { DevMode = devMode }(DevMode)
+	for _, devMode := range []bool{true, false} {
+		DevMode = devMode
+		resp = httptest.NewRecorder()
+		c = NewController(NewRequest(showRequest), NewResponse(resp))
+		c.Request.Format = "json"
+		c.RenderCsv(map[string]int{"a": 1}, "").Apply(c.Request, c.Response)
+		if resp.Code != http.StatusInternalServerError {
+			t.Errorf("Expected an error for a map, got %d", resp.Code)
+		}
+		if mentions := strings.Contains(resp.Body.String(), "map[string]int"); mentions != devMode {
+			t.Errorf("Expected the type to be named only in dev mode (dev=%v):\n%s", devMode, resp.Body)
+		}
+	}
+}
+
+type yamlService struct {
+	Name     string            `yaml:"name"`
+	Port     int               `yaml:"port"`
+	Hosts    []string          `yaml:"hosts"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	Version  string            `yaml:"version"`
+	Released string            `yaml:"released"`
+	Password string            `yaml:"-"`
+	Enabled  bool
+}
+
+func TestRenderYaml(t *testing.T) {
+	startFakeBookingApp()
+	service := yamlService{
+		Name:     "booking: api",
+		Port:     9000,
+		Hosts:    []string{"a.example.com", "b.example.com"},
+		Version:  "1.10",
+		Released: "2014-03-01",
+		Password: "secret",
+		Enabled:  true,
+	}
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderYaml(service).Apply(c.Request, c.Response)
+	if resp.Header().Get("Content-Type") != "application/x-yaml; charset=utf-8" {
+		t.Errorf("Unexpected Content-Type %q", resp.Header().Get("Content-Type"))
+	}
+	// Strings that would read back as another type (e.g. a timestamp) are quoted.
+	expected := `name: 'booking: api'
+port: 9000
+hosts:
+- a.example.com
+- b.example.com
+version: "1.10"
+released: "2014-03-01"
+enabled: true
+`
+	if resp.Body.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, resp.Body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderYaml([]map[string]interface{}{{"b": []int{}, "a": map[string]int{"x": 1}}}).Apply(c.Request, c.Response)
+	if expected := "- a:\n    x: 1\n  b: []\n"; resp.Body.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, resp.Body)
+	}
+
+	// The error is only described in dev mode.
+	defer func(devMode bool) { DevMode = devMode }(DevMode)
+	for _, devMode := range []bool{true, false} {
+		DevMode = devMode
+		resp = httptest.NewRecorder()
+		c = NewController(NewRequest(showRequest), NewResponse(resp))
+		c.Request.Format = "json"
+		c.RenderYaml(make(chan int)).Apply(c.Request, c.Response)
+		if resp.Code != http.StatusInternalServerError {
+			t.Errorf("Expected an error for a channel, got %d", resp.Code)
+		}
+		if mentions := strings.Contains(resp.Body.String(), "chan int"); mentions != devMode {
+			t.Errorf("Expected the type to be named only in dev mode (dev=%v):\n%s", devMode, resp.Body)
+		}
+	}
+}
+
+type msgPackHotel struct {
+	Id       int      `msgpack:"id" json:"hotel_id"`
+	Name     string   `json:"name"`
+	Price    float64  `json:"price,omitempty"`
+	Tags     []string `json:"tags"`
+	Password string   `json:"-"`
+	Open     bool
+}
+
+func TestRenderMsgPack(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderMsgPack(msgPackHotel{Id: 3, Name: "Hilton", Tags: []string{"a"}, Password: "secret", Open: true}).Apply(c.Request, c.Response)
+	if resp.Header().Get("Content-Type") != "application/msgpack" {
+		t.Errorf("Unexpected Content-Type %q", resp.Header().Get("Content-Type"))
+	}
+	expected := "\x84" + // map of 4
+		"\xa2id\x03" +
+		"\xa4name\xa6Hilton" +
+		"\xa4tags\x91\xa1a" +
+		"\xa4Open\xc3"
+	if resp.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, resp.Body.String())
+	}
+
+	for _, test := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "\xc0"},
+		{-200, "\xd1\xff\x38"},
+		{[]byte("ab"), "\xc4\x02ab"},
+		{map[string]interface{}{"b": 2, "a": 1}, "\x82\xa1a\x01\xa1b\x02"},
+	} {
+		resp = httptest.NewRecorder()
+		c = NewController(NewRequest(showRequest), NewResponse(resp))
+		c.RenderMsgPack(test.value).Apply(c.Request, c.Response)
+		if resp.Body.String() != test.expected {
+			t.Errorf("Expected %#v to encode as %q, got %q", test.value, test.expected, resp.Body.String())
+		}
+	}
+
+	// The value is streamed, so an error is logged and the connection closed.
+	var logged bytes.Buffer
+	defer func(logger *log.Logger) { ERROR = logger }(ERROR)
+	ERROR = log.New(&logged, "", 0)
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	aborted := applyAborts(func() { c.RenderMsgPack(make(chan int)).Apply(c.Request, c.Response) })
+	if !aborted || resp.Body.Len() != 0 || !strings.Contains(logged.String(), "chan int") {
+		t.Errorf("Expected the error to be logged and the response aborted, got %v, %q and %q",
+			aborted, resp.Body.String(), logged.String())
+	}
+}
+
+// applyAborts returns true if apply aborts the response with
+// http.ErrAbortHandler.
+func applyAborts(apply func()) (aborted bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			if err != http.ErrAbortHandler {
+				panic(err)
+			}
+			aborted = true
+		}
+	}()
+	apply()
+	return false
+}
+
+func TestRenderWithStatus(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderWithStatus(http.StatusCreated, c.RenderJson(map[string]int{"id": 1})).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusCreated || !strings.Contains(resp.Body.String(), `"id"`) {
+		t.Errorf("Expected a 201 with the JSON body, got %d %q", resp.Code, resp.Body)
+	}
+
+	// RenderWithStatus wins over SetStatus.
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.SetStatus(http.StatusAccepted)
+	c.RenderWithStatus(422, c.RenderXml(renderAutoHotel{})).Apply(c.Request, c.Response)
+	if resp.Code != 422 {
+		t.Errorf("Expected 422, got %d", resp.Code)
+	}
+
+	// A preset status is honored without the wrapper.
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.SetStatus(http.StatusCreated)
+	c.RenderText("created").Apply(c.Request, c.Response)
+	if resp.Code != http.StatusCreated {
+		t.Errorf("Expected 201 from SetStatus, got %d", resp.Code)
+	}
+}
+
+func TestRenderFormat(t *testing.T) {
+	startFakeBookingApp()
+	dir, err := ioutil.TempDir("", "revel-format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "Hotels"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "Hotels", "Show.txt"), []byte("{{.hotel.Name}}, {{.hotel.Address}}"), 0644)
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{dir})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.SetAction("Hotels", "Show")
+	methodType := *c.MethodType
+	c.MethodType = &methodType
+	hotel := &Hotel{Name: "A Hotel", Address: "300 Main St."}
+
+	// The names of the args are recorded by the line of the call.
+	_, _, line, _ := runtime.Caller(0)
+	c.MethodType.RenderArgNames = map[int][]string{line + 2: {"hotel"}}
+	result := c.RenderFormat("txt", hotel)
+
+	result.Apply(c.Request, c.Response)
+	if resp.Body.String() != "A Hotel, 300 Main St." ||
+		!strings.HasPrefix(resp.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected the txt template, got %v %s", resp.Header(), resp.Body)
+	}
+}
+
+func TestRenderWithLayout(t *testing.T) {
+	startFakeBookingApp()
+	dir, err := ioutil.TempDir("", "revel-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"layouts/main.html": `<title>{{.title}}</title><main>{{content .}}</main>`,
+		"Hotels/Page.html":  `{{set . "title" "Hotels"}}<p>{{.name}}</p>`,
+		"Hotels/Page.json":  `{"name": "{{.name}}"}`,
+	} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{dir})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(render func(c *Controller) Result) string {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.RenderArgs["name"] = "<Hilton>"
+		render(c).Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+
+	expected := `<title>Hotels</title><main><p>&lt;Hilton&gt;</p></main>`
+	if body := render(func(c *Controller) Result {
+		return c.RenderWithLayout("layouts/main.html", "Hotels/Page.html")
+	}); body != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+	if body := render(func(c *Controller) Result {
+		c.Layout = "layouts/main.html"
+		return c.RenderTemplate("Hotels/Page.html")
+	}); body != expected {
+		t.Errorf("Expected c.Layout to be applied, got %s", body)
+	}
+	if body := render(func(c *Controller) Result {
+		c.Layout = "layouts/main.html"
+		return c.RenderTemplate("Hotels/Page.json")
+	}); body != `{"name": "&lt;Hilton&gt;"}` {
+		t.Errorf("Expected a JSON template not to be wrapped, got %s", body)
+	}
+}
+
+func TestRenderTemplateString(t *testing.T) {
+	startFakeBookingApp()
+	dir, err := ioutil.TempDir("", "revel-string")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "Mail"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "Mail", "Welcome.txt"), []byte("Welcome, {{.user}}!"), 0644)
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{dir})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	body, err := c.RenderTemplateString("Mail/Welcome.txt", map[string]interface{}{"user": "Bob"})
+	if err != nil || body != "Welcome, Bob!" {
+		t.Errorf("Unexpected output %q (%v)", body, err)
+	}
+
+	c.RenderArgs["user"] = "Alice"
+	if body, _ = c.RenderTemplateString("Mail/Welcome.txt", nil); body != "Welcome, Alice!" {
+		t.Errorf("Expected the controller's render args to be used, got %q", body)
+	}
+
+	if _, err = c.RenderTemplateString("Mail/Missing.txt", nil); err == nil {
+		t.Error("Expected an error for a missing template")
+	}
+	if resp.Body.Len() != 0 {
+		t.Errorf("Expected nothing written to the response, got %q", resp.Body)
+	}
+}
+
+func TestRenderStatic(t *testing.T) {
+	startFakeBookingApp()
+	dir, err := ioutil.TempDir("", "revel-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "Pages"), 0755)
+	templatePath := filepath.Join(dir, "Pages", "About.html")
+	ioutil.WriteFile(templatePath, []byte("About {{.name}}"), 0644)
+	templateTime := time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)
+	os.Chtimes(templatePath, templateTime, templateTime)
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{dir})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	dataTime := templateTime.Add(time.Hour)
+	for _, test := range []struct {
+		modTime, since time.Time
+		status         int
+		lastModified   time.Time
+	}{
+		{time.Time{}, time.Time{}, http.StatusOK, templateTime},
+		{dataTime, time.Time{}, http.StatusOK, dataTime},
+		{time.Time{}, templateTime, http.StatusNotModified, templateTime},
+		{dataTime, templateTime, http.StatusOK, dataTime},
+		{dataTime, dataTime.Add(time.Minute), http.StatusNotModified, dataTime},
+	} {
+		req, _ := http.NewRequest("GET", "/about", nil)
+		if !test.since.IsZero() {
+			req.Header.Set("If-Modified-Since", test.since.Format(http.TimeFormat))
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.RenderArgs["name"] = "us"
+		c.RenderStatic("Pages/About.html", test.modTime).Apply(c.Request, c.Response)
+		if resp.Code != test.status {
+			t.Errorf("Expected %d for %v since %v, got %d", test.status, test.modTime, test.since, resp.Code)
+		}
+		if expected := test.lastModified.Format(http.TimeFormat); resp.Header().Get("Last-Modified") != expected {
+			t.Errorf("Expected Last-Modified %s, got %s", expected, resp.Header().Get("Last-Modified"))
+		}
+		if body := resp.Body.String(); test.status == http.StatusOK && body != "About us" || test.status != http.StatusOK && body != "" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Render304NotModified().Apply(c.Request, c.Response)
+	if resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304, got %d %q", resp.Code, resp.Body)
+	}
+}
+
+func TestRenderCached(t *testing.T) {
+	startFakeBookingApp()
+	article := versionedArticle{Id: 1, Version: 2, Body: &marshalCounter{}}
+	render := func(session Session, header http.Header, cached func(c *Controller) Result) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/articles/1", nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.Session = session
+		cached(c).Apply(c.Request, c.Response)
+		return resp
+	}
+	hour := func(c *Controller) Result { return c.RenderCached(time.Hour, c.RenderJson(article)) }
+
+	resp := render(Session{}, nil, hour)
+	expires, err := http.ParseTime(resp.Header().Get("Expires"))
+	if resp.Code != http.StatusOK || resp.Header().Get("Cache-Control") != "public, max-age=3600" ||
+		err != nil || expires.Sub(time.Now()) < 59*time.Minute {
+		t.Errorf("Expected a public response cached for an hour, got %d %v", resp.Code, resp.Header())
+	}
+
+	// Revalidating keeps the headers.
+	etag := resp.Header().Get("ETag")
+	resp = render(Session{}, http.Header{"If-None-Match": {etag}}, hour)
+	if resp.Code != http.StatusNotModified || resp.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("Expected a 304 with the caching headers, got %d %v", resp.Code, resp.Header())
+	}
+
+	// Authenticated responses are private.
+	for _, test := range []struct {
+		session Session
+		header  http.Header
+	}{
+		{Session{"user": "bob"}, nil},
+		{Session{}, http.Header{"Authorization": {"Bearer token"}}},
+	} {
+		resp = render(test.session, test.header, hour)
+		if resp.Header().Get("Cache-Control") != "private, max-age=3600" {
+			t.Errorf("%v %v: expected a private response, got %v", test.session, test.header, resp.Header())
+		}
+	}
+
+	resp = render(Session{}, nil, func(c *Controller) Result { return c.NoCache(c.RenderText("balance")) })
+	expires, _ = http.ParseTime(resp.Header().Get("Expires"))
+	if resp.Header().Get("Cache-Control") != "no-store, no-cache" || expires.After(time.Now()) ||
+		resp.Body.String() != "balance" {
+		t.Errorf("Expected an uncacheable response, got %v %q", resp.Header(), resp.Body)
+	}
+}
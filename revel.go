@@ -5,8 +5,10 @@ import (
 	"github.com/robfig/config"
 	"go/build"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -49,6 +51,20 @@ var (
 	ConfPaths     []string
 	TemplatePaths []string
 
+	// If set, the app's templates are loaded from TemplateFS rather than from
+	// its views directory, except in dev mode, so that they may be embedded in
+	// the binary.  (Those of Revel, e.g. errors/404.html, and of the modules are
+	// still loaded from the other TemplatePaths, unless embedded templates of
+	// the same name override them.)  It holds the app's views, e.g. in
+	// app/init.go:
+	//   //go:embed views
+	//   var views embed.FS
+	//
+	//   func init() {
+	//     revel.TemplateFS, _ = fs.Sub(views, "views")
+	//   }
+	TemplateFS fs.FS
+
 	Modules []Module
 
 	// Server config.
@@ -69,6 +85,7 @@ var (
 	// Cookie flags
 	CookieHttpOnly bool
 	CookieSecure   bool
+	CookieSameSite http.SameSite // "cookie.samesite": lax (default), strict, or none
 
 	// Delimiters to use when rendering templates
 	TemplateDelims string
@@ -94,6 +111,9 @@ var (
 	// Private
 	secretKey []byte // Key used to sign cookies. An empty key disables signing.
 	packaged  bool   // If true, this is running from a pre-built package.
+
+	// Keys that signed cookies before secretKey, which are still accepted.
+	previousSecretKeys [][]byte
 )
 
 func init() {
@@ -181,10 +201,17 @@ func Init(mode, importPath, srcPath string) {
 	CookiePrefix = Config.StringDefault("cookie.prefix", "REVEL")
 	CookieHttpOnly = Config.BoolDefault("cookie.httponly", false)
 	CookieSecure = Config.BoolDefault("cookie.secure", false)
+	CookieSameSite = parseSameSite(Config.StringDefault("cookie.samesite", "lax"))
 	TemplateDelims = Config.StringDefault("template.delimiters", "")
 	if secretStr := Config.StringDefault("app.secret", ""); secretStr != "" {
 		secretKey = []byte(secretStr)
 	}
+	// To rotate the secret, move the old one to app.secret.previous (a list
+	// separated by spaces) until the cookies it signed have expired.
+	previousSecretKeys = nil
+	for _, secretStr := range strings.Fields(Config.StringDefault("app.secret.previous", "")) {
+		previousSecretKeys = append(previousSecretKeys, []byte(secretStr))
+	}
 
 	// Configure logging.
 	TRACE = getLogger("trace")
@@ -213,11 +240,9 @@ func getLogger(name string) *log.Logger {
 	case "stderr":
 		newlog = revelLogs{c: colors[name], w: os.Stderr}
 		logger = newLogger(&newlog)
+	case "off":
+		logger = newLogger(ioutil.Discard)
 	default:
-		if output == "off" {
-			output = os.DevNull
-		}
-
 		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 		if err != nil {
 			log.Fatalln("Failed to open log file", output, ":", err)
@@ -239,6 +264,12 @@ func getLogger(name string) *log.Logger {
 	return logger
 }
 
+// traceEnabled returns false if TRACE discards its output, so that callers
+// may skip building what they would log.
+func traceEnabled() bool {
+	return TRACE.Writer() != ioutil.Discard
+}
+
 func newLogger(wr io.Writer) *log.Logger {
 	return log.New(wr, "", INFO.Flags())
 }
@@ -32,6 +32,8 @@ type RouteMatch struct {
 	MethodName     string // e.g. ShowApp
 	FixedParams    []string
 	Params         map[string][]string // e.g. {id: 123}
+
+	routePath string // e.g. /app/:id
 }
 
 type arg struct {
@@ -125,6 +127,7 @@ func (router *Router) Route(req *http.Request) *RouteMatch {
 		MethodName:     methodName,
 		Params:         params,
 		FixedParams:    route.FixedParams,
+		routePath:      route.Path,
 	}
 }
 
@@ -369,7 +372,7 @@ func (router *Router) Reverse(action string, argValues map[string]string) *Actio
 			pathElements = strings.Split(route.Path, "/")
 		)
 		for i, el := range pathElements {
-			if el == "" || el[0] != ':' {
+			if el == "" || (el[0] != ':' && el[0] != '*') {
 				continue
 			}
 
@@ -378,6 +381,10 @@ func (router *Router) Reverse(action string, argValues map[string]string) *Actio
 				val = "<nil>"
 				ERROR.Print("revel/router: reverse route missing route arg ", el[1:])
 			}
+			if el[0] == '*' {
+				// A wildcard captures the rest of the path, slashes included.
+				val = escapeWildcardPath(val)
+			}
 			pathElements[i] = val
 			delete(argValues, el[1:])
 			continue
@@ -414,8 +421,48 @@ func (router *Router) Reverse(action string, argValues map[string]string) *Actio
 	return nil
 }
 
+// escapeWildcardPath escapes each segment of a path captured by a wildcard,
+// keeping the slashes between them.
+func escapeWildcardPath(val string) string {
+	segments := strings.Split(val, "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(url.QueryEscape(segment), "+", "%20", -1)
+	}
+	return strings.Join(segments, "/")
+}
+
+// How to route a request whose path differs from that of its route only by a
+// trailing slash, e.g. /hotels/ for a route of /hotels.  Set by
+// "routes.trailingslash" in app.conf:
+//   match    - route the request as usual (the default)
+//   redirect - redirect to the path as the route has it, with 301 Moved
+//              Permanently (or 308 Permanent Redirect, which keeps the body of
+//              a POST), so that each page has one URL
+//   strict   - respond 404 Not Found
+// Routes with a wildcard (e.g. /public/*filepath) always match as usual.
+var trailingSlashPolicy = "match"
+
+// canonicalPath returns the request path with its trailing slash as the
+// route's path has it, and whether that is the path already.
+func canonicalPath(reqPath, routePath string) (string, bool) {
+	if routePath == "" || strings.Contains(routePath, "*") {
+		return reqPath, true
+	}
+	canonical := strings.TrimRight(reqPath, "/")
+	if strings.HasSuffix(routePath, "/") {
+		canonical += "/"
+	}
+	return canonical, canonical == reqPath
+}
+
 func init() {
 	OnAppStart(func() {
+		switch trailingSlashPolicy = Config.StringDefault("routes.trailingslash", "match"); trailingSlashPolicy {
+		case "match", "redirect", "strict":
+		default:
+			panic(fmt.Errorf("routes.trailingslash invalid: %s (expected match, redirect, or strict)", trailingSlashPolicy))
+		}
+
 		MainRouter = NewRouter(path.Join(BasePath, "conf", "routes"))
 		if MainWatcher != nil && Config.BoolDefault("watch.routes", true) {
 			MainWatcher.Listen(MainRouter, MainRouter.path)
@@ -428,6 +475,20 @@ func init() {
 func RouterFilter(c *Controller, fc []Filter) {
 	// Figure out the Controller/Action
 	var route *RouteMatch = MainRouter.Route(c.Request.Request)
+	if route != nil && trailingSlashPolicy != "match" {
+		if canonical, ok := canonicalPath(c.Request.URL.Path, route.routePath); !ok {
+			if trailingSlashPolicy == "strict" {
+				route = nil
+			} else {
+				status := http.StatusMovedPermanently
+				if c.Request.Method != "GET" && c.Request.Method != "HEAD" {
+					status = http.StatusPermanentRedirect
+				}
+				c.Result = c.RedirectStatus(status, (&url.URL{Path: canonical, RawQuery: c.Request.URL.RawQuery}).String())
+				return
+			}
+		}
+	}
 	if route == nil {
 		c.Result = c.NotFound("No matching route found")
 		return
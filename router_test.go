@@ -3,6 +3,7 @@ package revel
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 )
@@ -99,12 +100,23 @@ POST  /app/:id                   Application.Save
 PATCH /app/:id/                  Application.Update
 GET   /javascript/:filepath      Static.Serve("public/js")
 GET   /public/*filepath          Static.Serve("public")
+GET   /wiki/*path                Wiki.Page
 *     /:controller/:action       :controller.:action
 
 GET   /favicon.ico               404
 `
 
 var routeMatchTestCases = map[*http.Request]*RouteMatch{
+	&http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: "/wiki/guides/getting started"},
+	}: &RouteMatch{
+		ControllerName: "Wiki",
+		MethodName:     "Page",
+		FixedParams:    []string{},
+		Params:         map[string][]string{"path": {"guides/getting started"}},
+	},
+
 	&http.Request{
 		Method: "GET",
 		URL:    &url.URL{Path: "/"},
@@ -263,6 +275,16 @@ var reverseRoutingTestCases = map[*ReverseRouteArgs]*ActionDefinition{
 		Action: "Implicit.Route",
 	},
 
+	&ReverseRouteArgs{
+		action: "Wiki.Page",
+		args:   map[string]string{"path": "guides/getting started"},
+	}: &ActionDefinition{
+		Url:    "/wiki/guides/getting%20started",
+		Method: "GET",
+		Star:   false,
+		Action: "Wiki.Page",
+	},
+
 	&ReverseRouteArgs{
 		action: "Application.Save",
 		args:   map[string]string{"id": "123", "c": "http://continue"},
@@ -383,3 +405,45 @@ func eq(t *testing.T, name string, a, b interface{}) bool {
 	}
 	return true
 }
+
+func TestRouterFilterTrailingSlash(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { trailingSlashPolicy = "match" }()
+
+	for _, test := range []struct {
+		policy, method, path string
+		status               int
+		location             string
+	}{
+		{"match", "GET", "/hotels/3/", http.StatusOK, ""},
+		{"match", "GET", "/hotels/3", http.StatusOK, ""},
+		{"redirect", "GET", "/hotels/3/?x=1", http.StatusMovedPermanently, "/hotels/3?x=1"},
+		{"redirect", "GET", "/hotels/3//", http.StatusMovedPermanently, "/hotels/3"},
+		{"redirect", "POST", "/bookings/3/cancel/", http.StatusPermanentRedirect, "/bookings/3/cancel"},
+		{"redirect", "GET", "/hotels/3", http.StatusOK, ""},
+		{"strict", "GET", "/hotels/3/", http.StatusNotFound, ""},
+		{"strict", "GET", "/hotels/3", http.StatusOK, ""},
+	} {
+		trailingSlashPolicy = test.policy
+		req, _ := http.NewRequest(test.method, "http://localhost"+test.path, nil)
+		resp := httptest.NewRecorder()
+		handle(resp, req)
+		if resp.Code != test.status {
+			t.Errorf("%s %s %s: expected %d, got %d", test.policy, test.method, test.path, test.status, resp.Code)
+		}
+		if location := resp.Header().Get("Location"); location != test.location {
+			t.Errorf("%s %s %s: expected Location %q, got %q", test.policy, test.method, test.path, test.location, location)
+		}
+	}
+
+	for _, test := range []struct{ reqPath, routePath, expected string }{
+		{"/app/123", "/app/:id/", "/app/123/"},
+		{"/app/123/", "/app/:id/", "/app/123/"},
+		{"/public/css/", "/public/*filepath", "/public/css/"},
+		{"/", "/", "/"},
+	} {
+		if actual, _ := canonicalPath(test.reqPath, test.routePath); actual != test.expected {
+			t.Errorf("Expected %s for %s by %s, got %s", test.expected, test.reqPath, test.routePath, actual)
+		}
+	}
+}
@@ -15,10 +15,10 @@ var (
 )
 
 // This method handles all requests.  It dispatches to handleInternal after
-// handling / adapting websocket connections.
+// handling / adapting websocket connections.  (Websocket requests for other
+// than WS routes are dispatched as usual, for the action to Upgrade.)
 func handle(w http.ResponseWriter, r *http.Request) {
-	upgrade := r.Header.Get("Upgrade")
-	if upgrade == "websocket" || upgrade == "Websocket" {
+	if isWebsocketUpgrade(r) && isWebsocketRoute(r) {
 		websocket.Handler(func(ws *websocket.Conn) {
 			r.Method = "WS"
 			handleInternal(w, r, ws)
@@ -35,13 +35,53 @@ func handleInternal(w http.ResponseWriter, r *http.Request, ws *websocket.Conn)
 		c    = NewController(req, resp)
 	)
 	req.Websocket = ws
+	defer fireLifecycleEvent(RequestEnd, c)
+	defer c.runCleanups()
+
+	// This is the first cleanup, so it runs after those of the filters and
+	// results, and before the functions registered by Defer.  Only then is the
+	// response flushed, as that leaves it without a Content-Length.
+	c.addCleanup(func() {
+		// A websocket has taken over the connection.
+		if c.deferred > 0 && c.Request.Websocket == nil {
+			flushResponse(w)
+		}
+	})
+
+	setDefaultHeaders(w.Header())
+	w.Header().Set(RequestIdHeader, c.RequestID())
+
+	// Respond to HEAD as to GET, without the body, whatever the result.
+	if r.Method == "HEAD" {
+		head := newHeadResponseWriter(w)
+		resp.Out = head
+		c.addCleanup(head.Close)
+	}
+	fireLifecycleEvent(RequestStart, c)
 
 	Filters[0](c, Filters[1:])
 	if c.Result != nil {
+		fireLifecycleEvent(BeforeRender, c)
 		c.Result.Apply(req, resp)
 	}
 }
 
+// newMainTemplateLoader returns the loader of the app's templates: from
+// TemplateFS, if set and not in dev mode, followed by those of Revel and the
+// modules on disk; or else from the TemplatePaths.
+func newMainTemplateLoader() *TemplateLoader {
+	if TemplateFS == nil || DevMode {
+		return NewTemplateLoader(TemplatePaths)
+	}
+	var paths []string
+	for _, path := range TemplatePaths {
+		if path != ViewsPath {
+			paths = append(paths, path)
+		}
+	}
+	return NewTemplateLoaderFS(TemplateFS, []string{"."}, paths)
+}
+
 // Run the server.
 // This is called from the generated main file.
 // If port is non-zero, use that.  Else, read the port from app.conf.
@@ -56,7 +96,7 @@ func Run(port int) {
 		address = fmt.Sprintf("%s:%d", address, port)
 	}
 
-	MainTemplateLoader = NewTemplateLoader(TemplatePaths)
+	MainTemplateLoader = newMainTemplateLoader()
 
 	// The "watch" config variable can turn on and off all watching.
 	// (As a convenient way to control it all together.)
@@ -67,9 +107,11 @@ func Run(port int) {
 
 	// If desired (or by default), create a watcher for templates and routes.
 	// The watcher calls Refresh() on things on the first request.
-	if MainWatcher != nil && Config.BoolDefault("watch.templates", true) {
+	// (Embedded templates never change, so they are not watched.)
+	if MainWatcher != nil && MainTemplateLoader.fsys == nil && Config.BoolDefault("watch.templates", true) {
 		MainWatcher.Listen(MainTemplateLoader, MainTemplateLoader.paths...)
 	} else {
+		MainTemplateLoader.checkModTimes = DevMode && MainTemplateLoader.fsys == nil
 		MainTemplateLoader.Refresh()
 	}
 
@@ -1,10 +1,12 @@
 package revel
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -86,6 +88,28 @@ func TestFakeServer(t *testing.T) {
 	resp.Body = nil
 }
 
+func TestLifecycleEvents(t *testing.T) {
+	startFakeBookingApp()
+	defer func(subscribers map[LifecycleEvent][]func(*Controller)) {
+		lifecycleSubscribers = subscribers
+	}(lifecycleSubscribers)
+	lifecycleSubscribers = map[LifecycleEvent][]func(*Controller){}
+
+	var fired []string
+	for _, event := range []LifecycleEvent{RequestStart, AfterBind, BeforeRender, RequestEnd} {
+		event := event
+		OnLifecycleEvent(event, func(c *Controller) {
+			fired = append(fired, fmt.Sprintf("%d %s", event, c.Action))
+		})
+	}
+
+	handle(httptest.NewRecorder(), showRequest)
+	expected := []string{"0 ", "1 Hotels.Show", "2 Hotels.Show", "3 Hotels.Show"}
+	if !reflect.DeepEqual(fired, expected) {
+		t.Errorf("Expected events %v, got %v", expected, fired)
+	}
+}
+
 func getFileSize(t *testing.T, name string) int64 {
 	fi, err := os.Stat(name)
 	if err != nil {
@@ -101,3 +125,98 @@ var (
 	jsonRequest, _      = http.NewRequest("GET", "/hotels/3/booking", nil)
 	plaintextRequest, _ = http.NewRequest("GET", "/hotels", nil)
 )
+
+func TestDefaultHeaders(t *testing.T) {
+	startFakeBookingApp()
+	defer func(headers http.Header, filters []Filter) {
+		DefaultHeaders, Filters = headers, filters
+	}(DefaultHeaders, Filters)
+	DefaultHeaders = http.Header{
+		"X-Frame-Options":        {"SAMEORIGIN"},
+		"X-Content-Type-Options": {"nosniff"},
+	}
+
+	resp := httptest.NewRecorder()
+	handle(resp, showRequest)
+	for name, values := range DefaultHeaders {
+		if !reflect.DeepEqual(resp.Header()[name], values) {
+			t.Errorf("Expected %s: %v, got %v", name, values, resp.Header()[name])
+		}
+	}
+
+	// A filter or result may override them.
+	Filters = []Filter{func(c *Controller, _ []Filter) {
+		c.Response.Out.Header().Set("X-Frame-Options", "DENY")
+		c.Result = c.RenderText("framed")
+	}}
+	resp = httptest.NewRecorder()
+	handle(resp, showRequest)
+	if resp.Header().Get("X-Frame-Options") != "DENY" || resp.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Expected the overridden header, got %v", resp.Header())
+	}
+	if DefaultHeaders.Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Errorf("Expected the defaults to be unchanged, got %v", DefaultHeaders)
+	}
+}
+
+func TestControllerDefer(t *testing.T) {
+	startFakeBookingApp()
+	defer func(subscribers map[LifecycleEvent][]func(*Controller)) {
+		lifecycleSubscribers = subscribers
+	}(lifecycleSubscribers)
+
+	for _, panics := range []bool{false, true} {
+		lifecycleSubscribers = map[LifecycleEvent][]func(*Controller){}
+		var (
+			resp  = httptest.NewRecorder()
+			calls []string
+		)
+		OnLifecycleEvent(BeforeRender, func(c *Controller) {
+			for _, name := range []string{"first", "second"} {
+				name := name
+				c.Defer(func() {
+					calls = append(calls, fmt.Sprintf("%s flushed=%v written=%v", name, resp.Flushed, resp.Body.Len() > 0))
+				})
+			}
+			if panics {
+				panic("render failed")
+			}
+		})
+		OnLifecycleEvent(RequestEnd, func(c *Controller) {
+			calls = append(calls, "end")
+		})
+
+		func() {
+			defer func() { recover() }()
+			handle(resp, showRequest)
+		}()
+
+		written := !panics
+		expected := []string{
+			fmt.Sprintf("second flushed=true written=%v", written),
+			fmt.Sprintf("first flushed=true written=%v", written),
+			"end",
+		}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("panics %v: expected %v, got %v", panics, expected, calls)
+		}
+	}
+}
+
+// Responses are only flushed early for Defer, so that the rest keep their
+// Content-Length.
+func TestContentLength(t *testing.T) {
+	startFakeBookingApp()
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/hotels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ContentLength != int64(len("Hello, World!")) || len(resp.TransferEncoding) > 0 {
+		t.Errorf("Expected a Content-Length of %d, got %d (%v)",
+			len("Hello, World!"), resp.ContentLength, resp.TransferEncoding)
+	}
+}
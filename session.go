@@ -10,20 +10,49 @@ import (
 	"time"
 )
 
-// A signed cookie (and thus limited to 4kb in size).
+// A signed cookie (and thus limited to 4kb in size), unless SessionStorage
+// keeps sessions on the server.
 // Restriction: Keys may not have a colon in them.
 type Session map[string]string
 
 const (
 	SESSION_ID_KEY = "_ID"
 	TS_KEY         = "_TS"
+	ISSUED_KEY     = "_IS" // When the session began, if "session.lifetime" is set.
+	SEEN_KEY       = "_LS" // When the session was last used, if "session.idletimeout" is set.
 )
 
 var expireAfterDuration time.Duration
 
+// The longest that a session may last, and that it may go unused, enforced on
+// the server whatever the client does with the cookie.  Set by
+// "session.lifetime" and "session.idletimeout" in app.conf (e.g. "12h" and
+// "30m"); 0 (the default) for no limit.  An expired session is cleared, as if
+// the user had logged out.
+var (
+	sessionLifetime    time.Duration
+	sessionIdleTimeout time.Duration
+)
+
+// encryptSession is set by "session.encrypt" in app.conf, to encrypt the
+// session cookie rather than only signing it, so that the client can not read
+// it.  Signed cookies are still accepted, so that it may be turned on without
+// logging everyone out.
+var encryptSession bool
+
+// encryptedCookiePrefix marks an encrypted session cookie.  (The signature of
+// a signed cookie is hex, so it can not be mistaken for one.)
+const encryptedCookiePrefix = "enc."
+
 func init() {
 	// Set expireAfterDuration, default to 30 days if no value in config
 	OnAppStart(func() {
+		encryptSession = Config.BoolDefault("session.encrypt", false)
+		if encryptSession && len(secretKey) == 0 {
+			ERROR.Println("session.encrypt requires app.secret; the session will not be encrypted")
+			encryptSession = false
+		}
+
 		var err error
 		if expiresString, ok := Config.String("session.expires"); !ok {
 			expireAfterDuration = 30 * 24 * time.Hour
@@ -32,6 +61,13 @@ func init() {
 		} else if expireAfterDuration, err = time.ParseDuration(expiresString); err != nil {
 			panic(fmt.Errorf("session.expires invalid: %s", err))
 		}
+
+		if sessionLifetime, err = time.ParseDuration(Config.StringDefault("session.lifetime", "0")); err != nil {
+			panic(fmt.Errorf("session.lifetime invalid: %s", err))
+		}
+		if sessionIdleTimeout, err = time.ParseDuration(Config.StringDefault("session.idletimeout", "0")); err != nil {
+			panic(fmt.Errorf("session.idletimeout invalid: %s", err))
+		}
 	})
 }
 
@@ -57,11 +93,18 @@ func getSessionExpiration() time.Time {
 	return time.Now().Add(expireAfterDuration)
 }
 
-// Returns an http.Cookie containing the signed session.
+// Returns an http.Cookie containing the signed (or encrypted) session.
 func (s Session) cookie() *http.Cookie {
 	var sessionValue string
 	ts := getSessionExpiration()
 	s[TS_KEY] = getSessionExpirationCookie(ts)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if _, ok := s[ISSUED_KEY]; !ok && sessionLifetime > 0 {
+		s[ISSUED_KEY] = now
+	}
+	if sessionIdleTimeout > 0 {
+		s[SEEN_KEY] = now
+	}
 	for key, value := range s {
 		if strings.ContainsAny(key, ":\x00") {
 			panic("Session keys may not have colons or null bytes")
@@ -73,12 +116,21 @@ func (s Session) cookie() *http.Cookie {
 	}
 
 	sessionData := url.QueryEscape(sessionValue)
+	value := Sign(sessionData) + "-" + sessionData
+	if encryptSession {
+		if encrypted, err := Encrypt(sessionData); err != nil {
+			ERROR.Println("Failed to encrypt session:", err)
+		} else {
+			value = encryptedCookiePrefix + encrypted
+		}
+	}
 	return &http.Cookie{
 		Name:     CookiePrefix + "_SESSION",
-		Value:    Sign(sessionData) + "-" + sessionData,
+		Value:    value,
 		Path:     "/",
 		HttpOnly: CookieHttpOnly,
 		Secure:   CookieSecure,
+		SameSite: CookieSameSite,
 		Expires:  ts.UTC(),
 	}
 }
@@ -94,21 +146,46 @@ func sessionTimeoutExpiredOrMissing(session Session) bool {
 	return false
 }
 
+// sessionLimitExceeded returns true if the session has outlived
+// sessionLifetime, or gone unused for longer than sessionIdleTimeout.
+// (Sessions from before a limit was set have no time for it, and are allowed.)
+func sessionLimitExceeded(session Session) bool {
+	now := time.Now()
+	exceeded := func(key string, limit time.Duration) bool {
+		if limit <= 0 {
+			return false
+		}
+		ts, err := strconv.ParseInt(session[key], 10, 64)
+		return err == nil && now.Sub(time.Unix(ts, 0)) > limit
+	}
+	return exceeded(ISSUED_KEY, sessionLifetime) || exceeded(SEEN_KEY, sessionIdleTimeout)
+}
+
 // Returns a Session pulled from signed cookie.
 func getSessionFromCookie(cookie *http.Cookie) Session {
 	session := make(Session)
 
-	// Separate the data from the signature.
-	hyphen := strings.Index(cookie.Value, "-")
-	if hyphen == -1 || hyphen >= len(cookie.Value)-1 {
-		return session
-	}
-	sig, data := cookie.Value[:hyphen], cookie.Value[hyphen+1:]
+	var data string
+	if strings.HasPrefix(cookie.Value, encryptedCookiePrefix) {
+		var ok bool
+		if data, ok = Decrypt(cookie.Value[len(encryptedCookiePrefix):]); !ok {
+			INFO.Println("Session cookie decryption failed")
+			return session
+		}
+	} else {
+		// Separate the data from the signature.
+		hyphen := strings.Index(cookie.Value, "-")
+		if hyphen == -1 || hyphen >= len(cookie.Value)-1 {
+			return session
+		}
+		var sig string
+		sig, data = cookie.Value[:hyphen], cookie.Value[hyphen+1:]
 
-	// Verify the signature.
-	if !Verify(data, sig) {
-		INFO.Println("Session cookie signature failed")
-		return session
+		// Verify the signature.
+		if !Verify(data, sig) {
+			INFO.Println("Session cookie signature failed")
+			return session
+		}
 	}
 
 	ParseKeyValueCookie(data, func(key, val string) {
@@ -117,12 +194,20 @@ func getSessionFromCookie(cookie *http.Cookie) Session {
 
 	if sessionTimeoutExpiredOrMissing(session) {
 		session = make(Session)
+	} else if sessionLimitExceeded(session) {
+		INFO.Println("Session expired")
+		session = make(Session)
 	}
 
 	return session
 }
 
 func SessionFilter(c *Controller, fc []Filter) {
+	if SessionStorage != nil {
+		storedSessionFilter(c, fc)
+		return
+	}
+
 	c.Session = restoreSession(c.Request.Request)
 	// Make session vars available in templates as {{.session.xyz}}
 	c.RenderArgs["session"] = c.Session
@@ -130,12 +215,12 @@ func SessionFilter(c *Controller, fc []Filter) {
 	fc[0](c, fc[1:])
 
 	// Store the session (and sign it).
-	c.SetCookie(c.Session.cookie())
+	setChunkedCookie(c, c.Session.cookie())
 }
 
 func restoreSession(req *http.Request) Session {
 	session := make(Session)
-	cookie, err := req.Cookie(CookiePrefix + "_SESSION")
+	cookie, err := readChunkedCookie(req, CookiePrefix+"_SESSION")
 	if err != nil {
 		return session
 	}
@@ -0,0 +1,177 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoredSession(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { SessionStorage = nil }()
+	SessionStorage = NewMemorySessionStore()
+
+	// request runs the SessionFilter around the action, sending the cookies,
+	// and returns the cookies that were set.
+	request := func(cookies []*http.Cookie, action func(c *Controller)) []*http.Cookie {
+		req, _ := http.NewRequest("GET", "/", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) { action(c) }})
+		return (&http.Response{Header: resp.Header()}).Cookies()
+	}
+
+	cookies := request(nil, func(c *Controller) { c.Session["user"] = "bob" })
+	if len(cookies) != 1 || strings.Contains(cookies[0].Value, "bob") {
+		t.Fatalf("Expected a cookie holding only the session id, got %v", cookies)
+	}
+	request(cookies, func(c *Controller) {
+		if c.Session["user"] != "bob" {
+			t.Errorf("Expected the stored session, got %v", c.Session)
+		}
+	})
+
+	// Concurrent requests for the same session keep each other's changes.
+	request(cookies, func(c *Controller) {
+		request(cookies, func(c *Controller) { c.Session["cart"] = "3" })
+		c.Session["lang"] = "fr"
+		delete(c.Session, "user")
+	})
+	request(cookies, func(c *Controller) {
+		if c.Session["cart"] != "3" || c.Session["lang"] != "fr" || c.Session["user"] != "" {
+			t.Errorf("Expected both requests' changes, got %v", c.Session)
+		}
+	})
+
+	// Clearing the session deletes it from the store.
+	var id string
+	cookies = request(cookies, func(c *Controller) {
+		id = c.Session.Id()
+		for key := range c.Session {
+			delete(c.Session, key)
+		}
+	})
+	if session, _ := SessionStorage.Get(id); len(session) != 0 {
+		t.Errorf("Expected the cleared session to be deleted, got %v", session)
+	}
+	request(cookies, func(c *Controller) {
+		if c.Session.Id() == id || c.Session["cart"] != "" {
+			t.Errorf("Expected a new session, got %v", c.Session)
+		}
+	})
+}
+
+func TestSessionCookieSecretRotation(t *testing.T) {
+	startFakeBookingApp()
+	defer func(key []byte) { secretKey, previousSecretKeys = key, nil }(secretKey)
+
+	secretKey = []byte("old secret")
+	cookie := Session{"user": "bob"}.cookie()
+
+	// After rotation, cookies signed with the previous secret are accepted.
+	secretKey, previousSecretKeys = []byte("new secret"), [][]byte{[]byte("old secret")}
+	if session := getSessionFromCookie(cookie); session["user"] != "bob" {
+		t.Errorf("Expected the cookie signed by the previous secret to be accepted, got %v", session)
+	}
+
+	// Once the previous secret is retired, they are not.
+	previousSecretKeys = nil
+	if session := getSessionFromCookie(cookie); len(session) != 0 {
+		t.Errorf("Expected the cookie signed by an unknown secret to be rejected, got %v", session)
+	}
+}
+
+func TestEncryptedSessionCookie(t *testing.T) {
+	startFakeBookingApp()
+	defer func(key []byte) { secretKey, previousSecretKeys, encryptSession = key, nil, false }(secretKey)
+
+	secretKey, encryptSession = []byte("old secret"), true
+	cookie := Session{"user": "bob"}.cookie()
+	if !strings.HasPrefix(cookie.Value, encryptedCookiePrefix) || strings.Contains(cookie.Value, "bob") {
+		t.Fatalf("Expected an encrypted cookie, got %s", cookie.Value)
+	}
+	if session := getSessionFromCookie(cookie); session["user"] != "bob" {
+		t.Errorf("Expected the encrypted session, got %v", session)
+	}
+
+	// A signed cookie is still accepted after turning encryption on.
+	encryptSession = false
+	signed := Session{"user": "alice"}.cookie()
+	encryptSession = true
+	if session := getSessionFromCookie(signed); session["user"] != "alice" {
+		t.Errorf("Expected the signed session, got %v", session)
+	}
+
+	// Rotation applies to encrypted cookies as well.
+	secretKey, previousSecretKeys = []byte("new secret"), [][]byte{[]byte("old secret")}
+	if session := getSessionFromCookie(cookie); session["user"] != "bob" {
+		t.Errorf("Expected the cookie encrypted by the previous secret, got %v", session)
+	}
+	previousSecretKeys = nil
+	if session := getSessionFromCookie(cookie); len(session) != 0 {
+		t.Errorf("Expected the cookie encrypted by an unknown secret to be rejected, got %v", session)
+	}
+
+	// Altered cookies are rejected.
+	tampered := *cookie
+	tampered.Value = cookie.Value[:len(cookie.Value)-2] + "AA"
+	secretKey = []byte("old secret")
+	if session := getSessionFromCookie(&tampered); len(session) != 0 {
+		t.Errorf("Expected the altered cookie to be rejected, got %v", session)
+	}
+}
+
+func TestSessionLifetimeAndIdleTimeout(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { sessionLifetime, sessionIdleTimeout = 0, 0 }()
+	sessionLifetime, sessionIdleTimeout = 12*time.Hour, 30*time.Minute
+
+	ago := func(d time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(-d).Unix(), 10)
+	}
+	for _, test := range []struct {
+		issued, seen string
+		valid        bool
+	}{
+		{ago(time.Hour), ago(time.Minute), true},
+		{ago(13 * time.Hour), ago(time.Minute), false},
+		{ago(time.Hour), ago(time.Hour), false},
+		{"", "", true}, // From before the limits were set.
+	} {
+		session := Session{"user": "bob"}
+		cookie := session.cookie()
+		session = getSessionFromCookie(cookie)
+		if session[ISSUED_KEY] == "" || session[SEEN_KEY] == "" {
+			t.Fatalf("Expected the session to carry its times, got %v", session)
+		}
+
+		// Forge the times, as if the session had been issued and used earlier.
+		session[ISSUED_KEY], session[SEEN_KEY] = test.issued, test.seen
+		if test.issued == "" {
+			delete(session, ISSUED_KEY)
+			delete(session, SEEN_KEY)
+		}
+		sessionLifetime, sessionIdleTimeout = 0, 0
+		cookie = session.cookie()
+		sessionLifetime, sessionIdleTimeout = 12*time.Hour, 30*time.Minute
+
+		restored := getSessionFromCookie(cookie)
+		if (restored["user"] == "bob") != test.valid {
+			t.Errorf("issued %s, seen %s: expected valid %v, got %v", test.issued, test.seen, test.valid, restored)
+		}
+	}
+
+	// The issue time is kept, and the last-seen time renewed, as the session is used.
+	issued, seen := ago(time.Hour), ago(10*time.Minute)
+	session := Session{"user": "bob", ISSUED_KEY: issued, SEEN_KEY: seen}
+	restored := getSessionFromCookie(session.cookie())
+	if restored[ISSUED_KEY] != issued || restored[SEEN_KEY] == seen {
+		t.Errorf("Expected the issue time kept and the last-seen time renewed, got %v", restored)
+	}
+}
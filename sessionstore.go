@@ -0,0 +1,196 @@
+package revel
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// A SessionStore keeps sessions on the server, so that the session cookie
+// holds only the signed session id (and expiration).  This lifts the 4kb limit
+// of the cookie, and hides the session data from the client.
+//
+// Get returns an empty Session for an unknown or expired id.  The Session
+// passed to Save, and returned by Get, must not be retained or shared.
+//
+// The cache module provides a SessionStore backed by Redis or memcached.
+type SessionStore interface {
+	Get(id string) (Session, error)
+	Save(id string, session Session) error
+	Delete(id string) error
+}
+
+// SessionStorage is the store that SessionFilter reads and writes sessions
+// through.  If nil (the default), sessions are kept in the cookie itself.
+// Set "session.store = memory" in app.conf to keep them in memory.
+var SessionStorage SessionStore
+
+func init() {
+	OnAppStart(func() {
+		switch store := Config.StringDefault("session.store", "cookie"); store {
+		case "memory":
+			SessionStorage = NewMemorySessionStore()
+		case "cookie", "cache":
+			// The cache module installs its store itself.
+		default:
+			ERROR.Println("Unknown session.store:", store)
+		}
+	})
+}
+
+// sessionLocks serialize the saving of a session by concurrent requests, so
+// that each one's changes are merged into the other's.  Sessions are spread
+// across a fixed number of locks by their id.
+var sessionLocks [64]sync.Mutex
+
+func sessionLock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &sessionLocks[h.Sum32()%uint32(len(sessionLocks))]
+}
+
+// storedSessionFilter restores the session from SessionStorage, by the id in
+// the session cookie, and saves the changes made by the request.
+func storedSessionFilter(c *Controller, fc []Filter) {
+	session := make(Session)
+	restored := restoreSession(c.Request.Request)
+	if id, ok := restored[SESSION_ID_KEY]; ok {
+		if stored, err := SessionStorage.Get(id); err != nil {
+			ERROR.Println("Failed to load session:", err)
+		} else {
+			session = stored
+		}
+		session[SESSION_ID_KEY] = id
+	}
+	original := copySession(session)
+	c.Session = session
+	c.RenderArgs["session"] = c.Session
+
+	fc[0](c, fc[1:])
+
+	// A session cleared of its id (e.g. on logout) replaces the stored one.
+	id := c.Session.Id()
+	if oldId, ok := original[SESSION_ID_KEY]; ok && oldId != id {
+		if err := SessionStorage.Delete(oldId); err != nil {
+			ERROR.Println("Failed to delete session:", err)
+		}
+		original = make(Session)
+	}
+	if err := saveSessionChanges(id, original, c.Session); err != nil {
+		ERROR.Println("Failed to save session:", err)
+	}
+
+	// The cookie carries the time the session began, for "session.lifetime".
+	cookieSession := Session{SESSION_ID_KEY: id}
+	if issued, ok := restored[ISSUED_KEY]; ok && restored[SESSION_ID_KEY] == id {
+		cookieSession[ISSUED_KEY] = issued
+	}
+	setChunkedCookie(c, cookieSession.cookie())
+}
+
+// saveSessionChanges applies the keys the request set or deleted to the
+// stored session, rather than overwriting it, so that concurrent requests for
+// the same session do not lose each other's changes.
+func saveSessionChanges(id string, original, session Session) error {
+	// An unchanged session is saved anyway, to extend its expiration, unless
+	// it is empty (e.g. for an anonymous visitor).
+	changed, stored := false, false
+	for key, value := range session {
+		changed = changed || !isSessionMetaKey(key) && original[key] != value
+	}
+	for key := range original {
+		_, kept := session[key]
+		changed = changed || !isSessionMetaKey(key) && !kept
+		stored = stored || !isSessionMetaKey(key)
+	}
+	if !changed && !stored {
+		return nil
+	}
+
+	lock := sessionLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	latest, err := SessionStorage.Get(id)
+	if err != nil {
+		return err
+	}
+	for key, value := range session {
+		if original[key] != value {
+			latest[key] = value
+		}
+	}
+	for key := range original {
+		if _, ok := session[key]; !ok {
+			delete(latest, key)
+		}
+	}
+	delete(latest, TS_KEY)
+	delete(latest, SESSION_ID_KEY)
+	if len(latest) == 0 {
+		return SessionStorage.Delete(id)
+	}
+	latest[SESSION_ID_KEY] = id
+	latest[TS_KEY] = getSessionExpirationCookie(getSessionExpiration())
+	return SessionStorage.Save(id, latest)
+}
+
+// isSessionMetaKey returns true for the keys that Revel maintains itself.
+func isSessionMetaKey(key string) bool {
+	return key == SESSION_ID_KEY || key == TS_KEY || key == ISSUED_KEY || key == SEEN_KEY
+}
+
+func copySession(session Session) Session {
+	c := make(Session, len(session))
+	for key, value := range session {
+		c[key] = value
+	}
+	return c
+}
+
+// MemorySessionStore keeps sessions in memory.  They are lost when the
+// application restarts, and are not shared between processes.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+	swept    time.Time
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]Session), swept: time.Now()}
+}
+
+func (s *MemorySessionStore) Get(id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || sessionTimeoutExpiredOrMissing(session) {
+		delete(s.sessions, id)
+		return make(Session), nil
+	}
+	return copySession(session), nil
+}
+
+func (s *MemorySessionStore) Save(id string, session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = copySession(session)
+
+	// Sessions that are never requested again would otherwise be kept forever.
+	if time.Since(s.swept) > time.Minute {
+		for id, session := range s.sessions {
+			if sessionTimeoutExpiredOrMissing(session) {
+				delete(s.sessions, id)
+			}
+		}
+		s.swept = time.Now()
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
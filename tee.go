@@ -0,0 +1,79 @@
+package revel
+
+import (
+	"errors"
+)
+
+// A ResponseTee receives a copy of a streamed response as it is sent to the
+// client, e.g. to populate a cache entry without generating it a second time.
+type ResponseTee interface {
+	// Write receives the next part of the response.
+	Write(b []byte) (int, error)
+
+	// Close is called once the response is complete.  If err is non-nil, the
+	// tee did not receive the whole response (because it fell behind, or the
+	// stream failed), and what it received must be discarded.
+	Close(err error)
+}
+
+// The number of writes that may be queued for a slow tee before it is dropped.
+const teeBufferSize = 64
+
+var errTeeDropped = errors.New("revel: response tee fell behind and was dropped")
+
+// asyncTee passes writes to a ResponseTee from a separate goroutine, so that a
+// slow tee does not hold up the client.  If the tee falls more than
+// teeBufferSize writes behind, it is dropped: it receives no more writes, and
+// is closed with an error.
+type asyncTee struct {
+	tee     ResponseTee
+	queue   chan []byte
+	dropped bool
+	err     error // The error to close the tee with; set before queue is closed.
+}
+
+func newAsyncTee(tee ResponseTee) *asyncTee {
+	t := &asyncTee{
+		tee:   tee,
+		queue: make(chan []byte, teeBufferSize),
+	}
+	go t.run()
+	return t
+}
+
+func (t *asyncTee) run() {
+	var err error
+	for b := range t.queue {
+		if err == nil {
+			_, err = t.tee.Write(b)
+		}
+	}
+	if err == nil {
+		err = t.err
+	}
+	t.tee.Close(err)
+}
+
+// Write queues a copy of b for the tee.  It never blocks, and never fails.
+func (t *asyncTee) Write(b []byte) (int, error) {
+	if t.dropped {
+		return len(b), nil
+	}
+	select {
+	case t.queue <- append([]byte(nil), b...):
+	default:
+		t.dropped = true
+		WARN.Println("Response tee fell behind; dropping it")
+	}
+	return len(b), nil
+}
+
+// Close closes the tee once it has received the queued writes, without
+// waiting for them.  If err is non-nil, the response was incomplete.
+func (t *asyncTee) Close(err error) {
+	if err == nil && t.dropped {
+		err = errTeeDropped
+	}
+	t.err = err
+	close(t.queue)
+}
@@ -0,0 +1,69 @@
+package revel
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+// bufferTee is a ResponseTee that reports its contents once closed.
+type bufferTee struct {
+	bytes.Buffer
+	block  chan struct{} // If non-nil, writes wait until it is closed.
+	closed chan error
+}
+
+func newBufferTee() *bufferTee {
+	return &bufferTee{closed: make(chan error, 1)}
+}
+
+func (t *bufferTee) Write(b []byte) (int, error) {
+	if t.block != nil {
+		<-t.block
+	}
+	return t.Buffer.Write(b)
+}
+
+func (t *bufferTee) Close(err error) {
+	t.closed <- err
+}
+
+func TestRenderJsonObjectStreamTee(t *testing.T) {
+	ch := make(chan KV, 2)
+	ch <- KV{"a", 1}
+	ch <- KV{"b", 2}
+	close(ch)
+
+	tee := newBufferTee()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonObjectStreamTee(ch, tee).Apply(c.Request, c.Response)
+	if err := <-tee.closed; err != nil {
+		t.Fatalf("Expected the tee to complete, got %s", err)
+	}
+	if resp.Body.String() != `{"a":1,"b":2}` || tee.String() != resp.Body.String() {
+		t.Errorf("Expected the tee to receive the response, got %q and %q", resp.Body, tee)
+	}
+}
+
+func TestRenderJsonObjectStreamSlowTee(t *testing.T) {
+	ch := make(chan KV, teeBufferSize*2)
+	for i := 0; i < teeBufferSize*2; i++ {
+		ch <- KV{"k", i}
+	}
+	close(ch)
+
+	tee := newBufferTee()
+	tee.block = make(chan struct{})
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(jsonRequest), NewResponse(resp))
+	c.RenderJsonObjectStreamTee(ch, tee).Apply(c.Request, c.Response)
+	close(tee.block)
+
+	if err := <-tee.closed; err != errTeeDropped {
+		t.Errorf("Expected the slow tee to be dropped, got %v", err)
+	}
+	if resp.Body.Len() == 0 {
+		t.Errorf("Expected the client to receive the response")
+	}
+}
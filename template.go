@@ -5,6 +5,7 @@ import (
 	"html"
 	"html/template"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,14 +23,31 @@ var ERROR_CLASS = "hasError"
 // This object handles loading and parsing of templates.
 // Everything below the application's views directory is treated as a template.
 type TemplateLoader struct {
+	// Guards the templates and what was loaded with them (templateSet through
+	// modTimes), which Refresh replaces.
+	mu sync.RWMutex
 	// This is the set of all templates under views
 	templateSet *template.Template
 	// If an error was encountered parsing the templates, it is stored here.
 	compileError *Error
-	// Paths to search for templates, in priority order.
-	paths []string
+	// Paths to search for templates, in priority order: first fsPaths within
+	// fsys (if set), then paths on disk.
+	paths   []string
+	fsys    fs.FS
+	fsPaths []string
 	// Map from template name to the path from whence it was loaded.
 	templatePaths map[string]string
+	// The names of the templates loaded from fsys, rather than from disk.
+	templateFS map[string]bool
+
+	// The templates returned by Template, by the name requested.
+	cache   map[string]Template
+	cacheMu sync.RWMutex
+
+	// If set, Template reloads the templates when one of their files changes,
+	// for dev mode without a watcher.
+	checkModTimes bool
+	modTimes      map[string]time.Time // Modification time of each file on disk, by path.
 }
 
 type Template interface {
@@ -37,6 +56,10 @@ type Template interface {
 	Render(wr io.Writer, arg interface{}) error
 }
 
+// LayoutContentRenderArg is the render arg holding the output of the template
+// wrapped by a layout.
+const LayoutContentRenderArg = "layoutContent"
+
 var invalidSlugPattern = regexp.MustCompile(`[^a-z0-9 _-]`)
 var whiteSpacePattern = regexp.MustCompile(`\s+`)
 
@@ -58,6 +81,11 @@ var (
 			return template.HTML("")
 		},
 		"field": NewField,
+		// The output of the template wrapped by a layout; see RenderWithLayout.
+		"content": func(renderArgs map[string]interface{}) template.HTML {
+			content, _ := renderArgs[LayoutContentRenderArg].(template.HTML)
+			return content
+		},
 		"option": func(f *Field, val, label string) template.HTML {
 			selected := ""
 			if f.Flash() == val {
@@ -163,14 +191,91 @@ func NewTemplateLoader(paths []string) *TemplateLoader {
 	return loader
 }
 
+// NewTemplateLoaderFS returns a loader of the templates under the given
+// fsPaths of fsys (e.g. those embedded in the binary), and then of those under
+// the given paths on disk.  Of templates of the same name, the first found is
+// used, so that those in fsys take precedence.  Templates are named by their
+// path relative to the path they are under, as on disk: "Hotels/Show.html" is
+// "views/Hotels/Show.html" for a path of "views", or "Hotels/Show.html" in
+// fsys for a path of ".".  The templates in fsys use the app's
+// template.delimiters.
+func NewTemplateLoaderFS(fsys fs.FS, fsPaths, paths []string) *TemplateLoader {
+	return &TemplateLoader{
+		paths:   paths,
+		fsys:    fsys,
+		fsPaths: fsPaths,
+	}
+}
+
+// walkTemplates walks the file tree under root, on disk, or in fsys if it is
+// not nil.
+func walkTemplates(fsys fs.FS, root string, walkFn filepath.WalkFunc) error {
+	if fsys == nil {
+		return filepath.Walk(root, walkFn)
+	}
+	return fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return walkFn(path, nil, err)
+		}
+		info, err := d.Info()
+		return walkFn(path, info, err)
+	})
+}
+
+// readTemplateFile reads the named file, on disk, or in fsys if it is not nil.
+func readTemplateFile(fsys fs.FS, path string) ([]byte, error) {
+	if fsys == nil {
+		return ioutil.ReadFile(path)
+	}
+	return fs.ReadFile(fsys, path)
+}
+
+// templateRoot is a path under which templates are loaded, on disk, or in fsys
+// if it is not nil.
+type templateRoot struct {
+	fsys fs.FS
+	path string
+}
+
+// roots returns the paths to load templates from, in priority order.
+func (loader *TemplateLoader) roots() []templateRoot {
+	var roots []templateRoot
+	if loader.fsys != nil {
+		for _, path := range loader.fsPaths {
+			roots = append(roots, templateRoot{loader.fsys, path})
+		}
+	}
+	for _, path := range loader.paths {
+		roots = append(roots, templateRoot{nil, path})
+	}
+	return roots
+}
+
 // This scans the views directory and parses all templates as Go Templates.
 // If a template fails to parse, the error is set on the loader.
 // (It's awkward to refresh a single Go Template)
+//
+// The templates are parsed once, and then served from memory until Refresh is
+// called again: by the watcher when a file changes in dev mode, or by the
+// application to force them to be reloaded.
 func (loader *TemplateLoader) Refresh() *Error {
-	TRACE.Printf("Refreshing templates from %s", loader.paths)
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+	return loader.refresh()
+}
+
+// refresh is Refresh, with mu held.
+func (loader *TemplateLoader) refresh() *Error {
+	TRACE.Printf("Refreshing templates from %s (and %s embedded)", loader.paths, loader.fsPaths)
+
+	loader.cacheMu.Lock()
+	loader.cache = map[string]Template{}
+	loader.cacheMu.Unlock()
 
 	loader.compileError = nil
 	loader.templatePaths = map[string]string{}
+	loader.templateFS = map[string]bool{}
+	loader.modTimes = map[string]time.Time{}
 
 	// Set the template delimiters for the project if present, then split into left
 	// and right delimiters around a space character
@@ -184,18 +289,20 @@ func (loader *TemplateLoader) Refresh() *Error {
 
 	// Walk through the template loader's paths and build up a template set.
 	var templateSet *template.Template = nil
-	for _, basePath := range loader.paths {
+	for _, root := range loader.roots() {
+		basePath := root.path
+		appViews := basePath == ViewsPath || root.fsys != nil
 		// Walk only returns an error if the template loader is completely unusable
 		// (namely, if one of the TemplateFuncs does not have an acceptable signature).
-		funcErr := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+		funcErr := walkTemplates(root.fsys, basePath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				ERROR.Println("error walking templates:", err)
 				return nil
 			}
 
-			// Walk into watchable directories
+			// Walk into watchable directories (and the root, e.g. ".")
 			if info.IsDir() {
-				if !loader.WatchDir(info) {
+				if path != basePath && !loader.WatchDir(info) {
 					return filepath.SkipDir
 				}
 				return nil
@@ -205,6 +312,9 @@ func (loader *TemplateLoader) Refresh() *Error {
 			if !loader.WatchFile(info.Name()) {
 				return nil
 			}
+			if root.fsys == nil {
+				loader.modTimes[path] = info.ModTime()
+			}
 
 			var fileStr string
 
@@ -221,10 +331,11 @@ func (loader *TemplateLoader) Refresh() *Error {
 					return nil
 				}
 				loader.templatePaths[templateName] = path
+				loader.templateFS[templateName] = root.fsys != nil
 
 				// Load the file if we haven't already
 				if fileStr == "" {
-					fileBytes, err := ioutil.ReadFile(path)
+					fileBytes, err := readTemplateFile(root.fsys, path)
 					if err != nil {
 						ERROR.Println("Failed reading file:", path)
 						return nil
@@ -249,7 +360,7 @@ func (loader *TemplateLoader) Refresh() *Error {
 						}()
 						templateSet = template.New(templateName).Funcs(TemplateFuncs)
 						// If alternate delimiters set for the project, change them for this set
-						if splitDelims != nil && basePath == ViewsPath {
+						if splitDelims != nil && appViews {
 							templateSet.Delims(splitDelims[0], splitDelims[1])
 						} else {
 							// Reset to default otherwise
@@ -263,7 +374,7 @@ func (loader *TemplateLoader) Refresh() *Error {
 					}
 
 				} else {
-					if splitDelims != nil && basePath == ViewsPath {
+					if splitDelims != nil && appViews {
 						templateSet.Delims(splitDelims[0], splitDelims[1])
 					} else {
 						templateSet.Delims("", "")
@@ -273,7 +384,10 @@ func (loader *TemplateLoader) Refresh() *Error {
 				return err
 			}
 
-			templateName := path[len(basePath)+1:]
+			templateName := path
+			if basePath != "." || root.fsys == nil {
+				templateName = path[len(basePath)+1:]
+			}
 
 			// Lower case the file name for case-insensitive matching
 			lowerCaseTemplateName := strings.ToLower(templateName)
@@ -309,6 +423,51 @@ func (loader *TemplateLoader) Refresh() *Error {
 	return loader.compileError
 }
 
+// ModTime returns the modification time of the file of the named template,
+// when it was loaded, or the zero time if there is no such template.
+func (loader *TemplateLoader) ModTime(name string) time.Time {
+	loader.mu.RLock()
+	defer loader.mu.RUnlock()
+	name = strings.ToLower(name)
+	path := loader.templatePaths[name]
+	if loader.templateFS[name] {
+		if info, err := fs.Stat(loader.fsys, path); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	return loader.modTimes[path]
+}
+
+// refreshIfModified refreshes the templates if one of their files changed
+// since they were loaded.  Of concurrent requests that find a change, only the
+// first refreshes them.
+func (loader *TemplateLoader) refreshIfModified() {
+	loader.mu.RLock()
+	modified := loader.modified()
+	loader.mu.RUnlock()
+	if !modified {
+		return
+	}
+
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+	if loader.modified() {
+		loader.refresh()
+	}
+}
+
+// modified returns true if a template file changed since it was loaded.
+// mu must be held.
+func (loader *TemplateLoader) modified() bool {
+	for path, modTime := range loader.modTimes {
+		if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
 func (loader *TemplateLoader) WatchDir(info os.FileInfo) bool {
 	// Watch all directories, except the ones starting with a dot.
 	return !strings.HasPrefix(info.Name(), ".")
@@ -345,7 +504,23 @@ func parseTemplateError(err error) (templateName string, line int, description s
 // An Error is returned if there was any problem with any of the templates.  (In
 // this case, if a template is returned, it may still be usable.)
 func (loader *TemplateLoader) Template(name string) (Template, error) {
+	if loader.checkModTimes {
+		loader.refreshIfModified()
+	}
+
+	loader.mu.RLock()
+	defer loader.mu.RUnlock()
+
+	// Templates are cached by the name requested, to skip the lookup below.
+	loader.cacheMu.RLock()
+	cached, ok := loader.cache[name]
+	loader.cacheMu.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
 	// Lower case the file name to support case-insensitive matching
+	requested := name
 	name = strings.ToLower(name)
 	// Look up and return the template.
 	tmpl := loader.templateSet.Lookup(name)
@@ -362,7 +537,13 @@ func (loader *TemplateLoader) Template(name string) (Template, error) {
 		return nil, fmt.Errorf("Template %s not found.", name)
 	}
 
-	return GoTemplate{tmpl, loader}, err
+	result := GoTemplate{tmpl, loader}
+	if err == nil {
+		loader.cacheMu.Lock()
+		loader.cache[requested] = result
+		loader.cacheMu.Unlock()
+	}
+	return result, err
 }
 
 // Adapter for Go Templates.
@@ -377,8 +558,18 @@ func (gotmpl GoTemplate) Render(wr io.Writer, arg interface{}) error {
 }
 
 func (gotmpl GoTemplate) Content() []string {
-	content, _ := ReadLines(gotmpl.loader.templatePaths[gotmpl.Name()])
-	return content
+	var fsys fs.FS
+	gotmpl.loader.mu.RLock()
+	path := gotmpl.loader.templatePaths[gotmpl.Name()]
+	if gotmpl.loader.templateFS[gotmpl.Name()] {
+		fsys = gotmpl.loader.fsys
+	}
+	gotmpl.loader.mu.RUnlock()
+	content, err := readTemplateFile(fsys, path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(content), "\n")
 }
 
 /////////////////////
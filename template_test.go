@@ -0,0 +1,160 @@
+package revel
+
+import (
+	"bytes"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func BenchmarkTemplateLookup(b *testing.B) {
+	startFakeBookingApp()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := MainTemplateLoader.Template("Hotels/Show.html"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTemplateReloadedInDevMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "Page.html")
+	ioutil.WriteFile(file, []byte("one"), 0644)
+
+	render := func(loader *TemplateLoader) string {
+		tmpl, err := loader.Template("Page.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		tmpl.Render(&b, nil)
+		return b.String()
+	}
+	devLoader, prodLoader := NewTemplateLoader([]string{dir}), NewTemplateLoader([]string{dir})
+	devLoader.checkModTimes = true
+	devLoader.Refresh()
+	prodLoader.Refresh()
+	if render(devLoader) != "one" || render(prodLoader) != "one" {
+		t.Fatal("Failed to render the template")
+	}
+
+	ioutil.WriteFile(file, []byte("two"), 0644)
+	os.Chtimes(file, time.Now(), time.Now().Add(time.Second))
+	if body := render(devLoader); body != "two" {
+		t.Errorf("Expected the changed template in dev mode, got %s", body)
+	}
+	if body := render(prodLoader); body != "one" {
+		t.Errorf("Expected the cached template in prod mode, got %s", body)
+	}
+	prodLoader.Refresh()
+	if body := render(prodLoader); body != "two" {
+		t.Errorf("Expected Refresh to reload the template, got %s", body)
+	}
+
+	// Concurrent requests that find a change each see the new template.
+	ioutil.WriteFile(file, []byte("three"), 0644)
+	os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second))
+	bodies := make(chan string)
+	for i := 0; i < 8; i++ {
+		go func() { bodies <- render(devLoader) }()
+	}
+	for i := 0; i < 8; i++ {
+		if body := <-bodies; body != "three" {
+			t.Errorf("Expected the changed template, got %s", body)
+		}
+	}
+}
+
+func TestTemplateLoaderFS(t *testing.T) {
+	files := map[string]string{
+		"Hotels/Show.html":  `{{template "header.html" .}}Hotel {{.id}}`,
+		"header.html":       "Header ",
+		"errors/404.html":   "Not found",
+		".hidden/skip.html": "{{",
+	}
+	dir, err := ioutil.TempDir("", "revel-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mapFS := fstest.MapFS{}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, "views", filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "views", name), []byte(content), 0644)
+		mapFS["views/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	viewsFS, _ := fs.Sub(mapFS, "views")
+
+	// The templates are named and rendered the same from each source.
+	for _, loader := range []*TemplateLoader{
+		NewTemplateLoader([]string{filepath.Join(dir, "views")}),
+		NewTemplateLoaderFS(mapFS, []string{"views"}, nil),
+		NewTemplateLoaderFS(viewsFS, []string{"."}, nil),
+	} {
+		if err := loader.Refresh(); err != nil {
+			t.Fatalf("Failed to load the templates (fs %v): %s", loader.fsys != nil, err)
+		}
+		for _, name := range []string{"Hotels/Show.html", "hotels/show.html"} {
+			tmpl, err := loader.Template(name)
+			if err != nil {
+				t.Errorf("Failed to find %s (fs %v): %s", name, loader.fsys != nil, err)
+				continue
+			}
+			var b bytes.Buffer
+			tmpl.Render(&b, map[string]interface{}{"id": 3})
+			if b.String() != "Header Hotel 3" {
+				t.Errorf("Unexpected output of %s (fs %v): %q", name, loader.fsys != nil, b.String())
+			}
+			if content := tmpl.Content(); len(content) != 1 || content[0] != files["Hotels/Show.html"] {
+				t.Errorf("Unexpected content of %s: %q", name, content)
+			}
+		}
+		if _, err := loader.Template("errors/404.html"); err != nil {
+			t.Errorf("Failed to find errors/404.html (fs %v): %s", loader.fsys != nil, err)
+		}
+	}
+}
+
+// With TemplateFS, the app's templates are embedded, and Revel's (e.g. its
+// error pages) are still found on disk.
+func TestTemplateFSErrorPage(t *testing.T) {
+	startFakeBookingApp()
+	defer func(fsys fs.FS, loader *TemplateLoader, paths []string) {
+		TemplateFS, MainTemplateLoader, TemplatePaths = fsys, loader, paths
+	}(TemplateFS, MainTemplateLoader, TemplatePaths)
+	// (Leaving out the modules, as the fake app does.)
+	TemplatePaths = []string{ViewsPath, filepath.Join(RevelPath, "templates")}
+	TemplateFS = fstest.MapFS{
+		"Hotels/Show.html": {Data: []byte("Embedded hotel {{.hotel.HotelId}}")},
+	}
+	MainTemplateLoader = newMainTemplateLoader()
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	handle(resp, showRequest)
+	if resp.Body.String() != "Embedded hotel 3" {
+		t.Errorf("Expected the embedded template, got %q", resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.NotFound("No such hotel").Apply(c.Request, c.Response)
+	if resp.Code != http.StatusNotFound || !strings.Contains(resp.Body.String(), "<title>Not found</title>") ||
+		!strings.Contains(resp.Body.String(), "No such hotel") {
+		t.Errorf("Expected Revel's error page, got %d:\n%s", resp.Code, resp.Body)
+	}
+}
@@ -0,0 +1,211 @@
+package revel
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrUploadTooLarge is returned for the files of multipart forms larger than
+// "http.maxuploadsize", in bytes, if set.  (By default there is no limit.)
+var ErrUploadTooLarge = errors.New("revel/upload: upload too large")
+
+// The maximum size of a multipart request body, or 0 for no limit.
+var maxUploadSize int64
+
+func init() {
+	OnAppStart(func() {
+		maxUploadSize = int64(Config.IntDefault("http.maxuploadsize", 0))
+	})
+}
+
+// File opens the first file uploaded under the given name.  It returns
+// http.ErrMissingFile if there is none, or ErrUploadTooLarge if the request
+// was too large to read.  The file is closed (and any temp file removed) by
+// the ParamsFilter once the request is done, though the action may close it
+// sooner.
+func (p *Params) File(name string) (multipart.File, *multipart.FileHeader, error) {
+	fileHeaders, err := p.FileHeaders(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := fileHeaders[0].Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, fileHeaders[0], nil
+}
+
+// FileHeaders returns all of the files uploaded under the given name, e.g. by
+// an <input type="file" multiple>, with the same errors as File.  (The files of
+// every name are in p.Files.)
+func (p *Params) FileHeaders(name string) ([]*multipart.FileHeader, error) {
+	if p.filesErr != nil {
+		return nil, p.filesErr
+	}
+	if len(p.Files[name]) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return p.Files[name], nil
+}
+
+// SaveUploadedFile writes the first file uploaded under the given name to
+// destPath, e.g.:
+//   if err := c.SaveUploadedFile("avatar", filepath.Join(avatarDir, user.Id+".png")); err != nil {
+//     return c.RenderError(err)
+//   }
+// The file is streamed to a temp file beside destPath, which is renamed into
+// place once complete, so that a failed upload does not leave a partial file.
+func (c *Controller) SaveUploadedFile(name, destPath string) error {
+	file, _, err := c.Params.File(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(destPath), ".revel-upload")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmpFile, file)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), destPath)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+	}
+	return err
+}
+
+// An UploadSink stores uploaded files somewhere, e.g. S3 or GCS.
+type UploadSink interface {
+	// Store reads the file contents from r until EOF, and returns the key under
+	// which it was stored.
+	Store(fieldName, fileName, contentType string, r io.Reader) (key string, err error)
+}
+
+// A StreamedUpload is the result of streaming a multipart form to an
+// UploadSink.
+type StreamedUpload struct {
+	Keys map[string][]string // Stored object keys, by form field name.
+	Form url.Values          // The form's non-file fields.
+}
+
+// The maximum total size of the non-file fields read by StreamMultipartUpload.
+const maxStreamedFormSize = 10 << 20 // 10 MB
+
+// StreamMultipartUpload reads the multipart request body part by part, piping
+// each file to the sink as it arrives instead of buffering it in memory or a
+// temp file.  It returns the keys of the stored files.
+//
+// The non-file fields of the form are returned in StreamedUpload.Form, and are
+// also added to c.Params, so they may be bound as usual after this returns.
+// (Fields sent after a file are only read once that file has been stored.)
+//
+// Streaming requires that the body was not already parsed, so the action
+// should use QueryParamsFilter in place of ParamsFilter.  If the body has been
+// parsed regardless, the parsed files are passed to the sink instead.
+//
+// If an error occurs, the files stored up to that point are still returned, so
+// that the caller may clean them up.
+func (c *Controller) StreamMultipartUpload(sink UploadSink) (*StreamedUpload, error) {
+	upload := &StreamedUpload{
+		Keys: make(map[string][]string),
+		Form: make(url.Values),
+	}
+	if c.Request.MultipartForm != nil {
+		return upload, c.storeParsedUpload(sink, upload)
+	}
+
+	defer func() {
+		for name, vals := range upload.Form {
+			if c.Params.Form == nil {
+				c.Params.Form = make(url.Values)
+			}
+			c.Params.Form[name] = append(c.Params.Form[name], vals...)
+		}
+		c.Params.Values = c.Params.calcValues()
+	}()
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return upload, err
+	}
+
+	formSize := int64(0)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return upload, nil
+		}
+		if err != nil {
+			return upload, err
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		// A part without a filename is a regular form field.
+		if part.FileName() == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, maxStreamedFormSize-formSize+1))
+			part.Close()
+			if err != nil {
+				return upload, err
+			}
+			if formSize += int64(len(value)); formSize > maxStreamedFormSize {
+				return upload, errors.New("revel/upload: multipart form fields too large")
+			}
+			upload.Form.Add(name, string(value))
+			continue
+		}
+
+		key, err := sink.Store(name, part.FileName(), part.Header.Get("Content-Type"), part)
+		part.Close()
+		if err != nil {
+			return upload, err
+		}
+		upload.Keys[name] = append(upload.Keys[name], key)
+	}
+}
+
+// storeParsedUpload passes the files of an already-parsed multipart form to the
+// sink.
+func (c *Controller) storeParsedUpload(sink UploadSink, upload *StreamedUpload) error {
+	for name, vals := range c.Request.MultipartForm.Value {
+		upload.Form[name] = vals
+	}
+
+	var names []string
+	for name := range c.Request.MultipartForm.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, fileHeader := range c.Request.MultipartForm.File[name] {
+			file, err := fileHeader.Open()
+			if err != nil {
+				return err
+			}
+			key, err := sink.Store(name, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			upload.Keys[name] = append(upload.Keys[name], key)
+		}
+	}
+	return nil
+}
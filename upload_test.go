@@ -0,0 +1,136 @@
+package revel
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type memorySink map[string]string
+
+func (s memorySink) Store(fieldName, fileName, contentType string, r io.Reader) (string, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s/%d", fileName, len(s))
+	s[key] = string(content)
+	return key, nil
+}
+
+func TestStreamMultipartUpload(t *testing.T) {
+	for _, filter := range []Filter{QueryParamsFilter, ParamsFilter} {
+		var (
+			sink   = memorySink{}
+			upload *StreamedUpload
+			err    error
+			c      = Controller{
+				Request: NewRequest(getMultipartRequest()),
+				Params:  &Params{},
+			}
+		)
+		filter(&c, []Filter{func(c *Controller, _ []Filter) {
+			upload, err = c.StreamMultipartUpload(sink)
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sink) != 5 || len(upload.Keys["file2[]"]) != 2 {
+			t.Errorf("Expected 5 files to be stored, got %v", upload.Keys)
+		}
+		if sink[upload.Keys["file1"][0]] != "content1" {
+			t.Errorf("Unexpected stored content: %v", sink)
+		}
+		if !reflect.DeepEqual(expectedValues, map[string][]string(upload.Form)) ||
+			!reflect.DeepEqual(expectedValues, map[string][]string(c.Params.Values)) {
+			t.Errorf("Unexpected form values: %v, params: %v", upload.Form, c.Params.Values)
+		}
+	}
+}
+
+func TestParamsFile(t *testing.T) {
+	c := Controller{
+		Request: NewRequest(getMultipartRequest()),
+		Params:  &Params{},
+	}
+	ParamsFilter(&c, []Filter{func(c *Controller, _ []Filter) {
+		file, header, err := c.Params.File("file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(file)
+		if header.Filename != "test.txt" || string(content) != "content1" {
+			t.Errorf("Unexpected file %s: %q", header.Filename, content)
+		}
+
+		if headers, err := c.Params.FileHeaders("file2[]"); err != nil || len(headers) != 2 {
+			t.Errorf("Expected 2 files, got %v (%v)", headers, err)
+		}
+		if _, _, err := c.Params.File("missing"); err != http.ErrMissingFile {
+			t.Errorf("Expected http.ErrMissingFile, got %v", err)
+		}
+
+		dir, err := ioutil.TempDir("", "revel-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		dest := filepath.Join(dir, "saved.txt")
+		if err := c.SaveUploadedFile("file1", dest); err != nil {
+			t.Fatal(err)
+		}
+		if saved, _ := ioutil.ReadFile(dest); string(saved) != "content1" {
+			t.Errorf("Unexpected saved content: %q", saved)
+		}
+		if err := c.SaveUploadedFile("missing", filepath.Join(dir, "missing.txt")); err != http.ErrMissingFile {
+			t.Errorf("Expected http.ErrMissingFile, got %v", err)
+		}
+		if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected only the saved file, got %v", entries)
+		}
+	}})
+}
+
+func TestParamsFileTooLarge(t *testing.T) {
+	startFakeBookingApp()
+	defer func(size int64) { maxUploadSize = size }(maxUploadSize)
+	maxUploadSize = 100
+
+	for _, knownLength := range []bool{true, false} {
+		req := getMultipartRequest()
+		if !knownLength {
+			req.ContentLength = -1
+		}
+		params := &Params{}
+		ParseParams(params, NewRequest(req))
+		if _, _, err := params.File("file1"); err != ErrUploadTooLarge {
+			t.Errorf("Expected ErrUploadTooLarge, got %v", err)
+		}
+
+		// The ParamsFilter responds with a 413, without running the action.
+		req = getMultipartRequest()
+		if !knownLength {
+			req.ContentLength = -1
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		ParamsFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			t.Error("Expected the action not to run")
+		}})
+		if c.Response.Status != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected a 413, got %d", c.Response.Status)
+		}
+		c.Result.Apply(c.Request, c.Response)
+		if resp.Code != http.StatusRequestEntityTooLarge || !strings.Contains(resp.Body.String(), "The request body is too large") {
+			t.Errorf("Expected the 413 error page, got %d:\n%s", resp.Code, resp.Body)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package revel
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BindValidated binds the named parameter into dest like Bind, and then
+// validates the fields of the resulting struct according to their "validate"
+// tags, recording any failures in v.  For example:
+//   type Signup struct {
+//     Email string `validate:"required,email,max=255"`
+//     Age   int    `validate:"min=13"`
+//     Site  string `validate:"url"`
+//     Zip   string `validate:"regex=^[0-9]{5}$"`
+//   }
+//
+//   func (c Users) Create() revel.Result {
+//     var signup Signup
+//     c.Params.BindValidated(&signup, "signup", c.Validation)
+//     if c.Validation.HasErrors() {
+//       ...
+//     }
+//   }
+//
+// The rules are:
+//   required    the value must be present (see Required)
+//   min=N       integers must be at least N, strings and slices at least N long
+//   max=N       integers must be at most N, strings and slices at most N long
+//   email       strings must be an email address
+//   url         strings must be an absolute URL
+//   regex=RE    strings must match RE, which takes the rest of the tag
+//
+// Rules other than required are not checked for empty strings and nil
+// pointers.  Nested structs are validated as well.  Errors are keyed by the
+// param name of the field, e.g. "signup.Email" (or its form tag; see
+// BindStruct), as are values that could not be converted during binding.
+func (p *Params) BindValidated(dest interface{}, name string, v *Validation) {
+	p.Bind(dest, name)
+	for _, err := range p.bindErrorsFor(name) {
+		v.Error("%s", err).Key(err.Name)
+	}
+	validateTags(v, reflect.ValueOf(dest).Elem(), name)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// validateTags checks the tagged fields of the struct value, and of any structs
+// nested within it.
+func validateTags(v *Validation, value reflect.Value, key string) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch {
+	case value.Kind() == reflect.Slice || value.Kind() == reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateTags(v, value.Index(i), fmt.Sprintf("%s[%d]", key, i))
+		}
+		return
+	case value.Kind() != reflect.Struct || value.Type() == timeType:
+		return
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fieldKey := paramFieldName(field)
+		if fieldKey == "" {
+			fieldKey = field.Name
+		}
+		if key != "" {
+			fieldKey = key + "." + fieldKey
+		}
+		fieldValue := value.Field(i)
+
+		if tag := field.Tag.Get("validate"); tag != "" {
+			rules := parseValidateTag(tag, field.Type)
+			obj, empty := validateTagValue(fieldValue)
+			checks := rules.checks
+			if empty {
+				checks = rules.emptyChecks
+			}
+			if len(checks) > 0 {
+				if result := v.Check(obj, checks...); !result.Ok {
+					result.Key(fieldKey)
+				}
+			}
+		}
+
+		validateTags(v, fieldValue, fieldKey)
+	}
+}
+
+// validateTagValue returns the value to check for the field, and whether it is
+// empty.  Integers are converted to int, as expected by Min and Max.
+func validateTagValue(value reflect.Value) (obj interface{}, empty bool) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, true
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint()), false
+	case reflect.String:
+		return value.String(), value.Len() == 0
+	}
+	return value.Interface(), false
+}
+
+// validateRules are the validators for a tag.
+type validateRules struct {
+	checks      []Validator // For present values.
+	emptyChecks []Validator // For empty values: only Required.
+}
+
+var (
+	validateRulesCache   = make(map[string]validateRules)
+	validateRulesCacheMu sync.Mutex
+)
+
+// parseValidateTag returns the validators for the tag of a field of the given
+// type.  It panics if the tag is malformed, as that is a programming error.
+func parseValidateTag(tag string, typ reflect.Type) validateRules {
+	validateRulesCacheMu.Lock()
+	defer validateRulesCacheMu.Unlock()
+	cacheKey := typ.String() + " " + tag
+	if rules, ok := validateRulesCache[cacheKey]; ok {
+		return rules
+	}
+
+	kind := typ.Kind()
+	if kind == reflect.Ptr {
+		kind = typ.Elem().Kind()
+	}
+	var rules validateRules
+	for rest := tag; rest != ""; {
+		// A regex takes the rest of the tag, since it may contain commas.
+		rule := rest
+		if strings.HasPrefix(rest, "regex=") {
+			rest = ""
+		} else if comma := strings.Index(rest, ","); comma != -1 {
+			rule, rest = rest[:comma], rest[comma+1:]
+		} else {
+			rest = ""
+		}
+
+		name, arg := rule, ""
+		if eq := strings.Index(rule, "="); eq != -1 {
+			name, arg = rule[:eq], rule[eq+1:]
+		}
+		var check Validator
+		switch name {
+		case "required":
+			check = Required{}
+			rules.emptyChecks = append(rules.emptyChecks, check)
+		case "min", "max":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				panic(fmt.Sprintf("revel/validation: invalid %s in validate tag %q", name, tag))
+			}
+			switch kind {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if name == "min" {
+					check = Min{n}
+				} else {
+					check = Max{n}
+				}
+			case reflect.String, reflect.Slice:
+				if name == "min" {
+					check = MinSize{n}
+				} else {
+					check = MaxSize{n}
+				}
+			default:
+				panic(fmt.Sprintf("revel/validation: %s does not apply to %s in validate tag %q", name, typ, tag))
+			}
+		case "email", "url", "regex":
+			if kind != reflect.String {
+				panic(fmt.Sprintf("revel/validation: %s does not apply to %s in validate tag %q", name, typ, tag))
+			}
+			switch name {
+			case "email":
+				check = VaildEmail()
+			case "url":
+				check = ValidURL()
+			case "regex":
+				regex, err := regexp.Compile(arg)
+				if err != nil {
+					panic(fmt.Sprintf("revel/validation: invalid regex in validate tag %q: %s", tag, err))
+				}
+				check = Match{regex}
+			}
+		default:
+			panic(fmt.Sprintf("revel/validation: unknown rule %q in validate tag %q", name, tag))
+		}
+		rules.checks = append(rules.checks, check)
+	}
+
+	validateRulesCache[cacheKey] = rules
+	return rules
+}
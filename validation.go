@@ -135,6 +135,30 @@ func (v *Validation) Email(str string) *ValidationResult {
 	return v.apply(Email{Match{emailPattern}}, str)
 }
 
+func (v *Validation) URL(str string) *ValidationResult {
+	return v.apply(URL{}, str)
+}
+
+// RequiredIf tests that the argument is non-empty, as Required does, if the
+// other field has the given value, e.g.:
+//   c.Validation.RequiredIf(address.State, address.Country, "US")
+func (v *Validation) RequiredIf(obj, other, otherValue interface{}) *ValidationResult {
+	return v.apply(RequiredIf{other, otherValue}, obj)
+}
+
+// EqualTo tests that the argument equals the other field, e.g.:
+//   c.Validation.EqualTo(user.PasswordConfirm, user.Password)
+func (v *Validation) EqualTo(obj, other interface{}) *ValidationResult {
+	return v.apply(EqualTo{other}, obj)
+}
+
+// GreaterThanField tests that the argument, a number or time, is greater than
+// the other field, e.g.:
+//   c.Validation.GreaterThanField(booking.CheckOutDate, booking.CheckInDate)
+func (v *Validation) GreaterThanField(obj, other interface{}) *ValidationResult {
+	return v.apply(GreaterThanField{other}, obj)
+}
+
 func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 	if chk.IsSatisfied(obj) {
 		return &ValidationResult{Ok: true}
@@ -213,6 +237,7 @@ func ValidationFilter(c *Controller, fc []Filter) {
 			Path:     "/",
 			HttpOnly: CookieHttpOnly,
 			Secure:   CookieSecure,
+			SameSite: CookieSameSite,
 		})
 	} else if hasCookie {
 		c.SetCookie(&http.Cookie{
@@ -221,6 +246,7 @@ func ValidationFilter(c *Controller, fc []Filter) {
 			Path:     "/",
 			HttpOnly: CookieHttpOnly,
 			Secure:   CookieSecure,
+			SameSite: CookieSameSite,
 		})
 	}
 }
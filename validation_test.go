@@ -3,7 +3,10 @@ package revel
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // getRecordedCookie returns the recorded cookie from a ResponseRecorder with
@@ -74,3 +77,117 @@ func TestValidationNoKeepCookiePreviouslySet(t *testing.T) {
 		t.Fatalf("cookie should be deleted")
 	}
 }
+
+type validatedAddress struct {
+	Zip string `validate:"regex=^[0-9]{5}$"`
+}
+
+type validatedSignup struct {
+	Email   string   `validate:"required,email,max=20"`
+	Name    string   `validate:"min=2"`
+	Age     int      `validate:"min=13,max=130"`
+	Site    string   `validate:"url"`
+	Tags    []string `validate:"max=2"`
+	Address validatedAddress
+}
+
+func TestBindValidated(t *testing.T) {
+	for _, test := range []struct {
+		values url.Values
+		errors map[string]string
+	}{
+		{url.Values{
+			"signup.Email":       {"ann@example.com"},
+			"signup.Age":         {"30"},
+			"signup.Site":        {"https://example.com"},
+			"signup.Tags[]":      {"a", "b"},
+			"signup.Address.Zip": {"10010"},
+		}, map[string]string{}},
+		{url.Values{
+			"signup.Name":        {"A"},
+			"signup.Age":         {"abc"},
+			"signup.Site":        {"example.com"},
+			"signup.Address.Zip": {"1001"},
+		}, map[string]string{
+			"signup.Email":       "Required",
+			"signup.Name":        "Minimum size is 2\n",
+			"signup.Age":         `"abc" is not a valid int`,
+			"signup.Site":        "Must be a valid URL\n",
+			"signup.Address.Zip": "Must match ^[0-9]{5}$\n",
+		}},
+		{url.Values{
+			"signup.Email":  {"not an email"},
+			"signup.Age":    {"200"},
+			"signup.Tags[]": {"a", "b", "c"},
+		}, map[string]string{
+			"signup.Email": "Must be a valid email address\n",
+			"signup.Age":   "Maximum is 130\n",
+			"signup.Tags":  "Maximum size is 2\n",
+		}},
+	} {
+		params := &Params{Values: test.values}
+		validation := &Validation{}
+		var signup validatedSignup
+		params.BindValidated(&signup, "signup", validation)
+
+		errors := make(map[string]string)
+		for key, err := range validation.ErrorMap() {
+			errors[key] = err.Message
+		}
+		if !reflect.DeepEqual(errors, test.errors) {
+			t.Errorf("Expected errors %#v, got %#v", test.errors, errors)
+		}
+	}
+}
+
+func TestCrossFieldValidators(t *testing.T) {
+	day := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		validator Validator
+		obj       interface{}
+		expected  bool
+	}{
+		{RequiredIf{"US", "US"}, "CA", true},
+		{RequiredIf{"US", "US"}, "", false},
+		{RequiredIf{"US", "US"}, 0, false},
+		{RequiredIf{"FR", "US"}, "", true},
+		{RequiredIf{"", ""}, "", false}, // The other field is empty, as given.
+		{RequiredIf{true, true}, time.Time{}, false},
+		{RequiredIf{int64(1), 1}, "", true}, // Values of different types differ.
+
+		{EqualTo{"secret"}, "secret", true},
+		{EqualTo{"secret"}, "Secret", false},
+		{EqualTo{""}, "", true},
+		{EqualTo{"secret"}, "", false},
+
+		{GreaterThanField{5}, 6, true},
+		{GreaterThanField{5}, 5, false},
+		{GreaterThanField{5}, 0, false}, // An empty field is compared as 0.
+		{GreaterThanField{-1}, 0, true},
+		{GreaterThanField{int64(5)}, 5.5, true},
+		{GreaterThanField{uint8(5)}, int32(4), false},
+		{GreaterThanField{day}, day.AddDate(0, 0, 1), true},
+		{GreaterThanField{day}, day, false},
+		{GreaterThanField{day}, time.Time{}, false},
+		{GreaterThanField{time.Time{}}, day, true},
+		{GreaterThanField{day}, 5, false},
+		{GreaterThanField{"a"}, "b", false}, // Strings are not compared.
+	} {
+		if actual := test.validator.IsSatisfied(test.obj); actual != test.expected {
+			t.Errorf("%T%v.IsSatisfied(%#v): expected %v, got %v",
+				test.validator, test.validator, test.obj, test.expected, actual)
+		}
+	}
+
+	// Failures are keyed by field, like those of the other validators.
+	v := &Validation{}
+	v.RequiredIf("", "US", "US").Key("address.State")
+	v.EqualTo("secre", "secret").Key("user.PasswordConfirm")
+	v.GreaterThanField(day, day).Key("booking.CheckOutDate")
+	v.GreaterThanField(day.AddDate(0, 0, 1), day).Key("booking.Nights")
+	errors := v.ErrorMap()
+	if len(v.Errors) != 3 || errors["address.State"].Message != "Required" ||
+		errors["user.PasswordConfirm"].Message != "Does not match" || errors["booking.CheckOutDate"] == nil {
+		t.Errorf("Unexpected errors: %v", v.Errors)
+	}
+}
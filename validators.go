@@ -2,6 +2,7 @@ package revel
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"regexp"
 	"time"
@@ -207,3 +208,126 @@ func VaildEmail() Email {
 func (e Email) DefaultMessage() string {
 	return fmt.Sprintln("Must be a valid email address")
 }
+
+// Requires a string to be an absolute URL, e.g. "https://example.com/path".
+type URL struct{}
+
+func ValidURL() URL {
+	return URL{}
+}
+
+func (u URL) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok {
+		return false
+	}
+	parsed, err := url.Parse(str)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+func (u URL) DefaultMessage() string {
+	return fmt.Sprintln("Must be a valid URL")
+}
+
+// Requires a value when another field has the given value, e.g. a state when
+// the country is "US".  Values are equal as by reflect.DeepEqual, so they must
+// have the same type.
+type RequiredIf struct {
+	Other, OtherValue interface{}
+}
+
+func ValidRequiredIf(other, otherValue interface{}) RequiredIf {
+	return RequiredIf{other, otherValue}
+}
+
+func (r RequiredIf) IsSatisfied(obj interface{}) bool {
+	if !reflect.DeepEqual(r.Other, r.OtherValue) {
+		return true
+	}
+	return Required{}.IsSatisfied(obj)
+}
+
+func (r RequiredIf) DefaultMessage() string {
+	return "Required"
+}
+
+// Requires a value to equal another field's, e.g. a password confirmation.
+type EqualTo struct {
+	Other interface{}
+}
+
+func ValidEqualTo(other interface{}) EqualTo {
+	return EqualTo{other}
+}
+
+func (e EqualTo) IsSatisfied(obj interface{}) bool {
+	return reflect.DeepEqual(obj, e.Other)
+}
+
+func (e EqualTo) DefaultMessage() string {
+	return "Does not match"
+}
+
+// Requires a number or time to be greater than another field's, e.g. a
+// check-out date after the check-in date.  Both must be numbers (of any kind)
+// or both time.Time.  Zero values are compared like any other, so an empty
+// field fails unless the other is less; check it with Required first.
+type GreaterThanField struct {
+	Other interface{}
+}
+
+func ValidGreaterThanField(other interface{}) GreaterThanField {
+	return GreaterThanField{other}
+}
+
+func (g GreaterThanField) IsSatisfied(obj interface{}) bool {
+	cmp, ok := compareValues(obj, g.Other)
+	return ok && cmp > 0
+}
+
+func (g GreaterThanField) DefaultMessage() string {
+	return fmt.Sprintln("Must be greater than", g.Other)
+}
+
+// compareValues returns -1, 0, or 1 as a is less than, equal to, or greater
+// than b, which must both be numbers or both time.Time; otherwise ok is false.
+func compareValues(a, b interface{}) (cmp int, ok bool) {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		switch {
+		case !ok:
+			return 0, false
+		case at.Before(bt):
+			return -1, true
+		case at.After(bt):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	switch {
+	case !aok || !bok:
+		return 0, false
+	case af < bf:
+		return -1, true
+	case af > bf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// toFloat returns the value of a number of any kind.
+func toFloat(obj interface{}) (float64, bool) {
+	v := reflect.ValueOf(obj)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
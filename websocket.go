@@ -0,0 +1,102 @@
+package revel
+
+import (
+	"code.google.com/p/go.net/websocket"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNotWebsocket is returned by Upgrade for requests that do not ask for
+	// a websocket.
+	ErrNotWebsocket = errors.New("revel/websocket: not a websocket request")
+
+	// ErrResponseStarted is returned by Upgrade once the response has been
+	// (partly) written, since the connection can then only be an HTTP response.
+	ErrResponseStarted = errors.New("revel/websocket: response already started")
+)
+
+// isWebsocketUpgrade returns true if the request asks to upgrade to a websocket.
+func isWebsocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// isWebsocketRoute returns true if the request is routed as a websocket (by a
+// WS route), in which case it is upgraded before it is dispatched.
+func isWebsocketRoute(r *http.Request) bool {
+	if MainRouter == nil {
+		return true
+	}
+	wsReq := *r
+	wsReq.Method = "WS"
+	return MainRouter.Route(&wsReq) != nil
+}
+
+// Upgrade switches the connection to a websocket, so that the action may run
+// a websocket loop, once the filters and interceptors have passed the request
+// (e.g. checked that the user is logged in), for example:
+//   func (c Chat) Room(room string) revel.Result {
+//     if user, result := c.RequireAuth(); result != nil {
+//       return result
+//     }
+//     ws, err := c.Upgrade()
+//     if err != nil {
+//       return c.BadRequest("%s", err)
+//     }
+//     for {
+//       var msg string
+//       if websocket.Message.Receive(ws, &msg) != nil {
+//         return nil
+//       }
+//       ...
+//     }
+//   }
+// The route may be a GET route.  (Requests for WS routes are upgraded before
+// they are dispatched; for those, Upgrade returns c.Request.Websocket.)
+// The websocket is closed once the action returns, so the action's result is
+// not used.  Upgrade fails with ErrNotWebsocket if the client did not ask for
+// a websocket, or ErrResponseStarted if the response has been written.
+func (c *Controller) Upgrade() (*websocket.Conn, error) {
+	if c.Request.Websocket != nil {
+		return c.Request.Websocket, nil
+	}
+	if !isWebsocketUpgrade(c.Request.Request) {
+		return nil, ErrNotWebsocket
+	}
+	if c.Response.wroteHeader {
+		return nil, ErrResponseStarted
+	}
+	if _, ok := c.Response.Out.(http.Hijacker); !ok {
+		return nil, errors.New("revel/websocket: the connection can not be taken over")
+	}
+
+	// The websocket handler keeps the connection open until it returns, so it
+	// is left waiting until the request is done.
+	var (
+		conns    = make(chan *websocket.Conn, 1)
+		done     = make(chan struct{})
+		finished = make(chan struct{})
+	)
+	go func() {
+		defer close(finished)
+		websocket.Handler(func(ws *websocket.Conn) {
+			conns <- ws
+			<-done
+		}).ServeHTTP(c.Response.Out, c.Request.Request)
+	}()
+
+	select {
+	case ws := <-conns:
+		c.Request.Websocket = ws
+		c.addCleanup(func() {
+			close(done)
+			<-finished
+		})
+		return ws, nil
+	case <-finished:
+		// The handshake failed, and the client has been answered.
+		c.Request.Websocket = nil
+		return nil, errors.New("revel/websocket: handshake failed")
+	}
+}
@@ -0,0 +1,62 @@
+package revel
+
+import (
+	"code.google.com/p/go.net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestControllerUpgrade(t *testing.T) {
+	startFakeBookingApp()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := NewController(NewRequest(r), NewResponse(w))
+		defer c.runCleanups()
+		if r.URL.Path == "/started" {
+			c.RenderText("too late").Apply(c.Request, c.Response)
+			if _, err := c.Upgrade(); err != ErrResponseStarted {
+				t.Errorf("Expected ErrResponseStarted, got %v", err)
+			}
+			return
+		}
+
+		ws, err := c.Upgrade()
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		if again, _ := c.Upgrade(); again != ws {
+			t.Errorf("Expected the same websocket from a second Upgrade")
+		}
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			t.Errorf("Failed to receive: %v", err)
+			return
+		}
+		websocket.Message.Send(ws, strings.ToUpper(msg))
+	}))
+	defer server.Close()
+
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http")
+	ws, err := websocket.Dial(wsUrl+"/echo", "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	websocket.Message.Send(ws, "hello")
+	var reply string
+	if err := websocket.Message.Receive(ws, &reply); err != nil || reply != "HELLO" {
+		t.Errorf("Expected the echo, got %q (%v)", reply, err)
+	}
+
+	if _, err := websocket.Dial(wsUrl+"/started", "", server.URL); err == nil {
+		t.Error("Expected the handshake to fail once the response was written")
+	}
+
+	// A plain request can not be upgraded.
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	if _, err := c.Upgrade(); err != ErrNotWebsocket {
+		t.Errorf("Expected ErrNotWebsocket, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// XmlOptions control the root element of a RenderXmlResult, for consumers that
+// require particular namespaces (e.g. SOAP, GPX, or RSS).
+type XmlOptions struct {
+	// RootName overrides the name of the root element.  It may include a
+	// namespace prefix, e.g. "soap:Envelope".
+	// By default, it is taken from the XMLName field or the type name.
+	RootName string
+
+	// Namespaces are declared on the root element, keyed by prefix.
+	// The empty prefix declares the default namespace.  For example:
+	//   map[string]string{
+	//     "":    "http://www.topografix.com/GPX/1/1",
+	//     "xsi": "http://www.w3.org/2001/XMLSchema-instance",
+	//   }
+	Namespaces map[string]string
+}
+
+// marshal encodes obj as XML, applying the options to the root element.
+func (opts XmlOptions) marshal(obj interface{}, indent bool) ([]byte, error) {
+	start := xml.StartElement{Name: xml.Name{Local: opts.RootName}}
+	if start.Name.Local == "" {
+		start.Name = xmlRootName(reflect.ValueOf(obj))
+	}
+	// A declared default namespace replaces that of the XMLName field, which
+	// encoding/xml would otherwise declare as well.
+	if _, ok := opts.Namespaces[""]; ok {
+		start.Name.Space = ""
+	}
+
+	prefixes := make([]string, 0, len(opts.Namespaces))
+	for prefix := range opts.Namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: name},
+			Value: opts.Namespaces[prefix],
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if indent {
+		enc.Indent("", "  ")
+	}
+	if err := enc.EncodeElement(obj, start); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xmlRootName returns the name that encoding/xml would give the root element
+// for the value: that of its XMLName field, or else its type name.
+func xmlRootName(v reflect.Value) xml.Name {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return xml.Name{}
+	}
+	if v.Kind() == reflect.Struct {
+		if field, ok := v.Type().FieldByName("XMLName"); ok {
+			tag := strings.Split(field.Tag.Get("xml"), ",")[0]
+			if parts := strings.Fields(tag); len(parts) == 2 {
+				return xml.Name{Space: parts[0], Local: parts[1]}
+			} else if tag != "" {
+				return xml.Name{Local: tag}
+			}
+			if name, ok := v.FieldByIndex(field.Index).Interface().(xml.Name); ok && name.Local != "" {
+				return name
+			}
+		}
+	}
+	return xml.Name{Local: v.Type().Name()}
+}